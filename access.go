@@ -0,0 +1,490 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/volts-dev/orm/domain"
+	"github.com/volts-dev/utils"
+)
+
+type (
+	// TUser 附着在 context 上的当前用户身份，记录规则按其所属 Groups 生效
+	TUser struct {
+		Uid    any
+		Groups []string
+	}
+
+	// TRecordRule 记录规则(行级权限)：限定用户在 Model 上能读/写/建/删哪些记录。
+	//
+	// Domain 为 domain 语法的 string 或 []any，也可以是 func(user *TUser) any
+	// (返回前两者之一)以便按用户生成条件，如 `[('create_uid', '=', uid)]`；为空表示不限制。
+	//
+	// 与 Odoo ir.rule 语义一致：Groups 为空的全局规则彼此 AND；其余规则只对所属组的用户
+	// 生效，命中的组规则彼此 OR，结果再与全局规则 AND。Perm* 全部为 false 时视为全部为 true。
+	TRecordRule struct {
+		Name       string
+		Model      string
+		Groups     []string
+		Domain     any
+		PermRead   bool
+		PermWrite  bool
+		PermCreate bool
+		PermUnlink bool
+	}
+
+	// TModelAccess 模型访问控制(ACL)：授予 Group 的用户对 Model 的读/写/建/删权限，
+	// Group 为空表示授予所有用户。与 Odoo ir.model.access 一致，权限按条目叠加(任一条目授予即允许)；
+	// 未注册任何条目的模型不受限制，注册后只放行被授予的操作。
+	TModelAccess struct {
+		Name       string
+		Model      string
+		Group      string
+		PermRead   bool
+		PermWrite  bool
+		PermCreate bool
+		PermUnlink bool
+	}
+
+	userContextKey struct{}
+	sudoContextKey struct{}
+)
+
+// WithUser 在 ctx 上附加当前用户，session.WithContext(ctx) 后其 CRUD 按该用户套用记录规则
+func WithUser(ctx context.Context, uid any, groups ...string) context.Context {
+	return context.WithValue(ctx, userContextKey{}, &TUser{Uid: uid, Groups: groups})
+}
+
+// UserFromContext 返回 ctx 上的当前用户，未附加时返回 nil
+func UserFromContext(ctx context.Context) *TUser {
+	if ctx == nil {
+		return nil
+	}
+	user, _ := ctx.Value(userContextKey{}).(*TUser)
+	return user
+}
+
+// WithSudo 标记 ctx 以超级用户身份执行，跳过模型访问控制、记录规则与字段权限组
+func WithSudo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sudoContextKey{}, true)
+}
+
+// IsSudo 报告 ctx 是否经 WithSudo 标记
+func IsSudo(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	sudo, _ := ctx.Value(sudoContextKey{}).(bool)
+	return sudo
+}
+
+// HasGroup 报告用户是否属于 groups 之一
+func (self *TUser) HasGroup(groups ...string) bool {
+	if self == nil {
+		return false
+	}
+	for _, group := range groups {
+		if utils.IndexOf(group, self.Groups...) > -1 {
+			return true
+		}
+	}
+	return false
+}
+
+// AddRecordRule 注册记录规则，此后所有会话对 rule.Model 的读写都会套用
+func (self *TOrm) AddRecordRule(rules ...*TRecordRule) error {
+	for _, rule := range rules {
+		if rule.Model == "" {
+			return fmt.Errorf("record rule %q must specify a model", rule.Name)
+		}
+	}
+
+	self.ruleLock.Lock()
+	defer self.ruleLock.Unlock()
+	if self.recordRules == nil {
+		self.recordRules = make(map[string][]*TRecordRule)
+	}
+	for _, rule := range rules {
+		self.recordRules[rule.Model] = append(self.recordRules[rule.Model], rule)
+	}
+	return nil
+}
+
+// RemoveRecordRule 按名称移除 model 上的记录规则
+func (self *TOrm) RemoveRecordRule(model, name string) {
+	self.ruleLock.Lock()
+	defer self.ruleLock.Unlock()
+	rules := self.recordRules[model]
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].Name == name {
+			rules = append(rules[:i:i], rules[i+1:]...)
+		}
+	}
+	self.recordRules[model] = rules
+}
+
+// RecordRules 返回 model 上已注册的记录规则
+func (self *TOrm) RecordRules(model string) []*TRecordRule {
+	self.ruleLock.RLock()
+	defer self.ruleLock.RUnlock()
+	rules := make([]*TRecordRule, len(self.recordRules[model]))
+	copy(rules, self.recordRules[model])
+	return rules
+}
+
+// AddModelAccess 注册模型访问控制条目
+func (self *TOrm) AddModelAccess(acls ...*TModelAccess) error {
+	for _, acl := range acls {
+		if acl.Model == "" {
+			return fmt.Errorf("model access %q must specify a model", acl.Name)
+		}
+	}
+
+	self.ruleLock.Lock()
+	defer self.ruleLock.Unlock()
+	if self.modelAccess == nil {
+		self.modelAccess = make(map[string][]*TModelAccess)
+	}
+	for _, acl := range acls {
+		self.modelAccess[acl.Model] = append(self.modelAccess[acl.Model], acl)
+	}
+	return nil
+}
+
+// RemoveModelAccess 按名称移除 model 上的访问控制条目
+func (self *TOrm) RemoveModelAccess(model, name string) {
+	self.ruleLock.Lock()
+	defer self.ruleLock.Unlock()
+	acls := self.modelAccess[model]
+	for i := len(acls) - 1; i >= 0; i-- {
+		if acls[i].Name == name {
+			acls = append(acls[:i:i], acls[i+1:]...)
+		}
+	}
+	self.modelAccess[model] = acls
+}
+
+// ModelAccess 返回 model 上已注册的访问控制条目
+func (self *TOrm) ModelAccess(model string) []*TModelAccess {
+	self.ruleLock.RLock()
+	defer self.ruleLock.RUnlock()
+	acls := make([]*TModelAccess, len(self.modelAccess[model]))
+	copy(acls, self.modelAccess[model])
+	return acls
+}
+
+// grants 条目是否授予 op 操作。Count/Sum 按读取处理
+func (self *TModelAccess) grants(op SessionOp) bool {
+	switch op {
+	case OpCreate:
+		return self.PermCreate
+	case OpWrite:
+		return self.PermWrite
+	case OpDelete:
+		return self.PermUnlink
+	default:
+		return self.PermRead
+	}
+}
+
+// CheckAccessRights 校验当前用户对会话模型的 op 操作权限(见 TModelAccess)，无权时返回 *AccessError
+func (self *TSession) CheckAccessRights(op SessionOp) error {
+	if self.isSudo() || self.Statement.Model == nil {
+		return nil
+	}
+	model := self.Statement.Model.String()
+	acls := self.orm.ModelAccess(model)
+	if len(acls) == 0 {
+		return nil
+	}
+
+	user := self.User()
+	for _, acl := range acls {
+		if acl.grants(op) && (acl.Group == "" || user.HasGroup(acl.Group)) {
+			return nil
+		}
+	}
+	return &AccessError{Model: model, Op: op}
+}
+
+// Sudo 使本会话跳过模型访问控制、记录规则与字段权限组。sticky：一经设置在该 Session 生命周期内持续有效
+func (self *TSession) Sudo() *TSession {
+	self.sudo = true
+	return self
+}
+
+// User 返回本会话 context 上的当前用户
+func (self *TSession) User() *TUser {
+	return UserFromContext(self.context)
+}
+
+func (self *TSession) isSudo() bool {
+	return self.sudo || IsSudo(self.context)
+}
+
+// applies 规则是否约束 op 操作。Count/Sum 按读取处理
+func (self *TRecordRule) applies(op SessionOp) bool {
+	if !self.PermRead && !self.PermWrite && !self.PermCreate && !self.PermUnlink {
+		return true
+	}
+	switch op {
+	case OpCreate:
+		return self.PermCreate
+	case OpWrite:
+		return self.PermWrite
+	case OpDelete:
+		return self.PermUnlink
+	default:
+		return self.PermRead
+	}
+}
+
+// domain 每次调用都重新解析，避免规则节点被后续 OP 合并改写
+func (self *TRecordRule) domain(user *TUser) (*domain.TDomainNode, error) {
+	dom := self.Domain
+	if fn, ok := dom.(func(user *TUser) any); ok {
+		dom = fn(user)
+	}
+
+	var (
+		node *domain.TDomainNode
+		err  error
+	)
+	switch v := dom.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		node, err = domain.String2Domain(v, nil)
+	case []any:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		node, err = domain.Any2Domain(v, nil)
+	default:
+		return nil, fmt.Errorf("record rule %q: unsupported domain type %T", self.Name, dom)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("record rule %q: %w", self.Name, err)
+	}
+	if node == nil || node.IsEmpty() {
+		return nil, nil
+	}
+
+	// 隐式 AND 列表补全 '&' 前缀，否则作为 OP 的右操作数被 Merge 展开后语义错乱
+	if !node.IsLeafNode() && !node.Item(0).IsDomainOperator() {
+		if node.Count() == 1 {
+			node = node.Item(0)
+		} else {
+			for i, n := 0, node.Count()-1; i < n; i++ {
+				node.Insert(0, domain.AND_OPERATOR)
+			}
+		}
+	}
+	return node, nil
+}
+
+// _ruleDomain 组合当前用户在 op 操作上的记录规则，无限制时返回 nil。
+// 列模式多租户的租户条件同样经此生效，且不受 sudo 影响；新建记录已归属当前租户，不再校验
+func (self *TSession) _ruleDomain(op SessionOp) (*domain.TDomainNode, error) {
+	if self.Statement.Model == nil {
+		return nil, nil
+	}
+	var tenant *domain.TDomainNode
+	if op != OpCreate {
+		tenant = self._tenantDomain()
+	}
+	if self.isSudo() {
+		return tenant, nil
+	}
+	rules := self.orm.RecordRules(self.Statement.Model.String())
+	if len(rules) == 0 {
+		return tenant, nil
+	}
+
+	user := self.User()
+	var global, group *domain.TDomainNode
+	groupUnrestricted := false
+	for _, rule := range rules {
+		if !rule.applies(op) {
+			continue
+		}
+		if len(rule.Groups) > 0 && !user.HasGroup(rule.Groups...) {
+			continue
+		}
+
+		node, err := rule.domain(user)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rule.Groups) == 0 {
+			if node == nil {
+				continue
+			}
+			if global == nil {
+				global = node
+			} else {
+				global.OP(domain.AND_OPERATOR, node)
+			}
+			continue
+		}
+
+		// 任一命中的组规则不限制时，组规则整体放开
+		if node == nil {
+			groupUnrestricted = true
+		} else if group == nil {
+			group = node
+		} else {
+			group.OP(domain.OR_OPERATOR, node)
+		}
+	}
+
+	node := global
+	if group != nil && !groupUnrestricted {
+		if node == nil {
+			node = group
+		} else {
+			node = node.OP(domain.AND_OPERATOR, group)
+		}
+	}
+	if tenant == nil {
+		return node, nil
+	}
+	if node == nil {
+		return tenant, nil
+	}
+	return tenant.OP(domain.AND_OPERATOR, node), nil
+}
+
+// _applyRecordRules 把记录规则 AND 进 Statement 的查询条件(每条 Statement 只合并一次)
+func (self *TSession) _applyRecordRules(op SessionOp) error {
+	if self.Statement.ruled {
+		return nil
+	}
+	node, err := self._ruleDomain(op)
+	if err != nil {
+		return err
+	}
+	if node != nil {
+		self.Statement.domain.OP(domain.AND_OPERATOR, node)
+	}
+	self.Statement.ruled = true
+	return nil
+}
+
+// _checkRecordRules 校验 ids 全部满足 op 操作的记录规则，否则返回 *AccessError
+func (self *TSession) _checkRecordRules(op SessionOp, ids []any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	node, err := self._ruleDomain(op)
+	if err != nil || node == nil {
+		return err
+	}
+
+	idField := self.Statement.Model.IdField()
+	node.IN(idField, ids...)
+	query, err := self.Statement.where_calc(node, false, nil)
+	if err != nil {
+		return err
+	}
+	from_clause, where_clause, where_clause_params := query.getSql()
+	sql := JoinClause(
+		"SELECT",
+		query.qualify(self.Statement.Model.GetFieldByName(idField), self.Statement.Model),
+		"FROM",
+		from_clause,
+		"WHERE",
+		where_clause,
+	)
+	ds, err := self._query(sql, where_clause_params...)
+	if err != nil {
+		return err
+	}
+
+	allowed := make(map[string]bool, ds.Count())
+	for _, id := range ds.Keys(idField) {
+		allowed[fmt.Sprint(id)] = true
+	}
+	denied := make([]any, 0)
+	for _, id := range ids {
+		if !allowed[fmt.Sprint(id)] {
+			denied = append(denied, id)
+		}
+	}
+	if len(denied) > 0 {
+		return &AccessError{Model: self.Statement.Model.String(), Op: op, Ids: denied}
+	}
+	return nil
+}
+
+// _fieldAccessible 报告当前用户能否访问 field，未声明 groups 的字段对所有用户开放
+func (self *TSession) _fieldAccessible(field IField) bool {
+	groups := field.Groups()
+	if groups == "" || self.isSudo() {
+		return true
+	}
+	return self.User().HasGroup(strings.Split(groups, ",")...)
+}
+
+// _deniedFields 返回当前用户无权访问的字段名
+func (self *TSession) _deniedFields() []string {
+	denied := make([]string, 0)
+	for _, field := range self.Statement.Model.GetFields() {
+		if !self._fieldAccessible(field) {
+			denied = append(denied, field.Name())
+		}
+	}
+	return denied
+}
+
+// _omitDeniedFields 把无权访问的字段从本次读取/写入中剔除
+func (self *TSession) _omitDeniedFields() {
+	if denied := self._deniedFields(); len(denied) > 0 {
+		self.Omit(denied...)
+	}
+}
+
+// _checkFieldWrite 写入数据含无权访问的字段时返回 *AccessError。
+// struct 数据无法区分零值与未赋值：零值的受限字段不报错，由 _omitDeniedFields 从写入中剔除。
+func (self *TSession) _checkFieldWrite(op SessionOp, data any) error {
+	denied := self._deniedFields()
+	if len(denied) == 0 || data == nil {
+		return nil
+	}
+
+	deny := func(field string) error {
+		return &AccessError{Model: self.Statement.Model.String(), Op: op, Field: field}
+	}
+	switch v := data.(type) {
+	case map[string]any:
+		for _, name := range denied {
+			if _, has := v[name]; has {
+				return deny(name)
+			}
+		}
+	case map[string]string:
+		for _, name := range denied {
+			if _, has := v[name]; has {
+				return deny(name)
+			}
+		}
+	default:
+		ds, err := self._validateValues(data)
+		if err != nil {
+			return err
+		}
+		ds.First()
+		for !ds.Eof() {
+			for _, name := range denied {
+				if !utils.IsBlank(ds.Record().GetByField(name)) {
+					return deny(name)
+				}
+			}
+			ds.Next()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ormerr "github.com/volts-dev/orm/errors"
+)
+
+func setupAccessOrm(t *testing.T) *TOrm {
+	t.Helper()
+	o := setupMigrationOrm(t) // 文件库：建档规则会自开事务
+	if _, err := o.SyncModel("", new(BenchModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	for _, rec := range []map[string]any{
+		{"name": "alice", "age": 20},
+		{"name": "bob", "age": 30},
+		{"name": "carol", "age": 40},
+	} {
+		if _, err := o.NewSession().Model("bench.model").Create(rec); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	return o
+}
+
+func benchNames(t *testing.T, s *TSession) []string {
+	t.Helper()
+	ds, err := s.Model("bench.model").OrderBy("id").Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	names := make([]string, 0, ds.Count())
+	ds.First()
+	for !ds.Eof() {
+		names = append(names, ds.FieldByName("name").AsString())
+		ds.Next()
+	}
+	return names
+}
+
+func TestRecordRules_FilterReadSearchAndCount(t *testing.T) {
+	o := setupAccessOrm(t)
+	defer o.Close()
+
+	err := o.AddRecordRule(
+		&TRecordRule{Name: "adults", Model: "bench.model", Domain: `[('age', '>', 25)]`},
+		&TRecordRule{Name: "own", Model: "bench.model", Groups: []string{"user"}, Domain: func(user *TUser) any {
+			return []any{[]any{"name", "=", user.Uid}}
+		}},
+		&TRecordRule{Name: "manager", Model: "bench.model", Groups: []string{"manager"}},
+	)
+	if err != nil {
+		t.Fatalf("AddRecordRule: %v", err)
+	}
+
+	// 无用户：只套用全局规则
+	if names := benchNames(t, o.NewSession()); len(names) != 2 {
+		t.Fatalf("global rule should hide alice, got %v", names)
+	}
+
+	// 组规则与全局规则 AND
+	ctx := WithUser(context.Background(), "bob", "user")
+	if names := benchNames(t, o.NewSession().WithContext(ctx)); len(names) != 1 || names[0] != "bob" {
+		t.Fatalf("user should only see own adult record, got %v", names)
+	}
+	ids, _, err := o.NewSession().WithContext(ctx).Model("bench.model").Search()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("Search should honour rules, got %v, %v", ids, err)
+	}
+	if cnt, err := o.NewSession().WithContext(ctx).Model("bench.model").Count(); err != nil || cnt != 1 {
+		t.Fatalf("Count should honour rules, got %d, %v", cnt, err)
+	}
+
+	// 不限制的组规则放开全部组规则，全局规则仍然生效
+	ctx = WithUser(context.Background(), "bob", "user", "manager")
+	if names := benchNames(t, o.NewSession().WithContext(ctx)); len(names) != 2 {
+		t.Fatalf("manager should see all adult records, got %v", names)
+	}
+
+	// sudo 跳过全部规则
+	if names := benchNames(t, o.NewSession().Sudo()); len(names) != 3 {
+		t.Fatalf("sudo session should bypass rules, got %v", names)
+	}
+	if names := benchNames(t, o.NewSession().WithContext(WithSudo(ctx))); len(names) != 3 {
+		t.Fatalf("sudo context should bypass rules, got %v", names)
+	}
+}
+
+func TestRecordRules_DenyWriteUnlinkAndCreate(t *testing.T) {
+	o := setupAccessOrm(t)
+	defer o.Close()
+
+	err := o.AddRecordRule(&TRecordRule{
+		Name:       "no_seniors",
+		Model:      "bench.model",
+		Domain:     `[('age', '<', 35)]`,
+		PermWrite:  true,
+		PermCreate: true,
+		PermUnlink: true,
+	})
+	if err != nil {
+		t.Fatalf("AddRecordRule: %v", err)
+	}
+
+	// 规则不约束读取
+	if names := benchNames(t, o.NewSession()); len(names) != 3 {
+		t.Fatalf("read is not restricted by the rule, got %v", names)
+	}
+
+	carol, _, err := o.NewSession().Sudo().Model("bench.model").Domain(`[('name', '=', 'carol')]`).Search()
+	if err != nil || len(carol) != 1 {
+		t.Fatalf("Search carol: %v, %v", carol, err)
+	}
+
+	var accessErr *AccessError
+	_, err = o.NewSession().Model("bench.model").Ids(carol...).Write(map[string]any{"name": "caroline"})
+	if !errors.As(err, &accessErr) || !errors.Is(err, ormerr.ErrAccessDenied) || accessErr.Op != OpWrite {
+		t.Fatalf("writing carol should be denied, got %v", err)
+	}
+	if _, err = o.NewSession().Model("bench.model").Delete(carol...); !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("deleting carol should be denied, got %v", err)
+	}
+	if _, err = o.NewSession().Model("bench.model").Create(map[string]any{"name": "dave", "age": 50}); !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("creating a senior should be denied, got %v", err)
+	}
+	if names := benchNames(t, o.NewSession()); len(names) != 3 {
+		t.Fatalf("denied create must be rolled back, got %v", names)
+	}
+
+	if _, err = o.NewSession().Model("bench.model").Domain(`[('name', '=', 'bob')]`).Write(map[string]any{"age": 31}); err != nil {
+		t.Fatalf("writing bob should be allowed: %v", err)
+	}
+	if _, err = o.NewSession().Sudo().Model("bench.model").Delete(carol...); err != nil {
+		t.Fatalf("sudo delete should be allowed: %v", err)
+	}
+}
+
+func TestModelAccess_CheckedOnRequestsAndQueries(t *testing.T) {
+	o := setupAccessOrm(t)
+	defer o.Close()
+
+	err := o.AddModelAccess(
+		&TModelAccess{Name: "all_read", Model: "bench.model", PermRead: true},
+		&TModelAccess{Name: "admin_full", Model: "bench.model", Group: "admin", PermRead: true, PermWrite: true, PermCreate: true, PermUnlink: true},
+	)
+	if err != nil {
+		t.Fatalf("AddModelAccess: %v", err)
+	}
+
+	guest := WithUser(context.Background(), 7)
+	admin := WithUser(context.Background(), 1, "admin")
+
+	if _, _, err = o.NewSession().WithContext(guest).Model("bench.model").Search(); err != nil {
+		t.Fatalf("guest Search should be granted: %v", err)
+	}
+	if _, err = o.NewSession().WithContext(guest).Model("bench.model").Sum("age"); err != nil {
+		t.Fatalf("guest Sum should be granted: %v", err)
+	}
+
+	model, err := o.GetModel("bench.model", WithContext(guest))
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if ds, err := model.Read(&ReadRequest{}); err != nil || ds.Count() != 3 {
+		t.Fatalf("guest Read should be granted: %v", err)
+	}
+
+	var accessErr *AccessError
+	_, err = model.Create(&CreateRequest{Context: guest, Data: []any{map[string]any{"name": "eve"}}})
+	if !errors.As(err, &accessErr) || accessErr.Op != OpCreate || accessErr.Field != "" {
+		t.Fatalf("guest Create should be denied, got %v", err)
+	}
+	if _, err = model.Update(&UpdateRequest{Context: guest, Ids: []any{1}, Data: []any{map[string]any{"age": 1}}}); !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("guest Update should be denied, got %v", err)
+	}
+	if _, err = model.Delete(&DeleteRequest{Context: guest, Ids: []any{1}}); !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("guest Delete should be denied, got %v", err)
+	}
+
+	if _, err = model.Create(&CreateRequest{Context: admin, Data: []any{map[string]any{"name": "eve"}}}); err != nil {
+		t.Fatalf("admin Create should be granted: %v", err)
+	}
+
+	// 无任何读权限的条目时 Count 也被拒绝
+	o.RemoveModelAccess("bench.model", "all_read")
+	if _, err = o.NewSession().WithContext(guest).Model("bench.model").Count(); !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("guest Count should be denied once read ACL is removed, got %v", err)
+	}
+	if cnt, err := o.NewSession().Sudo().Model("bench.model").Count(); err != nil || cnt != 4 {
+		t.Fatalf("sudo Count should bypass ACLs, got %d, %v", cnt, err)
+	}
+}
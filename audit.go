@@ -0,0 +1,140 @@
+package orm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/orm/dialect"
+	"github.com/volts-dev/utils"
+)
+
+// AuditTable 记录字段级变更的审计表，由 ORM 在同步开启审计的模型时建立
+const AuditTable = "orm_audit"
+
+// auditEntry 一条字段变更：Old 为 nil 表示新建，New 为 nil 表示删除
+type auditEntry struct {
+	resId any
+	field string
+	old   any
+	new   any
+}
+
+// auditTableSql 审计表 DDL。值统一存为文本，便于跨类型比较与展示
+func auditTableSql(quoter dialect.Quoter, schema string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s VARCHAR(128), %s VARCHAR(64), %s VARCHAR(128), %s VARCHAR(16), %s TEXT, %s TEXT, %s VARCHAR(64), %s VARCHAR(64))",
+		quoter.QuoteTable(schema, AuditTable),
+		quoter.Quote("model"), quoter.Quote("res_id"), quoter.Quote("field"), quoter.Quote("op"),
+		quoter.Quote("old_value"), quoter.Quote("new_value"), quoter.Quote("uid"), quoter.Quote("changed_at"))
+}
+
+// _auditEnabled 会话模型是否开启审计(table 标签 audit / ModelBuilder.Audit)
+func (self *TSession) _auditEnabled() bool {
+	return self.Statement.Model != nil && self.Statement.Model.Obj().Audit
+}
+
+// _auditColumns 筛出 names 中有物理列的字段；names 为空时返回模型全部物理列
+func (self *TSession) _auditColumns(names ...string) []string {
+	model := self.Statement.Model
+	fields := make([]IField, 0, len(names))
+	if len(names) == 0 {
+		fields = model.GetFields()
+	} else {
+		for _, name := range names {
+			if field := model.GetFieldByName(name); field != nil {
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !field.Store() || field.SQLType().Name == "" ||
+			field.TypeName() == TYPE_O2M || field.TypeName() == TYPE_M2M {
+			continue
+		}
+		if field.Name() == model.IdField() {
+			continue
+		}
+		columns = append(columns, field.Name())
+	}
+	return columns
+}
+
+// _auditSnapshot 读取 ids 记录当前的 columns 值，按 id 文本索引
+func (self *TSession) _auditSnapshot(ids []any, columns []string) (map[string]*dataset.TRecordSet, error) {
+	snapshot := make(map[string]*dataset.TRecordSet, len(ids))
+	if len(ids) == 0 || len(columns) == 0 {
+		return snapshot, nil
+	}
+
+	quoter := self.orm.dialect.Quoter()
+	idField := self.Statement.Model.IdField()
+	cols := quoter.Quote(idField)
+	for _, col := range columns {
+		cols += "," + quoter.Quote(col)
+	}
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)",
+		cols, quoter.QuoteTable(self.Schema, self.Statement.Model.Table()), quoter.Quote(idField), idsToSqlHolder(ids...))
+	ds, err := self._query(sql, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.Range(func(pos int, record *dataset.TRecordSet) error {
+		snapshot[utils.ToString(record.GetByField(idField))] = record
+		return nil
+	})
+	return snapshot, nil
+}
+
+// _auditRecord 把变更写入审计表(沿用会话事务)，值未变的字段不记录
+func (self *TSession) _auditRecord(op SessionOp, entries []auditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	quoter := self.orm.dialect.Quoter()
+	sql := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		quoter.QuoteTable(self.Schema, AuditTable),
+		quoter.Quote("model"), quoter.Quote("res_id"), quoter.Quote("field"), quoter.Quote("op"),
+		quoter.Quote("old_value"), quoter.Quote("new_value"), quoter.Quote("uid"), quoter.Quote("changed_at"))
+
+	var uid any
+	if user := self.User(); user != nil && user.Uid != nil {
+		uid = utils.ToString(user.Uid)
+	}
+	model := self.Statement.Model.String()
+	changedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, entry := range entries {
+		oldVal, newVal := auditValue(entry.old), auditValue(entry.new)
+		if oldVal == newVal {
+			continue
+		}
+		if _, err := self._exec(sql, model, utils.ToString(entry.resId), entry.field, op.String(), oldVal, newVal, uid, changedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditValue 规整为文本以消除驱动返回类型差异(int64/int、[]byte/string 等)，nil 保持 nil
+func auditValue(v any) any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return string(val)
+	case time.Time:
+		if val.IsZero() {
+			return nil
+		}
+		return val.UTC().Format(time.RFC3339Nano)
+	case *time.Time:
+		if val == nil || val.IsZero() {
+			return nil
+		}
+		return val.UTC().Format(time.RFC3339Nano)
+	}
+	return utils.ToString(v)
+}
@@ -0,0 +1,114 @@
+package orm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type AuditedPartner struct {
+	TModel    `table:"name('audit_partner') audit"`
+	Id        int64     `field:"pk autoincr"`
+	Name      string    `field:"varchar() size(64)"`
+	Age       int       `field:"int()"`
+	DeletedAt time.Time `field:"datetime() deleted"`
+}
+
+type auditRow struct {
+	resId, field, op, oldValue, newValue, uid string
+}
+
+func auditRows(t *testing.T, o *TOrm, model string) []auditRow {
+	t.Helper()
+	ds, err := o.NewSession().Query(`SELECT res_id, field, op, old_value, new_value, uid, changed_at FROM orm_audit WHERE model = ? ORDER BY rowid`, model)
+	if err != nil {
+		t.Fatalf("query audit: %v", err)
+	}
+	rows := make([]auditRow, 0, ds.Count())
+	ds.First()
+	for !ds.Eof() {
+		if ds.FieldByName("changed_at").AsString() == "" {
+			t.Fatalf("audit row without timestamp")
+		}
+		rows = append(rows, auditRow{
+			resId:    ds.FieldByName("res_id").AsString(),
+			field:    ds.FieldByName("field").AsString(),
+			op:       ds.FieldByName("op").AsString(),
+			oldValue: ds.FieldByName("old_value").AsString(),
+			newValue: ds.FieldByName("new_value").AsString(),
+			uid:      ds.FieldByName("uid").AsString(),
+		})
+		ds.Next()
+	}
+	return rows
+}
+
+func TestAudit_RecordsCreateWriteAndDelete(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+
+	plan, err := o.PlanSyncModel("", new(AuditedPartner))
+	if err != nil {
+		t.Fatalf("PlanSyncModel: %v", err)
+	}
+	if !strings.Contains(plan.String(), AuditTable) {
+		t.Fatalf("plan should create the audit table:\n%s", plan)
+	}
+	names, err := o.SyncModel("", new(AuditedPartner))
+	if err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	model := names[0]
+	ctx := WithUser(context.Background(), 42)
+
+	ids, err := o.NewSession().WithContext(ctx).Model(model).Create(map[string]any{"name": "alice", "age": 20})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	rows := auditRows(t, o, model)
+	if len(rows) != 2 {
+		t.Fatalf("create should record name and age, got %+v", rows)
+	}
+	for _, row := range rows {
+		if row.op != "create" || row.oldValue != "" || row.uid != "42" {
+			t.Fatalf("unexpected create row %+v", row)
+		}
+	}
+
+	// 按条件更新同样被记录，值未变的字段不记录
+	_, err = o.NewSession().WithContext(ctx).Model(model).Domain(`[('name', '=', 'alice')]`).Write(map[string]any{"name": "alice", "age": 21})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	rows = auditRows(t, o, model)
+	if len(rows) != 3 {
+		t.Fatalf("write should only record the changed field, got %+v", rows)
+	}
+	if got := rows[2]; got.op != "write" || got.field != "age" || got.oldValue != "20" || got.newValue != "21" || got.uid != "42" {
+		t.Fatalf("unexpected write row %+v", got)
+	}
+
+	if _, err = o.NewSession().Model(model).SoftDelete(ids...); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+	rows = auditRows(t, o, model)
+	if got := rows[len(rows)-1]; got.field != "deleted_at" || got.oldValue != "" || got.newValue == "" || got.uid != "" {
+		t.Fatalf("soft delete should record the deleted field, got %+v", got)
+	}
+
+	count := len(rows)
+	if _, err = o.NewSession().Model(model).IncludeDeleted().Delete(ids...); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	rows = auditRows(t, o, model)
+	deleted := rows[count:]
+	if len(deleted) != 3 {
+		t.Fatalf("delete should record every stored column, got %+v", deleted)
+	}
+	for _, row := range deleted {
+		if row.op != "unlink" || row.newValue != "" || row.oldValue == "" {
+			t.Fatalf("unexpected delete row %+v", row)
+		}
+	}
+}
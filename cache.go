@@ -0,0 +1,185 @@
+package orm
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/orm/cacher"
+	"github.com/volts-dev/orm/core"
+	"github.com/volts-dev/utils"
+)
+
+// execTableRe 匹配写入语句的目标表，允许前置行注释与 schema 限定
+var execTableRe = regexp.MustCompile("(?is)^(?:\\s*--[^\\n]*\\n)*\\s*" +
+	"(?:INSERT(?:\\s+OR\\s+\\w+|\\s+IGNORE)?\\s+INTO|REPLACE\\s+INTO|UPDATE(?:\\s+ONLY)?|DELETE\\s+FROM(?:\\s+ONLY)?|" +
+	"TRUNCATE(?:\\s+TABLE)?(?:\\s+ONLY)?|(?:ALTER|DROP)\\s+TABLE(?:\\s+IF\\s+EXISTS)?(?:\\s+ONLY)?)" +
+	"\\s+((?:[`\"\\[]?[\\w$]+[`\"\\]]?\\s*\\.\\s*)*[`\"\\[]?[\\w$]+)")
+
+// execTable 写入语句(INSERT/UPDATE/DELETE/REPLACE/TRUNCATE、ALTER/DROP TABLE)的目标表名，
+// 已去掉 schema 与引号；其他语句返回空
+func execTable(sql_str string) string {
+	m := execTableRe.FindStringSubmatch(sql_str)
+	if m == nil {
+		return ""
+	}
+	name := m[1]
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.Trim(strings.TrimSpace(name), "`\"[]")
+}
+
+// _sqlTables 语句中出现的已注册模型表(含翻译表)，作为 Sql 缓存结果的依赖。
+// 按标识符粗判，宁多勿漏：多失效一次只是多查一次库，漏掉会返回过期结果
+func (self *TOrm) _sqlTables(sql_str string) (tables []string) {
+	seen := make(map[string]bool)
+	for i := 0; i < len(sql_str); {
+		c := sql_str[i]
+		if c == '\'' {
+			// 跳过字符串字面量，'' 为转义的单引号
+			for i++; i < len(sql_str); i++ {
+				if sql_str[i] == '\'' {
+					if i+1 < len(sql_str) && sql_str[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+			continue
+		}
+		if !isIdentByte(c) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(sql_str) && isIdentByte(sql_str[i]) {
+			i++
+		}
+		word := sql_str[start:i]
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		if _, has := self.osv.tables.Load(word); has || word == TranslationTable {
+			tables = append(tables, word)
+		}
+	}
+	return tables
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// _deferInvalidation 登记事务提交后执行的缓存操作
+func (self *TOrm) _deferInvalidation(tx *core.Tx, fn func(chr *cacher.TCacher)) {
+	self.txInvalidMu.Lock()
+	if self.txInvalid == nil {
+		self.txInvalid = make(map[*core.Tx][]func(*cacher.TCacher))
+	}
+	self.txInvalid[tx] = append(self.txInvalid[tx], fn)
+	self.txInvalidMu.Unlock()
+}
+
+// _deferredInvalidations 事务已登记的缓存操作数
+func (self *TOrm) _deferredInvalidations(tx *core.Tx) int {
+	self.txInvalidMu.Lock()
+	defer self.txInvalidMu.Unlock()
+	return len(self.txInvalid[tx])
+}
+
+// _discardInvalidations 回滚到保存点时丢弃其后登记的缓存操作，只保留前 n 个
+func (self *TOrm) _discardInvalidations(tx *core.Tx, n int) {
+	self.txInvalidMu.Lock()
+	if fns := self.txInvalid[tx]; len(fns) > n {
+		clear(fns[n:])
+		self.txInvalid[tx] = fns[:n]
+	}
+	self.txInvalidMu.Unlock()
+}
+
+// _endTx 事务结束：提交时执行其间登记的缓存操作，回滚时丢弃
+func (self *TOrm) _endTx(tx *core.Tx, committed bool) {
+	if tx == nil {
+		return
+	}
+	self.txInvalidMu.Lock()
+	fns := self.txInvalid[tx]
+	delete(self.txInvalid, tx)
+	self.txInvalidMu.Unlock()
+
+	if committed && self.Cacher != nil {
+		for _, fn := range fns {
+			fn(self.Cacher)
+		}
+	}
+}
+
+// _invalidate 写入后更新缓存。事务中推迟到提交后执行：事务内的读取不经缓存，
+// 提交前其他会话读到并缓存的仍是已提交的旧数据，须在提交后清除；回滚时丢弃
+func (self *TSession) _invalidate(fn func(chr *cacher.TCacher)) {
+	if self.orm.Cacher == nil {
+		return
+	}
+	if self.tx != nil {
+		self.orm._deferInvalidation(self.tx, fn)
+		return
+	}
+	fn(self.orm.Cacher)
+}
+
+// _invalidateIds 移除表中变动记录的缓存，Sql 查询结果由执行语句时的失效处理(见 _exec)
+func (self *TSession) _invalidateIds(table string, ids ...any) {
+	if len(ids) == 0 {
+		return
+	}
+	keys := make([]any, len(ids))
+	for i, id := range ids {
+		keys[i] = utils.ToString(id) // 与 PutById 的键一致
+	}
+	self._invalidate(func(chr *cacher.TCacher) {
+		chr.RemoveById(table, keys...)
+	})
+}
+
+// _invalidateExec 语句执行成功后使其目标表的缓存失效：改动结构的语句清除整表缓存，
+// 写入语句清除涉及该表的 Sql 查询结果，变动记录的缓存由各写入流程按 Id 移除。
+// raw 为 Exec 执行的原生语句：改动了哪些记录无从得知，更新与删除另清除整表
+func (self *TSession) _invalidateExec(sql_str string, raw bool) {
+	table := execTable(sql_str)
+	if table == "" {
+		return
+	}
+
+	full := isDDL(sql_str)
+	if raw {
+		switch firstKeyword(sql_str) {
+		case "UPDATE", "DELETE", "REPLACE":
+			full = true
+		default:
+			return // _exec 已处理
+		}
+	}
+
+	self._invalidate(func(chr *cacher.TCacher) {
+		if full {
+			chr.ClearByTable(table)
+		} else {
+			chr.ClearSqlByTable(table)
+		}
+	})
+}
+
+// _getBySql 读取 _putBySql 缓存的查询结果
+func (self *TSession) _getBySql(table string, sql_str string, params []any) *dataset.TDataSet {
+	return self.orm.Cacher.GetBySql(table, sql_str, params, self.orm._sqlTables(sql_str)...)
+}
+
+// _putBySql 缓存查询结果，语句中出现的其他表变动时该结果一并失效
+func (self *TSession) _putBySql(table string, sql_str string, params []any, ds *dataset.TDataSet) {
+	self.orm.Cacher.PutBySql(table, sql_str, params, ds, self.orm._sqlTables(sql_str)...)
+}
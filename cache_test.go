@@ -0,0 +1,246 @@
+package orm
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/volts-dev/utils"
+)
+
+// setupCacheOrm 开启 Sql 结果缓存；库文件使直接改库与 ORM 读取落在同一份数据上
+func setupCacheOrm(t *testing.T) *TOrm {
+	t.Helper()
+	ds := &TDataSource{DbType: "sqlite", DbName: filepath.Join(t.TempDir(), "cache.db")}
+	o, err := New(WithDataSource(ds))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err = o.SyncModel("", new(RelatedCountry), new(RelatedPartner), new(RelatedOrder), new(SoftDeleteUser)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	for _, table := range []string{"rl_country", "rl_partner", "rl_order", "sd_user"} {
+		o.Cacher.SetStatus(true, table)
+	}
+	return o
+}
+
+// cacheNames 按名称排序读取 model 的 name
+func cacheNames(t *testing.T, session *TSession, model string) string {
+	t.Helper()
+	ds, err := session.Model(model).OrderBy("name").Read()
+	if err != nil {
+		t.Fatalf("Read %s: %v", model, err)
+	}
+	return fmt.Sprint(ds.Keys("name"))
+}
+
+func TestCache_WriteThrough(t *testing.T) {
+	o := setupCacheOrm(t)
+	defer o.Close()
+
+	ids, err := o.NewSession().Model("rl_partner").Create(map[string]any{"name": "ACME"}, map[string]any{"name": "Initech"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := cacheNames(t, o.NewSession(), "rl_partner"); got != "[ACME Initech]" {
+		t.Fatalf("names = %s", got)
+	}
+	// 绕过 ORM 改库，读取仍命中缓存
+	if _, err = o.db.Exec("UPDATE rl_partner SET name = 'Hidden' WHERE id = ?", ids[1]); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if got := cacheNames(t, o.NewSession(), "rl_partner"); got != "[ACME Initech]" {
+		t.Fatalf("read should be served from the cache, got %s", got)
+	}
+
+	steps := []struct {
+		name   string
+		mutate func() error
+		want   string
+	}{
+		{"Write by id", func() error {
+			_, err := o.NewSession().Model("rl_partner").Ids(ids[0]).Write(map[string]any{"name": "Acme"})
+			return err
+		}, "[Acme Hidden]"},
+		{"Write by domain", func() error {
+			_, err := o.NewSession().Model("rl_partner").Where("name = ?", "Hidden").Write(map[string]any{"name": "Initech"})
+			return err
+		}, "[Acme Initech]"},
+		{"Create", func() error {
+			_, err := o.NewSession().Model("rl_partner").Create(map[string]any{"name": "Globex"})
+			return err
+		}, "[Acme Globex Initech]"},
+		{"Delete", func() error {
+			_, err := o.NewSession().Model("rl_partner").Delete(ids[1])
+			return err
+		}, "[Acme Globex]"},
+		{"raw Exec", func() error {
+			_, err := o.NewSession().Exec("UPDATE rl_partner SET name = ? WHERE id = ?", "Raw", ids[0])
+			return err
+		}, "[Globex Raw]"},
+	}
+	for _, step := range steps {
+		if err := step.mutate(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := cacheNames(t, o.NewSession(), "rl_partner"); got != step.want {
+			t.Fatalf("%s should invalidate cached reads, got %s want %s", step.name, got, step.want)
+		}
+	}
+	if n, _ := o.NewSession().Model("rl_partner").Count(); n != 2 {
+		t.Fatalf("Count = %d, want 2", n)
+	}
+
+	users, err := o.NewSession().Model("sd_user").Create(map[string]any{"name": "u1"}, map[string]any{"name": "u2"})
+	if err != nil {
+		t.Fatalf("Create users: %v", err)
+	}
+	if got := cacheNames(t, o.NewSession(), "sd_user"); got != "[u1 u2]" {
+		t.Fatalf("users = %s", got)
+	}
+	if _, err = o.NewSession().Model("sd_user").SoftDelete(users[0]); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+	if got := cacheNames(t, o.NewSession(), "sd_user"); got != "[u2]" {
+		t.Fatalf("SoftDelete should invalidate cached reads, got %s", got)
+	}
+}
+
+func TestCache_JoinedTable(t *testing.T) {
+	o := setupCacheOrm(t)
+	defer o.Close()
+
+	countries, err := o.NewSession().Model("rl_country").Create(map[string]any{"name": "Japan"})
+	if err != nil {
+		t.Fatalf("Create country: %v", err)
+	}
+	partners, err := o.NewSession().Model("rl_partner").Create(map[string]any{"name": "Initech", "country_id": countries[0]})
+	if err != nil {
+		t.Fatalf("Create partner: %v", err)
+	}
+	if _, err = o.NewSession().Model("rl_order").Create(map[string]any{"partner_id": partners[0]}); err != nil {
+		t.Fatalf("Create order: %v", err)
+	}
+
+	count := func(country string) int {
+		n, err := o.NewSession().Model("rl_order").Domain(fmt.Sprintf(`[('country_name', '=', '%s')]`, country)).Count()
+		if err != nil {
+			t.Fatalf("Count: %v", err)
+		}
+		return n
+	}
+	if n := count("Japan"); n != 1 {
+		t.Fatalf("orders in Japan = %d, want 1", n)
+	}
+
+	// 订单的查询联接了国家表，国家变动后结果失效
+	if _, err = o.NewSession().Model("rl_country").Ids(countries[0]).Write(map[string]any{"name": "Nippon"}); err != nil {
+		t.Fatalf("Write country: %v", err)
+	}
+	if n := count("Japan"); n != 0 {
+		t.Fatalf("result joining the changed table should be invalidated, got %d", n)
+	}
+	if n := count("Nippon"); n != 1 {
+		t.Fatalf("orders in Nippon = %d, want 1", n)
+	}
+}
+
+func TestCache_Transaction(t *testing.T) {
+	o := setupCacheOrm(t)
+	defer o.Close()
+
+	ids, err := o.NewSession().Model("rl_partner").Create(map[string]any{"name": "ACME"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 事务内读到未提交的数据且不写入缓存，回滚后读取仍为旧值
+	session := o.NewSession()
+	if err = session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err = session.Model("rl_partner").Ids(ids...).Write(map[string]any{"name": "Draft"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := cacheNames(t, session, "rl_partner"); got != "[Draft]" {
+		t.Fatalf("transaction should read its own write, got %s", got)
+	}
+	session.Rollback(nil)
+	if got := cacheNames(t, o.NewSession(), "rl_partner"); got != "[ACME]" {
+		t.Fatalf("rolled back write should not reach the cache, got %s", got)
+	}
+
+	// 提交前其他会话缓存的旧值在提交后失效
+	session = o.NewSession()
+	if err = session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err = session.Model("rl_partner").Ids(ids...).Write(map[string]any{"name": "Final"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := cacheNames(t, o.NewSession(), "rl_partner"); got != "[ACME]" {
+		t.Fatalf("other sessions should read committed data, got %s", got)
+	}
+	if err = session.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := cacheNames(t, o.NewSession(), "rl_partner"); got != "[Final]" {
+		t.Fatalf("commit should invalidate reads cached during the transaction, got %s", got)
+	}
+}
+
+func TestCache_ExecTable(t *testing.T) {
+	for sql, want := range map[string]string{
+		`INSERT INTO "public"."res_user" (name) VALUES (?)`: "res_user",
+		"-- note\nUPDATE `res_user` SET name = ?":           "res_user",
+		"DELETE FROM res_user WHERE id IN (?)":              "res_user",
+		"INSERT OR REPLACE INTO res_user VALUES (?)":        "res_user",
+		"TRUNCATE TABLE ONLY res_user":                      "res_user",
+		"ALTER TABLE IF EXISTS s.res_user ADD x int":        "res_user",
+		"SELECT * FROM res_user":                            "",
+		"CREATE INDEX idx ON res_user (name)":               "",
+	} {
+		if got := execTable(sql); got != want {
+			t.Errorf("execTable(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+func TestCache_SavepointRollback(t *testing.T) {
+	o := setupCacheOrm(t)
+	defer o.Close()
+
+	// 回滚到保存点的写入不得在外层提交后进入缓存
+	session := o.NewSession()
+	if err := session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	kept, err := session.Model("rl_partner").Create(map[string]any{"name": "Kept"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err = session.Begin(); err != nil {
+		t.Fatalf("nested Begin: %v", err)
+	}
+	dropped, err := session.Model("rl_partner").Create(map[string]any{"name": "Dropped"})
+	if err != nil {
+		t.Fatalf("nested Create: %v", err)
+	}
+	session.Rollback(nil)
+	if err = session.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// 按 id 读取缓存：保存点前的新记录已缓存，回滚掉的不应出现
+	o.Cacher.Active(true)
+	if recs, _ := o.Cacher.GetByIds("rl_partner", utils.ToString(dropped[0])); len(recs) != 0 {
+		t.Fatalf("record rolled back to the savepoint should not be cached, got %d records", len(recs))
+	}
+	if recs, _ := o.Cacher.GetByIds("rl_partner", utils.ToString(kept[0])); len(recs) != 1 {
+		t.Fatalf("record created before the savepoint should be cached")
+	}
+	if got := cacheNames(t, o.NewSession(), "rl_partner"); got != "[Kept]" {
+		t.Fatalf("names = %s", got)
+	}
+}
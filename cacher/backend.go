@@ -0,0 +1,72 @@
+package cacher
+
+import (
+	"time"
+
+	"github.com/volts-dev/cacher"
+)
+
+type (
+	// IBackend 缓存存储后端，TCacher 的 Id 缓存与 Sql 缓存均经由它存取。
+	// 值为 *dataset.TRecordSet 或 *dataset.TDataSet；未命中时 Get 返回 cacher.ErrCacheMiss
+	IBackend interface {
+		String() string
+		Get(key string) (any, error)
+		Set(key string, value any, ttl time.Duration) error
+		Delete(key string) error
+		Close() error
+	}
+
+	// IBroadcaster 可向共用同一后端的其他 TCacher 广播消息的后端。
+	// TCacher 经由它把 RemoveById/RemoveBySql/ClearByTable 的失效通知到其他 ORM 实例
+	IBroadcaster interface {
+		Publish(msg []byte) error
+		// Subscribe 注册消息处理函数，后端关闭前持续接收其他实例发布的消息
+		Subscribe(handler func(msg []byte)) error
+	}
+
+	// IVersioner 可保存共享计数的后端。TCacher 在其上为每张表维护缓存代数并写入缓存键，
+	// ClearByTable/ClearSqlByTable 递增代数后，所有实例此前写入的该表缓存随即不再命中，
+	// 不依赖各实例本地的键索引，也不怕失效广播丢失。旧代数的键由 TTL 回收
+	IVersioner interface {
+		// Versions 读取各计数的当前值，不存在的计数为 0
+		Versions(keys ...string) ([]int64, error)
+		Incr(key string) (int64, error)
+	}
+
+	// memoryBackend 进程内缓存，不跨实例共享。缓存的是值的引用
+	memoryBackend struct {
+		cache cacher.ICacher
+	}
+)
+
+// NewMemoryBackend 进程内缓存后端，TCacher 的默认后端
+func NewMemoryBackend() (IBackend, error) {
+	cache, err := cacher.New("memory")
+	if err != nil {
+		return nil, err
+	}
+	return &memoryBackend{cache: cache}, nil
+}
+
+func (self *memoryBackend) String() string {
+	return "memory"
+}
+
+func (self *memoryBackend) Get(key string) (any, error) {
+	return self.cache.Get(key)
+}
+
+func (self *memoryBackend) Set(key string, value any, ttl time.Duration) error {
+	return self.cache.Set(&cacher.CacheBlock{Key: key, Value: value, TTL: ttl})
+}
+
+func (self *memoryBackend) Delete(key string) error {
+	// 内存缓存删除不存在的键时也返回错误，无需处理
+	self.cache.Delete(key)
+	return nil
+}
+
+func (self *memoryBackend) Close() error {
+	return self.cache.Close()
+}
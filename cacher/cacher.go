@@ -1,12 +1,16 @@
 package cacher
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/volts-dev/cacher"
 	_ "github.com/volts-dev/cacher/memory"
 	"github.com/volts-dev/dataset"
 	"github.com/volts-dev/volts/logger"
@@ -33,8 +37,19 @@ type CacheEntry struct {
 }
 
 // TODO cache name
-// TODO 9缓存必须是支持读写多个ORM共享
 type (
+	// Option 配置 TCacher
+	Option func(*TCacher)
+
+	// tInvalidation 广播给其他实例的失效消息，Ids、Sqls 均为空且非 Results 时清除整张表
+	tInvalidation struct {
+		Node    string   `json:"node"`
+		Table   string   `json:"table"`
+		Ids     []string `json:"ids,omitempty"`     // Id 缓存键
+		Sqls    []string `json:"sqls,omitempty"`    // Sql 缓存键
+		Results bool     `json:"results,omitempty"` // 只清除涉及该表的 Sql 查询结果
+	}
+
 	// FIXME 未提供使用
 	//ModelCacher interface {
 	//	PutById(table string, id interface{}, record *dataset.TRecordSet)
@@ -50,8 +65,12 @@ type (
 		lastCleanupTime          atomic.Int64 // 上次清理过期缓存的时间戳
 		status                   map[string]bool
 		statusLock               sync.RWMutex // 保护 status map 的并发读写
-		id_caches                cacher.ICacher // 缓存Id 对应记录 map[model]record
-		sql_caches               cacher.ICacher // 缓存Sql查询结果
+		node                     string       // 本实例标识，用于忽略自己广播的失效消息
+		backend                  IBackend     // 存储后端，默认进程内缓存
+		broadcaster              IBroadcaster // 后端支持广播时不为空
+		versioner                IVersioner   // 后端可保存表的缓存代数时不为空
+		id_caches                IBackend     // 缓存Id 对应记录 map[model]record
+		sql_caches               IBackend     // 缓存Sql查询结果
 		table_id_key_index       map[string]map[string]bool
 		table_sql_key_index      map[string]map[string]bool
 		table_id_key_index_lock  sync.RWMutex
@@ -65,7 +84,15 @@ type (
 	}
 )
 
-func New() (*TCacher, error) {
+// WithBackend 指定存储后端，如 NewRedisBackend。后端实现 IBroadcaster 时，
+// 本实例的失效操作会广播到共用该后端的其他实例。后端随 TCacher.Close 关闭
+func WithBackend(backend IBackend) Option {
+	return func(self *TCacher) {
+		self.backend = backend
+	}
+}
+
+func New(opts ...Option) (*TCacher, error) {
 	chr := &TCacher{
 		status:              make(map[string]bool),
 		table_id_key_index:  make(map[string]map[string]bool),
@@ -76,24 +103,149 @@ func New() (*TCacher, error) {
 	}
 	chr.ttl.Store(DefaultCacheTTL)
 	chr.lastCleanupTime.Store(time.Now().Unix())
+	for _, opt := range opts {
+		opt(chr)
+	}
+
 	var err error
+	if chr.backend == nil {
+		if chr.id_caches, err = NewMemoryBackend(); err != nil {
+			return nil, err
+		}
+		if chr.sql_caches, err = NewMemoryBackend(); err != nil {
+			return nil, err
+		}
+		return chr, nil
+	}
+
+	// 共享后端上 Id 键与 Sql 键格式不同，可共用同一后端
+	chr.id_caches, chr.sql_caches = chr.backend, chr.backend
+	chr.versioner, _ = chr.backend.(IVersioner)
+	if broadcaster, ok := chr.backend.(IBroadcaster); ok {
+		node := make([]byte, 8)
+		rand.Read(node)
+		chr.node = hex.EncodeToString(node)
+		if err = broadcaster.Subscribe(chr._onInvalidate); err != nil {
+			return nil, err
+		}
+		chr.broadcaster = broadcaster
+	}
+	return chr, nil
+}
+
+// Close 停止后台清理并关闭存储后端
+func (self *TCacher) Close() error {
+	self.StopCleanup()
+	if self.backend != nil {
+		return self.backend.Close()
+	}
+	self.id_caches.Close()
+	return self.sql_caches.Close()
+}
 
-	chr.id_caches, err = cacher.New("memory")
+func (self *TCacher) _ttl() time.Duration {
+	return time.Duration(self.ttl.Load()) * time.Second
+}
+
+// _broadcast 把失效消息发送给共用后端的其他实例
+func (self *TCacher) _broadcast(msg *tInvalidation) {
+	if self.broadcaster == nil {
+		return
+	}
+	msg.Node = self.node
+	data, err := json.Marshal(msg)
+	if err == nil {
+		err = self.broadcaster.Publish(data)
+	}
 	if err != nil {
-		return nil, err
+		log.Warnf("broadcast cache invalidation of table %s failed: %v", msg.Table, err)
+	}
+}
+
+// _onInvalidate 处理其他实例广播的失效消息，只清理本地，不再转发
+func (self *TCacher) _onInvalidate(data []byte) {
+	var msg tInvalidation
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Warnf("invalid cache invalidation message: %v", err)
+		return
 	}
+	if msg.Node == self.node {
+		return
+	}
+
+	if msg.Results {
+		self._clearTable(msg.Table, true)
+		return
+	}
+	if len(msg.Ids) == 0 && len(msg.Sqls) == 0 {
+		self._clearTable(msg.Table, false)
+		return
+	}
+	self._removeIdKeys(msg.Table, msg.Ids)
+	self._removeSqlKeys(msg.Table, msg.Sqls)
+}
 
-	chr.sql_caches, err = cacher.New("memory")
+func idKey(table string, id any) string {
+	return fmt.Sprintf("%v-%#v", table, id) // %#v 使 id 类型敏感，避免类型漂移键冲突
+}
+
+func sqlKey(table string, sql string, args any) string {
+	// 用 %#v 使参数类型敏感（int64(1)/"1"/float64(1) 不再同形），避免类型漂移命中错误缓存键
+	return fmt.Sprintf("%#v-%v-%#v", table, sql, args)
+}
+
+// versionKey 表缓存代数的计数键，kind 为 id 或 sql
+func versionKey(kind string, table string) string {
+	return "version:" + kind + ":" + table
+}
+
+// _version 各表当前缓存代数组成的键后缀，后端不维护代数时为空。
+// 读取代数失败时 ok=false，调用方视为未命中且不写入缓存
+func (self *TCacher) _version(kind string, tables ...string) (suffix string, ok bool) {
+	if self.versioner == nil {
+		return "", true
+	}
+	keys := make([]string, len(tables))
+	for i, table := range tables {
+		keys[i] = versionKey(kind, table)
+	}
+	versions, err := self.versioner.Versions(keys...)
 	if err != nil {
-		return nil, err
+		log.Warnf("read cache version of table %v failed: %v", tables, err)
+		return "", false
 	}
+	for _, version := range versions {
+		suffix += "@" + strconv.FormatInt(version, 10)
+	}
+	return suffix, true
+}
 
-	return chr, nil
+// _bump 递增各表的缓存代数，使所有实例写入的旧缓存不再命中
+func (self *TCacher) _bump(table string, kinds ...string) {
+	if self.versioner == nil {
+		return
+	}
+	for _, kind := range kinds {
+		if _, err := self.versioner.Incr(versionKey(kind, table)); err != nil {
+			log.Warnf("bump cache version of table %s failed: %v", table, err)
+		}
+	}
+}
+
+// sqlTables Sql 缓存结果依赖的表：主表在前，其后为去重后的联接表
+func sqlTables(table string, joins []string) []string {
+	tables := []string{table}
+	for _, join := range joins {
+		if !slices.Contains(tables, join) {
+			tables = append(tables, join)
+		}
+	}
+	return tables
 }
 
 // @removed 是否用于移除（内部版本，不持有锁）
-func (self *TCacher) _genIdKeyUnsafe(table string, key any, removed bool) string {
-	str := fmt.Sprintf("%v-%#v", table, key) // %#v 使 id 类型敏感，避免类型漂移键冲突
+func (self *TCacher) _genIdKeyUnsafe(table string, key any, version string, removed bool) string {
+	str := idKey(table, key) + version
 
 	var (
 		tb  map[string]bool
@@ -124,24 +276,29 @@ func (self *TCacher) _genIdKeyUnsafe(table string, key any, removed bool) string
 	return str
 }
 
-// @removed 是否用于移除
-func (self *TCacher) genIdKey(table string, key any, removed bool) string {
+// @removed 是否用于移除；version 为 _version 返回的代数后缀
+func (self *TCacher) genIdKey(table string, key any, version string, removed bool) string {
 	self.table_id_key_index_lock.Lock()
 	defer self.table_id_key_index_lock.Unlock()
-	return self._genIdKeyUnsafe(table, key, removed)
+	return self._genIdKeyUnsafe(table, key, version, removed)
 }
 
-func (self *TCacher) genSqlKey(table string, sql string, args any, removed bool) string {
+func (self *TCacher) genSqlKey(table string, sql string, args any, version string, removed bool) string {
 	//# lock
 	self.table_sql_key_index_lock.Lock()
 	defer self.table_sql_key_index_lock.Unlock()
-	return self._genSqlKeyUnsafe(table, sql, args, removed)
+	return self._genSqlKeyUnsafe(table, sql, args, version, removed)
 }
 
 // @removed 是否用于移除（内部版本，不持有锁）
-func (self *TCacher) _genSqlKeyUnsafe(table string, sql string, args any, removed bool) string {
-	// 用 %#v 使参数类型敏感（int64(1)/"1"/float64(1) 不再同形），避免类型漂移命中错误缓存键
-	str := fmt.Sprintf("%#v-%v-%#v", table, sql, args)
+func (self *TCacher) _genSqlKeyUnsafe(table string, sql string, args any, version string, removed bool) string {
+	str := sqlKey(table, sql, args) + version
+	self._indexSqlKeyUnsafe(table, str, removed)
+	return str
+}
+
+// _indexSqlKeyUnsafe 在 table 的 Sql 缓存索引中登记或移除 key（不持有锁）
+func (self *TCacher) _indexSqlKeyUnsafe(table string, str string, removed bool) {
 	// # 添加索引
 	var (
 		tb  map[string]bool
@@ -156,7 +313,7 @@ func (self *TCacher) _genSqlKeyUnsafe(table string, sql string, args any, remove
 	// #移除索引
 	if removed {
 		delete(tb, str)
-		return str
+		return
 	} else {
 		// 防止缓存索引无限增长，超过容量时清理
 		if len(tb) >= DefaultMaxCacheSize {
@@ -170,8 +327,6 @@ func (self *TCacher) _genSqlKeyUnsafe(table string, sql string, args any, remove
 		}
 		tb[str] = true
 	}
-
-	return str
 }
 
 // turn on the cacher for query
@@ -221,10 +376,27 @@ func (self *TCacher) getStatus(table string) (open bool, has bool) {
 }
 
 // #缓存Sql查询结果ID集
-func (self *TCacher) PutBySql(table string, sql string, arg any, data *dataset.TDataSet) {
+// joins 为语句联接或引用的其他表，这些表变动时(ClearByTable/ClearSqlByTable)该结果一并失效，
+// 读取时须以相同的 joins 调用 GetBySql
+func (self *TCacher) PutBySql(table string, sql string, arg any, data *dataset.TDataSet, joins ...string) {
 	if open, has := self.getStatus(table); has && open {
-		key := self.genSqlKey(table, sql, arg, false)
-		self.sql_caches.Set(&cacher.CacheBlock{Key: key, Value: data})
+		version, ok := self._version("sql", sqlTables(table, joins)...)
+		if !ok {
+			return
+		}
+		key := self.genSqlKey(table, sql, arg, version, false)
+		if len(joins) > 0 {
+			self.table_sql_key_index_lock.Lock()
+			for _, join := range joins {
+				if join != table {
+					self._indexSqlKeyUnsafe(join, key, false)
+				}
+			}
+			self.table_sql_key_index_lock.Unlock()
+		}
+		if err := self.sql_caches.Set(key, data, self._ttl()); err != nil {
+			log.Warnf("cache sql result of table %s failed: %v", table, err)
+		}
 
 		// 记录过期时间
 		expiryTime := time.Now().Unix() + self.ttl.Load()
@@ -239,11 +411,16 @@ func (self *TCacher) PutBySql(table string, sql string, arg any, data *dataset.T
 
 // #通过Sql获取查询结果ID集
 // @Return:  nil or 空[]string
+// joins 与 PutBySql 时相同。
 // WARNING: 返回的 *dataset.TDataSet 是缓存中的直接引用，请勿修改其内容，否则会污染缓存。
 // 如需修改，请先复制一份副本。
-func (self *TCacher) GetBySql(table string, sql string, arg any) *dataset.TDataSet {
+func (self *TCacher) GetBySql(table string, sql string, arg any, joins ...string) *dataset.TDataSet {
 	if open, has := self.getStatus(table); has && open {
-		key := self.genSqlKey(table, sql, arg, false)
+		version, ok := self._version("sql", sqlTables(table, joins)...)
+		if !ok {
+			return nil
+		}
+		key := self.genSqlKey(table, sql, arg, version, false)
 
 		// 检查缓存是否已过期
 		self.table_sql_expiry_lock.RLock()
@@ -263,7 +440,10 @@ func (self *TCacher) GetBySql(table string, sql string, arg any) *dataset.TDataS
 		if err != nil {
 			return nil
 		}
-		ds := v.(*dataset.TDataSet)
+		ds, ok := v.(*dataset.TDataSet)
+		if !ok {
+			return nil
+		}
 		log.Tracef("Cache hit for table %s, key %s", table, key)
 		return ds
 	}
@@ -275,8 +455,14 @@ func (self *TCacher) GetBySql(table string, sql string, arg any) *dataset.TDataS
 func (self *TCacher) PutById(table string, id any, record *dataset.TRecordSet) {
 	if open, has := self.getStatus(table); !has || (has && open) {
 		//ck := self.RecCacher(table)
-		key := self.genIdKey(table, id, false)
-		self.id_caches.Set(&cacher.CacheBlock{Key: key, Value: record})
+		version, ok := self._version("id", table)
+		if !ok {
+			return
+		}
+		key := self.genIdKey(table, id, version, false)
+		if err := self.id_caches.Set(key, record, self._ttl()); err != nil {
+			log.Warnf("cache record of table %s failed: %v", table, err)
+		}
 
 		// 记录过期时间
 		expiryTime := time.Now().Unix() + self.ttl.Load()
@@ -296,8 +482,12 @@ func (self *TCacher) GetByIds(table string, ids ...any) (records []*dataset.TRec
 	}
 
 	if open, has := self.getStatus(table); !has || (has && open) {
+		version, ok := self._version("id", table)
+		if !ok {
+			return nil, ids
+		}
 		for _, id := range ids {
-			key := self.genIdKey(table, id, false)
+			key := self.genIdKey(table, id, version, false)
 
 			// 检查缓存是否已过期
 			self.table_id_expiry_lock.RLock()
@@ -318,11 +508,12 @@ func (self *TCacher) GetByIds(table string, ids ...any) (records []*dataset.TRec
 			}
 
 			v, err = self.id_caches.Get(key)
-			if err != nil {
+			rec, ok := v.(*dataset.TRecordSet)
+			if err != nil || !ok {
 				ids_less = append(ids_less, id)
 				continue
 			}
-			records = append(records, v.(*dataset.TRecordSet))
+			records = append(records, rec)
 		}
 
 		return records, ids_less
@@ -331,54 +522,101 @@ func (self *TCacher) GetByIds(table string, ids ...any) (records []*dataset.TRec
 	}
 }
 
+// RemoveById 移除记录缓存，后端支持广播时同时通知其他实例
 func (self *TCacher) RemoveById(table string, ids ...any) {
-	self.table_id_key_index_lock.Lock()
-	defer self.table_id_key_index_lock.Unlock()
+	version, _ := self._version("id", table)
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = idKey(table, id) + version
+	}
+	self._removeIdKeys(table, keys)
+	self._broadcast(&tInvalidation{Table: table, Ids: keys})
+}
 
-	if _, has := self.table_id_key_index[table]; has {
-		for _, id := range ids {
-			key := self._genIdKeyUnsafe(table, id, true)
+// RemoveBySql 移除Sql查询结果缓存，后端支持广播时同时通知其他实例
+func (self *TCacher) RemoveBySql(table string, sqls ...string) {
+	version, _ := self._version("sql", table)
+	keys := make([]string, len(sqls))
+	for i, sql := range sqls {
+		keys[i] = sqlKey(table, sql, "") + version
+	}
+	self._removeSqlKeys(table, keys)
+	self._broadcast(&tInvalidation{Table: table, Sqls: keys})
+}
 
-			self.id_caches.Delete(key)
+// ClearByTable 清除表的全部缓存，后端支持广播时同时通知其他实例；
+// 后端可保存缓存代数时递增该表代数，其他实例写入而本实例未记下的键同样失效
+func (self *TCacher) ClearByTable(table string) {
+	self._bump(table, "id", "sql")
+	self._clearTable(table, false)
+	self._broadcast(&tInvalidation{Table: table})
+}
 
-			// 清理过期时间记录
-			self.table_id_expiry_lock.Lock()
-			if _, has := self.table_id_expiry[table]; has {
-				delete(self.table_id_expiry[table], key)
-			}
-			self.table_id_expiry_lock.Unlock()
-		}
+// ClearSqlByTable 只清除涉及表(含联接了该表)的 Sql 查询结果，保留记录缓存。
+// 用于新增记录或已按 Id 移除了变动记录的场景，后端支持广播时同时通知其他实例
+func (self *TCacher) ClearSqlByTable(table string) {
+	self._bump(table, "sql")
+	self._clearTable(table, true)
+	self._broadcast(&tInvalidation{Table: table, Results: true})
+}
+
+// _removeIdKeys 按键移除记录缓存。共享后端上的键可能由其他实例写入，不论本地索引是否有记录都删除
+func (self *TCacher) _removeIdKeys(table string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	self.table_id_key_index_lock.Lock()
+	for _, key := range keys {
+		delete(self.table_id_key_index[table], key)
+		self.id_caches.Delete(key)
+	}
+	self.table_id_key_index_lock.Unlock()
+
+	// 清理过期时间记录
+	self.table_id_expiry_lock.Lock()
+	for _, key := range keys {
+		delete(self.table_id_expiry[table], key)
 	}
+	self.table_id_expiry_lock.Unlock()
 }
 
-func (self *TCacher) RemoveBySql(table string, sqls ...string) {
-	self.table_sql_key_index_lock.Lock()
-	defer self.table_sql_key_index_lock.Unlock()
-	if _, has := self.table_sql_key_index[table]; has {
-		for _, sql := range sqls {
-			key := self._genSqlKeyUnsafe(table, sql, "", true)
+func (self *TCacher) _removeSqlKeys(table string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
 
-			self.sql_caches.Delete(key)
+	self.table_sql_key_index_lock.Lock()
+	for _, key := range keys {
+		delete(self.table_sql_key_index[table], key)
+		self.sql_caches.Delete(key)
+	}
+	self.table_sql_key_index_lock.Unlock()
 
-			// 清理过期时间记录
-			self.table_sql_expiry_lock.Lock()
-			if _, has := self.table_sql_expiry[table]; has {
-				delete(self.table_sql_expiry[table], key)
-			}
-			self.table_sql_expiry_lock.Unlock()
-		}
+	// 清理过期时间记录
+	self.table_sql_expiry_lock.Lock()
+	for _, key := range keys {
+		delete(self.table_sql_expiry[table], key)
 	}
+	self.table_sql_expiry_lock.Unlock()
 }
 
-func (self *TCacher) ClearByTable(table string) {
-	self.table_id_key_index_lock.Lock()
-	if m, has := self.table_id_key_index[table]; has {
-		for key := range m {
-			self.id_caches.Delete(key)
+// _clearTable 清除本实例记下的该表缓存键，sqlOnly 时保留记录缓存
+func (self *TCacher) _clearTable(table string, sqlOnly bool) {
+	if !sqlOnly {
+		self.table_id_key_index_lock.Lock()
+		if m, has := self.table_id_key_index[table]; has {
+			for key := range m {
+				self.id_caches.Delete(key)
+			}
+			delete(self.table_id_key_index, table)
 		}
-		delete(self.table_id_key_index, table)
+		self.table_id_key_index_lock.Unlock()
+
+		self.table_id_expiry_lock.Lock()
+		delete(self.table_id_expiry, table)
+		self.table_id_expiry_lock.Unlock()
 	}
-	self.table_id_key_index_lock.Unlock()
 
 	self.table_sql_key_index_lock.Lock()
 	if m, has := self.table_sql_key_index[table]; has {
@@ -390,10 +628,6 @@ func (self *TCacher) ClearByTable(table string) {
 	self.table_sql_key_index_lock.Unlock()
 
 	// 清理过期时间记录
-	self.table_id_expiry_lock.Lock()
-	delete(self.table_id_expiry, table)
-	self.table_id_expiry_lock.Unlock()
-
 	self.table_sql_expiry_lock.Lock()
 	delete(self.table_sql_expiry, table)
 	self.table_sql_expiry_lock.Unlock()
@@ -0,0 +1,111 @@
+package cacher
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/volts-dev/dataset"
+)
+
+const (
+	valueRecord uint8 = iota + 1
+	valueDataset
+)
+
+type (
+	// tCacheValue 缓存值的序列化形式，供需要跨进程存放缓存的后端使用
+	tCacheValue struct {
+		Kind     uint8
+		Name     string
+		KeyField string
+		Fields   []string
+		Rows     [][]any
+	}
+)
+
+func init() {
+	// 数据库驱动返回的基础类型之外，时间字段以 time.Time 存放
+	gob.Register(time.Time{})
+}
+
+// encodeValue 把缓存的记录或数据集编码为字节。经典模式的数据集含结构化字段值，不予编码
+func encodeValue(value any) ([]byte, error) {
+	var val tCacheValue
+	switch v := value.(type) {
+	case *dataset.TRecordSet:
+		fields := v.Fields()
+		sort.Slice(fields, func(i, j int) bool {
+			return v.GetFieldIndex(fields[i]) < v.GetFieldIndex(fields[j])
+		})
+		val = tCacheValue{Kind: valueRecord, Fields: fields, Rows: [][]any{recordValues(v, fields)}}
+	case *dataset.TDataSet:
+		if v.IsClassic() {
+			return nil, fmt.Errorf("cache: classic dataset %q can not be encoded", v.Name)
+		}
+		val = tCacheValue{Kind: valueDataset, Name: v.Name, KeyField: v.KeyField, Fields: v.Fields()}
+		for _, rec := range v.Data {
+			val.Rows = append(val.Rows, recordValues(rec, val.Fields))
+		}
+	default:
+		return nil, fmt.Errorf("cache: unsupported value type %T", value)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&val); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeValue 还原 encodeValue 编码的记录或数据集
+func decodeValue(data []byte) (any, error) {
+	var val tCacheValue
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&val); err != nil {
+		return nil, err
+	}
+
+	switch val.Kind {
+	case valueRecord:
+		rec := dataset.NewRecordSet()
+		if len(val.Rows) > 0 {
+			setRecordValues(rec, val.Fields, val.Rows[0])
+		}
+		return rec, nil
+	case valueDataset:
+		ds := dataset.NewDataSet()
+		ds.Name = val.Name
+		ds.SetFields(val.Fields...)
+		for _, row := range val.Rows {
+			rec := dataset.NewRecordSet()
+			setRecordValues(rec, val.Fields, row)
+			if err := ds.AppendRecord(rec); err != nil {
+				return nil, err
+			}
+		}
+		if val.KeyField != "" && !ds.SetKeyField(val.KeyField) {
+			ds.KeyField = val.KeyField
+		}
+		ds.First()
+		return ds, nil
+	}
+	return nil, fmt.Errorf("cache: unknown value kind %d", val.Kind)
+}
+
+func recordValues(rec *dataset.TRecordSet, fields []string) []any {
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		values[i] = rec.GetByField(field)
+	}
+	return values
+}
+
+func setRecordValues(rec *dataset.TRecordSet, fields []string, values []any) {
+	for i, field := range fields {
+		if i < len(values) {
+			rec.SetByField(field, values[i])
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package cacher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/volts-dev/cacher"
+)
+
+const (
+	DefaultRedisPrefix   = "orm:"           // 缓存键前缀
+	DefaultRedisChannel  = "orm:invalidate" // 失效广播频道
+	DefaultRedisTimeout  = 3 * time.Second  // 连接与单条命令的超时
+	DefaultRedisPoolSize = 8                // 空闲连接上限
+	redisRetryInterval   = 500 * time.Millisecond
+)
+
+var errRedisClosed = errors.New("cache: redis backend is closed")
+
+type (
+	// RedisOption 配置 TRedisBackend
+	RedisOption func(*TRedisBackend)
+
+	// TRedisBackend 基于 Redis 协议(RESP)的缓存后端。连接同一服务的多个 ORM 实例共享缓存，
+	// 整表清除经由保存在服务上的表缓存代数(见 IVersioner)对所有实例生效，其余失效消息经由发布订阅广播。
+	// 断线期间发布的失效消息会丢失，由缓存 TTL 兜底
+	TRedisBackend struct {
+		addr     string
+		password string
+		db       int
+		prefix   string
+		channel  string
+		timeout  time.Duration
+		idle     chan *redisConn
+		closed   atomic.Bool
+		done     chan struct{}
+		subLock  sync.Mutex
+		sub      *redisConn // 订阅专用连接
+	}
+
+	redisConn struct {
+		conn net.Conn
+		r    *bufio.Reader
+		w    *bufio.Writer
+	}
+
+	redisError string
+)
+
+func (self redisError) Error() string {
+	return "redis: " + string(self)
+}
+
+// WithRedisPassword 连接后以 AUTH 认证
+func WithRedisPassword(password string) RedisOption {
+	return func(self *TRedisBackend) {
+		self.password = password
+	}
+}
+
+// WithRedisDB 连接后以 SELECT 切换到第 db 个库
+func WithRedisDB(db int) RedisOption {
+	return func(self *TRedisBackend) {
+		self.db = db
+	}
+}
+
+// WithRedisPrefix 缓存键前缀，默认 DefaultRedisPrefix。共用一个服务的多套系统应各用不同前缀
+func WithRedisPrefix(prefix string) RedisOption {
+	return func(self *TRedisBackend) {
+		self.prefix = prefix
+	}
+}
+
+// WithRedisChannel 失效广播频道，默认 DefaultRedisChannel
+func WithRedisChannel(channel string) RedisOption {
+	return func(self *TRedisBackend) {
+		self.channel = channel
+	}
+}
+
+// WithRedisTimeout 连接与单条命令的超时，默认 DefaultRedisTimeout
+func WithRedisTimeout(timeout time.Duration) RedisOption {
+	return func(self *TRedisBackend) {
+		self.timeout = timeout
+	}
+}
+
+// WithRedisPoolSize 空闲连接上限，默认 DefaultRedisPoolSize
+func WithRedisPoolSize(size int) RedisOption {
+	return func(self *TRedisBackend) {
+		self.idle = make(chan *redisConn, size)
+	}
+}
+
+// NewRedisBackend 连接 addr(host:port) 上的 Redis 协议服务。连接按需建立，
+// 服务不可用时读取视为未命中，写入失败仅记录日志
+func NewRedisBackend(addr string, opts ...RedisOption) *TRedisBackend {
+	backend := &TRedisBackend{
+		addr:    addr,
+		prefix:  DefaultRedisPrefix,
+		channel: DefaultRedisChannel,
+		timeout: DefaultRedisTimeout,
+		idle:    make(chan *redisConn, DefaultRedisPoolSize),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(backend)
+	}
+	return backend
+}
+
+func (self *TRedisBackend) String() string {
+	return "redis"
+}
+
+func (self *TRedisBackend) Get(key string) (any, error) {
+	reply, err := self.do("GET", self.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, cacher.ErrCacheMiss
+	}
+	return decodeValue(data)
+}
+
+func (self *TRedisBackend) Set(key string, value any, ttl time.Duration) error {
+	data, err := encodeValue(value)
+	if err != nil {
+		return err
+	}
+	args := []any{"SET", self.prefix + key, data}
+	if ttl > 0 {
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+	_, err = self.do(args...)
+	return err
+}
+
+func (self *TRedisBackend) Delete(key string) error {
+	_, err := self.do("DEL", self.prefix+key)
+	return err
+}
+
+func (self *TRedisBackend) Versions(keys ...string) ([]int64, error) {
+	args := make([]any, 0, len(keys)+1)
+	args = append(args, "MGET")
+	for _, key := range keys {
+		args = append(args, self.prefix+key)
+	}
+	reply, err := self.do(args...)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) != len(keys) {
+		return nil, fmt.Errorf("redis: unexpected MGET reply %v", reply)
+	}
+	versions := make([]int64, len(keys))
+	for i, item := range items {
+		if item == nil {
+			continue
+		}
+		if versions[i], err = strconv.ParseInt(string(asBytes(item)), 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+func (self *TRedisBackend) Incr(key string) (int64, error) {
+	reply, err := self.do("INCR", self.prefix+key)
+	if err != nil {
+		return 0, err
+	}
+	version, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected INCR reply %v", reply)
+	}
+	return version, nil
+}
+
+func (self *TRedisBackend) Publish(msg []byte) error {
+	_, err := self.do("PUBLISH", self.channel, msg)
+	return err
+}
+
+// Subscribe 建立订阅连接并在后台接收消息；断线后自动重连，直到 Close
+func (self *TRedisBackend) Subscribe(handler func(msg []byte)) error {
+	conn, err := self.subscribe()
+	if err != nil {
+		return err
+	}
+	go self.receive(conn, handler)
+	return nil
+}
+
+// Close 关闭订阅与全部空闲连接，可安全重复调用
+func (self *TRedisBackend) Close() error {
+	if !self.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	close(self.done)
+
+	self.subLock.Lock()
+	if self.sub != nil {
+		self.sub.conn.Close()
+		self.sub = nil
+	}
+	self.subLock.Unlock()
+
+	for {
+		select {
+		case conn := <-self.idle:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (self *TRedisBackend) subscribe() (*redisConn, error) {
+	conn, err := self.dial()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.do(self.timeout, "SUBSCRIBE", self.channel); err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+	// 订阅连接只接收推送，不设读超时
+	conn.conn.SetDeadline(time.Time{})
+
+	self.subLock.Lock()
+	defer self.subLock.Unlock()
+	if self.closed.Load() {
+		conn.conn.Close()
+		return nil, errRedisClosed
+	}
+	self.sub = conn
+	return conn, nil
+}
+
+func (self *TRedisBackend) receive(conn *redisConn, handler func(msg []byte)) {
+	for {
+		reply, err := conn.read()
+		if err == nil {
+			// 推送格式: ["message", channel, payload]
+			if msg, ok := reply.([]any); ok && len(msg) == 3 && string(asBytes(msg[0])) == "message" {
+				handler(asBytes(msg[2]))
+			}
+			continue
+		}
+
+		conn.conn.Close()
+		for {
+			select {
+			case <-self.done:
+				return
+			case <-time.After(redisRetryInterval):
+			}
+			if conn, err = self.subscribe(); err == nil {
+				break
+			}
+			log.Warnf("redis cache resubscribe failed: %v", err)
+		}
+	}
+}
+
+// do 从连接池取一个连接执行命令。服务返回的错误不影响连接复用
+func (self *TRedisBackend) do(args ...any) (any, error) {
+	if self.closed.Load() {
+		return nil, errRedisClosed
+	}
+
+	var (
+		conn *redisConn
+		err  error
+	)
+	select {
+	case conn = <-self.idle:
+	default:
+		if conn, err = self.dial(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := conn.do(self.timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case self.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+	return reply, err
+}
+
+func (self *TRedisBackend) dial() (*redisConn, error) {
+	nc, err := net.DialTimeout("tcp", self.addr, self.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if self.password != "" {
+		if _, err = conn.do(self.timeout, "AUTH", self.password); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if self.db > 0 {
+		if _, err = conn.do(self.timeout, "SELECT", self.db); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// do 发送一条命令并读取回复
+func (self *redisConn) do(timeout time.Duration, args ...any) (any, error) {
+	if timeout > 0 {
+		self.conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	fmt.Fprintf(self.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		case int:
+			b = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(nil, v, 10)
+		default:
+			b = []byte(fmt.Sprint(v))
+		}
+		fmt.Fprintf(self.w, "$%d\r\n", len(b))
+		self.w.Write(b)
+		self.w.WriteString("\r\n")
+	}
+	if err := self.w.Flush(); err != nil {
+		return nil, err
+	}
+	return self.read()
+}
+
+// read 读取一条 RESP 回复：简单字符串为 string，整数为 int64，批量字符串为 []byte，数组为 []any，空值为 nil
+func (self *redisConn) read() (any, error) {
+	line, err := self.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(self.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = self.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: malformed reply %q", line)
+}
+
+func asBytes(v any) []byte {
+	switch b := v.(type) {
+	case []byte:
+		return b
+	case string:
+		return []byte(b)
+	}
+	return nil
+}
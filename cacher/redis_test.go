@@ -0,0 +1,237 @@
+package cacher
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/volts-dev/dataset"
+)
+
+// fakeRedis 进程内的 Redis 协议服务，支持缓存后端用到的 GET/MGET/SET/INCR/DEL/PUBLISH/SUBSCRIBE
+type fakeRedis struct {
+	sync.Mutex
+	ln          net.Listener
+	data        map[string][]byte
+	subscribers map[string][]*redisConn
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &fakeRedis{ln: ln, data: make(map[string][]byte), subscribers: make(map[string][]*redisConn)}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(&redisConn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)})
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return srv
+}
+
+func (self *fakeRedis) addr() string {
+	return self.ln.Addr().String()
+}
+
+func (self *fakeRedis) serve(conn *redisConn) {
+	defer conn.conn.Close()
+	for {
+		req, err := conn.read()
+		if err != nil {
+			return
+		}
+		items, _ := req.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(asBytes(item))
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		self.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			if v, has := self.data[args[1]]; has {
+				fmt.Fprintf(conn.w, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				conn.w.WriteString("$-1\r\n")
+			}
+		case "MGET":
+			fmt.Fprintf(conn.w, "*%d\r\n", len(args)-1)
+			for _, key := range args[1:] {
+				if v, has := self.data[key]; has {
+					fmt.Fprintf(conn.w, "$%d\r\n%s\r\n", len(v), v)
+				} else {
+					conn.w.WriteString("$-1\r\n")
+				}
+			}
+		case "INCR":
+			n, _ := strconv.ParseInt(string(self.data[args[1]]), 10, 64)
+			n++
+			self.data[args[1]] = strconv.AppendInt(nil, n, 10)
+			fmt.Fprintf(conn.w, ":%d\r\n", n)
+		case "SET":
+			self.data[args[1]] = []byte(args[2])
+			conn.w.WriteString("+OK\r\n")
+		case "DEL":
+			n := 0
+			for _, key := range args[1:] {
+				if _, has := self.data[key]; has {
+					delete(self.data, key)
+					n++
+				}
+			}
+			fmt.Fprintf(conn.w, ":%d\r\n", n)
+		case "PUBLISH":
+			subs := self.subscribers[args[1]]
+			for _, sub := range subs {
+				fmt.Fprintf(sub.w, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(args[2]), args[2])
+				sub.w.Flush()
+			}
+			fmt.Fprintf(conn.w, ":%d\r\n", len(subs))
+		case "SUBSCRIBE":
+			self.subscribers[args[1]] = append(self.subscribers[args[1]], conn)
+			fmt.Fprintf(conn.w, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+		default:
+			fmt.Fprintf(conn.w, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		conn.w.Flush()
+		self.Unlock()
+	}
+}
+
+func newRedisCacher(t *testing.T, srv *fakeRedis) *TCacher {
+	t.Helper()
+	c, err := New(WithBackend(NewRedisBackend(srv.addr())))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.Active(true)
+	c.SetStatus(true, "user")
+	return c
+}
+
+func TestRedisBackend_SharedCache(t *testing.T) {
+	srv := newFakeRedis(t)
+	a, b := newRedisCacher(t, srv), newRedisCacher(t, srv)
+
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	a.PutById("user", int64(1), dataset.NewRecordSet(map[string]any{"id": int64(1), "name": "ann", "created": created, "note": nil}))
+
+	// 另一实例读取到同一份记录，值类型保持不变
+	recs, less := b.GetByIds("user", int64(1), int64(2))
+	if len(recs) != 1 || len(less) != 1 || less[0] != int64(2) {
+		t.Fatalf("GetByIds should hit the shared record, got %d records, missing %v", len(recs), less)
+	}
+	if id, ok := recs[0].GetByField("id").(int64); !ok || id != 1 {
+		t.Fatalf("id should stay int64, got %#v", recs[0].GetByField("id"))
+	}
+	if v, ok := recs[0].GetByField("created").(time.Time); !ok || !v.Equal(created) {
+		t.Fatalf("created should stay time.Time, got %#v", recs[0].GetByField("created"))
+	}
+	if recs[0].GetByField("name") != "ann" || recs[0].GetByField("note") != nil {
+		t.Fatalf("record values changed: %v", recs[0].AsMap())
+	}
+
+	ds := dataset.NewDataSet()
+	ds.NewRecord(map[string]any{"id": int64(1), "name": "ann"})
+	ds.NewRecord(map[string]any{"id": int64(2), "name": "bob"})
+	a.PutBySql("user", "SELECT id, name FROM user", nil, ds)
+	got := b.GetBySql("user", "SELECT id, name FROM user", nil)
+	if got == nil || got.Count() != 2 || fmt.Sprint(got.Keys("name")) != "[ann bob]" {
+		t.Fatalf("GetBySql should hit the shared result, got %v", got)
+	}
+
+	// 删除记录缓存即刻对所有实例生效
+	a.RemoveById("user", int64(1))
+	if recs, _ := b.GetByIds("user", int64(1)); len(recs) != 0 {
+		t.Fatal("RemoveById should drop the shared record")
+	}
+
+	// 其他实例写入的 Sql 缓存经由广播清除
+	b.PutBySql("user", "SELECT count(1) FROM user", nil, ds)
+	a.ClearByTable("user")
+	deadline := time.Now().Add(2 * time.Second)
+	for b.GetBySql("user", "SELECT count(1) FROM user", nil) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("ClearByTable should invalidate results cached by other instances")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if a.GetBySql("user", "SELECT id, name FROM user", nil) != nil {
+		t.Fatal("ClearByTable should drop the local results")
+	}
+}
+
+func TestRedisBackend_ClearByVersion(t *testing.T) {
+	srv := newFakeRedis(t)
+	a, b := newRedisCacher(t, srv), newRedisCacher(t, srv)
+
+	ds := dataset.NewDataSet()
+	ds.NewRecord(map[string]any{"id": int64(1), "name": "ann"})
+	b.SetStatus(true, "order")
+	b.PutById("user", int64(1), dataset.NewRecordSet(map[string]any{"id": int64(1), "name": "ann"}))
+	b.PutBySql("user", "SELECT id, name FROM user", nil, ds)
+	b.PutBySql("order", "SELECT id FROM order JOIN user", nil, ds, "user")
+	b.Close()
+
+	// 写入缓存的实例已退出，其键不在任何实例的本地索引中，清除仍须对之后启动的实例生效
+	c := newRedisCacher(t, srv)
+	c.SetStatus(true, "order")
+	if c.GetBySql("user", "SELECT id, name FROM user", nil) == nil || c.GetBySql("order", "SELECT id FROM order JOIN user", nil, "user") == nil {
+		t.Fatal("results cached by another instance should be shared")
+	}
+	a.ClearSqlByTable("user")
+	d := newRedisCacher(t, srv)
+	d.SetStatus(true, "order")
+	if d.GetBySql("user", "SELECT id, name FROM user", nil) != nil {
+		t.Fatal("ClearSqlByTable should invalidate results cached by every instance")
+	}
+	if d.GetBySql("order", "SELECT id FROM order JOIN user", nil, "user") != nil {
+		t.Fatal("ClearSqlByTable should invalidate results joining the table")
+	}
+	if recs, _ := d.GetByIds("user", int64(1)); len(recs) != 1 {
+		t.Fatal("ClearSqlByTable should keep the record cache")
+	}
+
+	a.ClearByTable("user")
+	if recs, _ := d.GetByIds("user", int64(1)); len(recs) != 0 {
+		t.Fatal("ClearByTable should invalidate records cached by every instance")
+	}
+}
+
+func TestRedisBackend_Unavailable(t *testing.T) {
+	srv := newFakeRedis(t)
+	backend := NewRedisBackend(srv.addr(), WithRedisTimeout(time.Second))
+	c, err := New(WithBackend(backend))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	c.Active(true)
+	srv.ln.Close()
+	backend.Close()
+
+	// 服务不可用时读取视为未命中
+	c.PutById("user", int64(1), dataset.NewRecordSet(map[string]any{"id": int64(1)}))
+	if recs, less := c.GetByIds("user", int64(1)); len(recs) != 0 || len(less) != 1 {
+		t.Fatalf("unavailable backend should miss, got %d records", len(recs))
+	}
+
+	if _, err := New(WithBackend(NewRedisBackend(srv.addr(), WithRedisTimeout(time.Second)))); err == nil {
+		t.Fatal("New should fail when the subscription can not be set up")
+	}
+}
@@ -0,0 +1,381 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/utils"
+)
+
+// maxRecomputeDepth 计算字段级联重算的最大层数，超过视为 depends 成环
+const maxRecomputeDepth = 16
+
+type (
+	// tComputeTrigger 写入某模型的 on 字段时，沿 path 反查 model 上需重算 field 的记录
+	tComputeTrigger struct {
+		model string   // 计算字段所属模型
+		field string   // 存储型计算字段
+		path  []string // model 到被写模型的关系字段路径(many2one/one2many)，空表示同一模型
+		on    string   // 被写模型上触发重算的字段
+	}
+
+	// tComputeTodo 待重算的字段与记录，按模型归集
+	tComputeTodo struct {
+		fields []string
+		ids    []any
+		seen   map[string]bool
+	}
+)
+
+func (self *tComputeTodo) add(field string, ids ...any) {
+	if utils.IndexOf(field, self.fields...) == -1 {
+		self.fields = append(self.fields, field)
+	}
+	for _, id := range ids {
+		if key := utils.ToString(id); !self.seen[key] {
+			self.seen[key] = true
+			self.ids = append(self.ids, id)
+		}
+	}
+}
+
+// _resetComputeTriggers 模型注册/字段变更后使触发表失效，下次使用时重建
+func (self *TOrm) _resetComputeTriggers() {
+	self.computeLock.Lock()
+	self.computeTriggers = nil
+	self.computeLock.Unlock()
+}
+
+// _computeTriggers 返回写入 modelName 时可能触发的全部重算
+func (self *TOrm) _computeTriggers(modelName string) []*tComputeTrigger {
+	self.computeLock.RLock()
+	triggers := self.computeTriggers
+	self.computeLock.RUnlock()
+	if triggers == nil {
+		self.computeLock.Lock()
+		if self.computeTriggers == nil {
+			self.computeTriggers = self._buildComputeTriggers()
+		}
+		triggers = self.computeTriggers
+		self.computeLock.Unlock()
+	}
+	return triggers[modelName]
+}
+
+// _buildComputeTriggers 按 depends 声明展开触发表。
+// 路径 a.b.c 在每一级都登记触发：写 model.a、写 a 指向模型的 b、写 b 指向模型的 c；
+// one2many 段还登记其反向外键，子记录换挂父记录时新旧父记录都会重算。
+func (self *TOrm) _buildComputeTriggers() map[string][]*tComputeTrigger {
+	triggers := make(map[string][]*tComputeTrigger)
+	add := func(on string, trigger *tComputeTrigger) {
+		triggers[on] = append(triggers[on], trigger)
+	}
+
+	self.osv.models.Range(func(key, value any) bool {
+		obj, ok := value.(*TModelObject)
+		if !ok {
+			return true
+		}
+		modelName := key.(string) // 以注册名为准，obj.name 可能是结构体推导的名称
+
+		for _, field := range obj.GetFields() {
+			base := field.Base()
+			if base.computeFunc == nil || len(base.depends) == 0 || !base.store {
+				continue
+			}
+
+			for _, depend := range base.depends {
+				path := strings.Split(depend, ".")
+				current, currentName := obj, modelName
+				for k, name := range path {
+					add(currentName, &tComputeTrigger{model: modelName, field: field.Name(), path: path[:k], on: name})
+					if k == len(path)-1 {
+						break
+					}
+
+					rel := current.GetFieldByName(name)
+					if rel == nil || (rel.TypeName() != TYPE_M2O && rel.TypeName() != TYPE_O2M) {
+						log.Warnf("depends %s of %s.%s: %s is not a many2one/one2many field", depend, modelName, field.Name(), name)
+						break
+					}
+
+					currentName = rel.RelatedModelName()
+					next, ok := self.osv.models.Load(currentName)
+					if !ok {
+						log.Warnf("depends %s of %s.%s: model %s is not registered", depend, modelName, field.Name(), currentName)
+						break
+					}
+					current = next.(*TModelObject)
+
+					if rel.TypeName() == TYPE_O2M {
+						add(currentName, &tComputeTrigger{model: modelName, field: field.Name(), path: path[:k+1], on: rel.RelatedKeyName()})
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	return triggers
+}
+
+// _computeSession 派生会话：沿用本会话事务，以 sudo 读写，避免重算受记录规则影响
+func (self *TSession) _computeSession() *TSession {
+	session := self.Clone()
+	session.IsAutoCommit = self.IsAutoCommit
+	session.IsCommitedOrRollbacked = self.IsCommitedOrRollbacked
+	session.IsAutoClose = false
+	session.sudo = true
+	return session
+}
+
+// _computeTriggered 写入会话模型是否会引起计算字段重算
+func (self *TSession) _computeTriggered() bool {
+	return self.Statement.Model != nil && len(self.orm._computeTriggers(self.Statement.Model.String())) > 0
+}
+
+// _computeAffected 收集写入 modelName 的 fields(为空表示全部字段)后需要重算的记录，
+// 合并进 todo。写入前后各调用一次即可同时覆盖旧关联与新关联。
+func (self *TSession) _computeAffected(todo map[string]*tComputeTodo, modelName string, fields []string, ids []any) (map[string]*tComputeTodo, error) {
+	if len(ids) == 0 {
+		return todo, nil
+	}
+
+	for _, trigger := range self.orm._computeTriggers(modelName) {
+		if fields != nil && utils.IndexOf(trigger.on, fields...) == -1 {
+			continue
+		}
+
+		affected := ids
+		if len(trigger.path) > 0 {
+			var err error
+			if affected, err = self._computeInverse(trigger, ids); err != nil {
+				return nil, err
+			}
+		}
+		if len(affected) == 0 {
+			continue
+		}
+
+		if todo == nil {
+			todo = make(map[string]*tComputeTodo)
+		}
+		if todo[trigger.model] == nil {
+			todo[trigger.model] = &tComputeTodo{seen: make(map[string]bool)}
+		}
+		todo[trigger.model].add(trigger.field, affected...)
+	}
+
+	return todo, nil
+}
+
+// _computeInverse 沿 trigger.path 反向查找：由路径末端模型的 ids 得到 trigger.model 的 ids
+func (self *TSession) _computeInverse(trigger *tComputeTrigger, ids []any) ([]any, error) {
+	// 正向解析路径上每一级的模型与关系字段
+	models := make([]IModel, len(trigger.path)+1)
+	fields := make([]IField, len(trigger.path))
+	model, err := self.orm.osv.GetModel(trigger.model)
+	if err != nil {
+		return nil, err
+	}
+	models[0] = model
+	for i, name := range trigger.path {
+		if fields[i] = models[i].GetFieldByName(name); fields[i] == nil {
+			return nil, fmt.Errorf("depends path %s of %s: field %s not found", strings.Join(trigger.path, "."), trigger.model, name)
+		}
+		if models[i+1], err = self.orm.osv.GetModel(fields[i].RelatedModelName()); err != nil {
+			return nil, err
+		}
+	}
+
+	session := self._computeSession()
+	quoter := self.orm.dialect.Quoter()
+	for i := len(fields) - 1; i >= 0 && len(ids) > 0; i-- {
+		var table, key, col string
+		if fields[i].TypeName() == TYPE_O2M {
+			// 子表外键即父记录 id
+			table, key, col = models[i+1].Table(), fields[i].RelatedKeyName(), models[i+1].IdField()
+		} else {
+			table, key, col = models[i].Table(), models[i].IdField(), fields[i].Name()
+		}
+
+		// IN 列表按批拆分，避免超出方言的参数上限
+		next := make([]any, 0, len(ids))
+		for start := 0; start < len(ids); start += DefaultBatchSize {
+			chunk := ids[start:min(start+DefaultBatchSize, len(ids))]
+			sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)",
+				quoter.Quote(key), quoter.QuoteTable(self.Schema, table), quoter.Quote(col), idsToSqlHolder(chunk...))
+			ds, err := session._query(sql, chunk...)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, ds.Keys(key)...)
+		}
+		ids = next
+	}
+
+	return ids, nil
+}
+
+// _runRecompute 依次重算 todo 中的记录；重算改变的值继续触发依赖它的计算字段
+func (self *TSession) _runRecompute(todo map[string]*tComputeTodo, depth int) error {
+	for modelName, item := range todo {
+		if err := self._recompute(modelName, item.fields, item.ids, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// _recompute 调用计算函数并把结果写回数据库，只写值有变化的记录。
+// 记录按 DefaultBatchSize 分批读取与计算，各批改变的值汇总后再触发下一层重算
+func (self *TSession) _recompute(modelName string, fieldNames []string, ids []any, depth int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if depth > maxRecomputeDepth {
+		return fmt.Errorf("orm: recompute of %s exceeds %d levels, check depends() for cycles", modelName, maxRecomputeDepth)
+	}
+
+	model, err := self.orm.osv.GetModel(modelName)
+	if err != nil {
+		return err
+	}
+
+	var todo map[string]*tComputeTodo
+	for start := 0; start < len(ids); start += DefaultBatchSize {
+		if todo, err = self._recomputeBatch(todo, model, fieldNames, ids[start:min(start+DefaultBatchSize, len(ids))]); err != nil {
+			return err
+		}
+	}
+
+	return self._runRecompute(todo, depth+1)
+}
+
+// _recomputeBatch 重算一批记录，改变了值的记录需触发的重算合并进 todo
+func (self *TSession) _recomputeBatch(todo map[string]*tComputeTodo, model IModel, fieldNames []string, ids []any) (map[string]*tComputeTodo, error) {
+	modelName := model.String()
+	session := self._computeSession()
+	ds, err := session.Model(modelName).Ids(ids...).Limit(-1).Read()
+	if err != nil {
+		return nil, err
+	}
+	if ds.Count() == 0 {
+		return todo, nil
+	}
+
+	idField := model.IdField()
+	records := make([]*dataset.TRecordSet, 0, ds.Count())
+	recIds := make([]any, 0, ds.Count())
+	ds.Range(func(pos int, record *dataset.TRecordSet) error {
+		records = append(records, record)
+		recIds = append(recIds, record.GetByField(idField))
+		return nil
+	})
+
+	quoter := self.orm.dialect.Quoter()
+	for _, name := range fieldNames {
+		field := model.GetFieldByName(name)
+		if field == nil || field.Base().computeFunc == nil {
+			continue
+		}
+
+		ctx := &TFieldContext{
+			Session: session,
+			Model:   model,
+			Dataset: ds,
+			Field:   field,
+			Ids:     recIds,
+			Context: self.context,
+		}
+		if err = field.Base().computeFunc(ctx); err != nil {
+			return nil, err
+		}
+
+		// 计算函数返回与 Ids 等长的 []any 时逐条对应，否则整体取同一个值
+		values, ok := ctx.values.([]any)
+		if !ok || len(values) != len(recIds) {
+			values = make([]any, len(recIds))
+			for i := range values {
+				values[i] = ctx.values
+			}
+		}
+
+		sql := fmt.Sprintf("UPDATE %s SET %s=? WHERE %s=?",
+			quoter.QuoteTable(self.Schema, model.Table()), quoter.Quote(name), quoter.Quote(idField))
+		changed := make([]any, 0, len(recIds))
+		for i, id := range recIds {
+			if auditValue(records[i].GetByField(name)) == auditValue(values[i]) {
+				continue
+			}
+			if _, err = session._exec(sql, field.onConvertToWrite(session, values[i]), id); err != nil {
+				return nil, err
+			}
+			session._invalidateIds(model.Table(), id)
+			changed = append(changed, id)
+		}
+
+		if todo, err = self._computeAffected(todo, modelName, []string{name}, changed); err != nil {
+			return nil, err
+		}
+	}
+
+	return todo, nil
+}
+
+// Recompute 重算会话模型的存储型计算字段(fields 为空表示全部)，用于补算历史数据或修改计算逻辑后的维护。
+// 按 Ids()/Domain() 定位记录，未指定条件时重算全部记录。
+func (self *TSession) Recompute(fields ...string) (err error) {
+	defer self._resetStatement()
+	if self.IsAutoClose {
+		defer self.Close()
+	}
+
+	if self.IsDeprecated {
+		return ErrInvalidSession
+	}
+
+	model := self.Statement.Model
+	if model == nil || len(model.String()) < 1 {
+		return ErrTableNotFound
+	}
+
+	if len(fields) == 0 {
+		for _, field := range model.GetFields() {
+			if field.Base().computeFunc != nil && field.Store() {
+				fields = append(fields, field.Name())
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	ids := self.Statement.IdParam
+	if len(ids) == 0 {
+		// 与 _recomputeBatch 一致以 sudo 定位记录，调用者的记录规则不应让部分记录漏算
+		sudo := self.sudo
+		self.sudo = true
+		ids, _, err = self._search("", nil)
+		self.sudo = sudo
+		if err != nil {
+			return err
+		}
+	}
+
+	if self.IsAutoCommit {
+		if err = self.Begin(); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				self.Rollback(err)
+				return
+			}
+			err = self.Commit()
+		}()
+	}
+
+	return self._recompute(model.String(), fields, ids, 0)
+}
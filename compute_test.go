@@ -0,0 +1,229 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/volts-dev/dataset"
+)
+
+type (
+	ComputePartner struct {
+		TModel `table:"name('cp_partner')"`
+		Id     int64  `field:"pk autoincr"`
+		Name   string `field:"varchar() size(64)"`
+	}
+
+	ComputeLine struct {
+		TModel  `table:"name('cp_line')"`
+		Id      int64 `field:"pk autoincr"`
+		Qty     int   `field:"int()"`
+		Price   int   `field:"int()"`
+		Amount  int   `field:"int() compute('ComputeAmount') depends('qty','price')"`
+		OrderId int64 `field:"many2one(cp_order)"`
+	}
+
+	ComputeOrder struct {
+		TModel      `table:"name('cp_order')"`
+		Id          int64  `field:"pk autoincr"`
+		PartnerId   int64  `field:"many2one(cp_partner)"`
+		PartnerName string `field:"varchar() size(64) compute('ComputePartnerName') depends('partner_id.name')"`
+		Total       int    `field:"int() compute('ComputeTotal') depends('lines.amount')"`
+		Lines       []any  `field:"one2many(cp_line,order_id)"`
+	}
+)
+
+func (self *ComputeLine) ComputeAmount(ctx *TFieldContext) error {
+	values := make([]any, 0, len(ctx.Ids))
+	ctx.Dataset.Range(func(pos int, record *dataset.TRecordSet) error {
+		values = append(values, record.FieldByName("qty").AsInteger()*record.FieldByName("price").AsInteger())
+		return nil
+	})
+	return ctx.SetValue(values)
+}
+
+func (self *ComputeOrder) ComputeTotal(ctx *TFieldContext) error {
+	values := make([]any, 0, len(ctx.Ids))
+	for _, id := range ctx.Ids {
+		ds, err := ctx.Session.Query(`SELECT COALESCE(SUM(amount), 0) AS total FROM cp_line WHERE order_id = ?`, id)
+		if err != nil {
+			return err
+		}
+		values = append(values, ds.FieldByName("total").AsInteger())
+	}
+	return ctx.SetValue(values)
+}
+
+func (self *ComputeOrder) ComputePartnerName(ctx *TFieldContext) error {
+	values := make([]any, 0, len(ctx.Ids))
+	for _, id := range ctx.Ids {
+		ds, err := ctx.Session.Query(`SELECT p.name FROM cp_partner p JOIN cp_order o ON o.partner_id = p.id WHERE o.id = ?`, id)
+		if err != nil {
+			return err
+		}
+		values = append(values, ds.FieldByName("name").AsString())
+	}
+	return ctx.SetValue(values)
+}
+
+func computedValue(t *testing.T, o *TOrm, model string, id any, field string) *dataset.TFieldSet {
+	t.Helper()
+	ds, err := o.NewSession().Model(model).Ids(id).Read()
+	if err != nil || ds.Count() != 1 {
+		t.Fatalf("Read %s(%v): %v", model, id, err)
+	}
+	return ds.FieldByName(field)
+}
+
+func TestCompute_RecomputedOnDependencyWrites(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(ComputePartner), new(ComputeOrder), new(ComputeLine)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	partner, err := o.NewSession().Model("cp_partner").Create(map[string]any{"name": "ACME"})
+	if err != nil {
+		t.Fatalf("Create partner: %v", err)
+	}
+	orders, err := o.NewSession().Model("cp_order").Create(map[string]any{"partner_id": partner[0]}, map[string]any{"partner_id": partner[0]})
+	if err != nil {
+		t.Fatalf("Create orders: %v", err)
+	}
+	if name := computedValue(t, o, "cp_order", orders[0], "partner_name").AsString(); name != "ACME" {
+		t.Fatalf("new order should compute partner_name, got %q", name)
+	}
+
+	// 新建子记录：自身计算字段与经 one2many 依赖它的父记录都重算
+	line, err := o.NewSession().Model("cp_line").Create(map[string]any{"qty": 2, "price": 5, "order_id": orders[0]})
+	if err != nil {
+		t.Fatalf("Create line: %v", err)
+	}
+	if amount := computedValue(t, o, "cp_line", line[0], "amount").AsInteger(); amount != 10 {
+		t.Fatalf("line amount should be 10, got %d", amount)
+	}
+	if total := computedValue(t, o, "cp_order", orders[0], "total").AsInteger(); total != 10 {
+		t.Fatalf("order total should be 10, got %d", total)
+	}
+
+	// 写依赖字段：级联重算 amount -> total
+	if _, err = o.NewSession().Model("cp_line").Ids(line...).Write(map[string]any{"qty": 3}); err != nil {
+		t.Fatalf("Write line: %v", err)
+	}
+	if total := computedValue(t, o, "cp_order", orders[0], "total").AsInteger(); total != 15 {
+		t.Fatalf("order total should follow line qty, got %d", total)
+	}
+
+	// many2one 点路径
+	if _, err = o.NewSession().Model("cp_partner").Ids(partner...).Write(map[string]any{"name": "ACME Ltd"}); err != nil {
+		t.Fatalf("Write partner: %v", err)
+	}
+	for _, id := range orders {
+		if name := computedValue(t, o, "cp_order", id, "partner_name").AsString(); name != "ACME Ltd" {
+			t.Fatalf("order %v partner_name should follow partner, got %q", id, name)
+		}
+	}
+
+	// 子记录换挂父记录：新旧父记录都重算
+	if _, err = o.NewSession().Model("cp_line").Ids(line...).Write(map[string]any{"order_id": orders[1]}); err != nil {
+		t.Fatalf("Move line: %v", err)
+	}
+	if total := computedValue(t, o, "cp_order", orders[0], "total").AsInteger(); total != 0 {
+		t.Fatalf("old order total should drop to 0, got %d", total)
+	}
+	if total := computedValue(t, o, "cp_order", orders[1], "total").AsInteger(); total != 15 {
+		t.Fatalf("new order total should be 15, got %d", total)
+	}
+
+	// 维护调用：绕过 ORM 改坏的存量数据可整体补算
+	if _, err = o.NewSession().Exec(`UPDATE cp_order SET total = 99, partner_name = ''`); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if err = o.NewSession().Model("cp_order").Recompute(); err != nil {
+		t.Fatalf("Recompute: %v", err)
+	}
+	if total := computedValue(t, o, "cp_order", orders[1], "total").AsInteger(); total != 15 {
+		t.Fatalf("Recompute should restore total, got %d", total)
+	}
+	if name := computedValue(t, o, "cp_order", orders[0], "partner_name").AsString(); name != "ACME Ltd" {
+		t.Fatalf("Recompute should restore partner_name, got %q", name)
+	}
+}
+
+func TestCompute_RecomputeBeyondDefaultLimit(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(ComputePartner), new(ComputeOrder), new(ComputeLine)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	const n = DefaultLimit + 100
+	lines := make([]any, n)
+	for i := range lines {
+		lines[i] = map[string]any{"qty": 1, "price": 1}
+	}
+	ids, err := o.NewSession().Model("cp_line").Create(lines...)
+	if err != nil || len(ids) != n {
+		t.Fatalf("Create lines: %d ids, %v", len(ids), err)
+	}
+
+	countAmount := func(amount int) int64 {
+		ds, err := o.NewSession().Query(`SELECT count(1) AS cnt FROM cp_line WHERE amount = ?`, amount)
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		return ds.FieldByName("cnt").AsInteger()
+	}
+
+	// 依赖触发的重算覆盖全部记录
+	if _, err = o.NewSession().Model("cp_line").Ids(ids...).Write(map[string]any{"qty": 2}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := countAmount(2); got != n {
+		t.Fatalf("dependency recompute should update all %d lines, got %d", n, got)
+	}
+
+	if _, err = o.NewSession().Exec(`UPDATE cp_line SET amount = 0`); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if err = o.NewSession().Model("cp_line").Recompute(); err != nil {
+		t.Fatalf("Recompute: %v", err)
+	}
+	if got := countAmount(2); got != n {
+		t.Fatalf("Recompute should restore all %d lines, got %d", n, got)
+	}
+}
+
+func TestCompute_RecomputeIgnoresRecordRules(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(ComputePartner), new(ComputeOrder), new(ComputeLine)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	ids, err := o.NewSession().Model("cp_line").Create(
+		map[string]any{"qty": 1, "price": 3},
+		map[string]any{"qty": 2, "price": 3},
+	)
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("Create lines: %d ids, %v", len(ids), err)
+	}
+	if err = o.AddRecordRule(&TRecordRule{Name: "small", Model: "cp.line", Domain: `[('qty', '<', 2)]`}); err != nil {
+		t.Fatalf("AddRecordRule: %v", err)
+	}
+
+	// 记录规则隐藏了部分记录，重算仍须覆盖全部
+	if _, err = o.NewSession().Exec(`UPDATE cp_line SET amount = 0`); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	ctx := WithUser(context.Background(), "bob", "user")
+	if err = o.NewSession().WithContext(ctx).Model("cp_line").Recompute(); err != nil {
+		t.Fatalf("Recompute: %v", err)
+	}
+	ds, err := o.NewSession().Query(`SELECT amount FROM cp_line WHERE id = ?`, ids[1])
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if amount := ds.FieldByName("amount").AsInteger(); amount != 6 {
+		t.Fatalf("Recompute should reach records hidden by record rules, got %d", amount)
+	}
+}
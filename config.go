@@ -1,6 +1,11 @@
 package orm
 
-import "time"
+import (
+	"database/sql"
+	"time"
+
+	"github.com/volts-dev/orm/cacher"
+)
 
 type (
 	Option func(*Config)
@@ -35,8 +40,44 @@ type (
 		//
 		// 契约:开启后不再自动建表/补列,库结构必须先由迁移就位,否则运行期查询
 		// 会因缺表/缺列失败。需要跑一次同步时(如上线新版本),关掉本开关启动一次
-		// 或用专门的迁移入口。
+		// 或用迁移入口 TOrm.Migrator()(生成/审阅/加锁应用版本化迁移)。
 		DisableSchemaSync bool
+
+		// AllowDestructiveSync 为 true 时 SyncModel 放行破坏性结构变更(收窄类型/长度、
+		// 对含 NULL 的列加 NOT NULL、按 oldname 改列名)。默认 false:遇到此类变更
+		// 返回 *UnsafeSchemaError 并列出全部变更,不执行任何 DDL。
+		AllowDestructiveSync bool
+
+		// DefaultLang 翻译字段的默认语言，会话未经 WithLang 指定语言或该语言缺值时使用。默认 DefaultLang(en_US)
+		DefaultLang string
+
+		// TxIsolation 会话开启事务的默认隔离级别，会话可经 TSession.Isolation 另行指定。
+		// 默认 sql.LevelDefault，即数据库自身的默认级别
+		TxIsolation sql.IsolationLevel
+
+		// Replicas 只读副本。非事务中的 Read/Search/Count/Sum/Query 按权重轮询分发到健康的副本；
+		// 写入、加锁读取、事务内的一切语句以及写入后 ReplicaStickyWindow 内同一会话的读取走主库
+		Replicas []*TReplica
+		// ReplicaCheckInterval 副本健康检查(Ping)间隔，默认 DefaultReplicaCheckInterval；<0 关闭定期检查
+		ReplicaCheckInterval time.Duration
+		// ReplicaStickyWindow 会话写入后该时长内的读取仍走主库(读己之写)，默认 DefaultReplicaStickyWindow；<0 关闭
+		ReplicaStickyWindow time.Duration
+
+		// Shards 分片库，按下标编号。设置了分片解析器(见 WithShardResolver)的模型按分片键路由到其中之一，
+		// 其余模型仍在主库；SyncModel 在主库与每个分片上同步表结构。未填写的账号与 SSL 设置沿用主库
+		Shards []*TDataSource
+
+		// CacheBackend Id 与 Sql 缓存的存储后端，为空时使用进程内缓存。使用共享后端(如 cacher.NewRedisBackend)时
+		// 多个 ORM 实例共享缓存，一处的 RemoveById/ClearByTable 会广播到其余实例。后端随 TOrm.Close 关闭
+		CacheBackend cacher.IBackend
+
+		// Tenancy 多租户模式。会话经 WithContext 附加租户(见 WithTenant)后：TenancySchema 下
+		// 使用该租户的 schema；TenancyColumn 下含 TenantField 的模型按租户过滤读写，新建时归属该租户
+		Tenancy TenancyMode
+		// TenantField 列模式的租户字段，默认 DefaultTenantField
+		TenantField string
+		// TenantSchema schema 模式下由租户得出 schema 名，默认 DefaultTenantSchema
+		TenantSchema func(tenant any) string
 	}
 )
 
@@ -47,6 +88,9 @@ func newConfig(opts ...Option) *Config {
 		ShowSqlTime:     true,
 		FieldIdentifier: FieldIdentifier,
 		TableIdentifier: TableIdentifier,
+
+		ReplicaCheckInterval: DefaultReplicaCheckInterval,
+		ReplicaStickyWindow:  DefaultReplicaStickyWindow,
 	}
 
 	cfg.Init(opts...)
@@ -122,3 +166,81 @@ func WithDisableSchemaSync(on bool) Option {
 		cfg.DisableSchemaSync = on
 	}
 }
+
+// WithDefaultLang 设置翻译字段的默认语言。见 Config.DefaultLang。
+func WithDefaultLang(lang string) Option {
+	return func(cfg *Config) {
+		cfg.DefaultLang = lang
+	}
+}
+
+// WithTxIsolation 设置事务的默认隔离级别。见 Config.TxIsolation。
+func WithTxIsolation(level sql.IsolationLevel) Option {
+	return func(cfg *Config) {
+		cfg.TxIsolation = level
+	}
+}
+
+// WithReplicas 添加只读副本。见 Config.Replicas。
+func WithReplicas(replicas ...*TReplica) Option {
+	return func(cfg *Config) {
+		cfg.Replicas = append(cfg.Replicas, replicas...)
+	}
+}
+
+// WithReplicaCheckInterval 设置副本健康检查间隔。见 Config.ReplicaCheckInterval。
+func WithReplicaCheckInterval(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.ReplicaCheckInterval = d
+	}
+}
+
+// WithReplicaStickyWindow 设置写入后读取仍走主库的时长。见 Config.ReplicaStickyWindow。
+func WithReplicaStickyWindow(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.ReplicaStickyWindow = d
+	}
+}
+
+// WithShards 添加分片库。见 Config.Shards。
+func WithShards(shards ...*TDataSource) Option {
+	return func(cfg *Config) {
+		cfg.Shards = append(cfg.Shards, shards...)
+	}
+}
+
+// WithCacheBackend 指定缓存存储后端。见 Config.CacheBackend。
+func WithCacheBackend(backend cacher.IBackend) Option {
+	return func(cfg *Config) {
+		cfg.CacheBackend = backend
+	}
+}
+
+// WithSchemaTenancy 启用 schema 模式多租户，naming 为空时使用 DefaultTenantSchema。见 Config.Tenancy。
+func WithSchemaTenancy(naming func(tenant any) string) Option {
+	return func(cfg *Config) {
+		if naming == nil {
+			naming = DefaultTenantSchema
+		}
+		cfg.Tenancy = TenancySchema
+		cfg.TenantSchema = naming
+	}
+}
+
+// WithColumnTenancy 启用列模式多租户，field 为空时使用 DefaultTenantField。见 Config.Tenancy。
+func WithColumnTenancy(field string) Option {
+	return func(cfg *Config) {
+		if field == "" {
+			field = DefaultTenantField
+		}
+		cfg.Tenancy = TenancyColumn
+		cfg.TenantField = field
+	}
+}
+
+// WithAllowDestructiveSync 放行 SyncModel 的破坏性结构变更。见 Config.AllowDestructiveSync。
+func WithAllowDestructiveSync(on bool) Option {
+	return func(cfg *Config) {
+		cfg.AllowDestructiveSync = on
+	}
+}
@@ -1,12 +1,25 @@
 package orm
 
+import "time"
+
 const (
 	DefaultLimit        = 500
+	DefaultBatchSize    = 500  // 流式读取、批量插入等按批处理时每批的记录数
+	BulkCopyThreshold   = 1000 // Postgres 上同列批量新建达到该条数时改用 COPY
+	TxMaxRetries        = 3    // Transaction 遇序列化失败/死锁时的最多重试次数
+	TxRetryInterval     = 20 * time.Millisecond
 	DefaultIdField      = "id"
 	DefaultNameField    = "name"
+	DefaultTenantField  = "tenant_id" // 列模式多租户的租户字段，见 WithColumnTenancy
 	DefaultIndexPrefix  = "IDX_"
 	DefaultUniquePrefix = "UQE_"
 
 	FieldIdentifier = "field"
 	TableIdentifier = "table"
 )
+
+// 只读副本的健康检查间隔与写入后读取仍走主库的时长，见 Config.Replicas
+const (
+	DefaultReplicaCheckInterval = 10 * time.Second
+	DefaultReplicaStickyWindow  = 2 * time.Second
+)
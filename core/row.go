@@ -119,7 +119,11 @@ func fieldByName(v reflect.Value, name string) reflect.Value {
 	return reflect.Value{}
 }
 
-// ScanStructByName scan data to a struct's pointer according field name
+// scannerType 自行处理 NULL 的字段(sql.Null* 等)直接交给驱动扫描
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// ScanStructByName scan data to a struct's pointer according field name.
+// NULL 列在非指针字段上得到零值
 func (rs *Rows) ScanStructByName(dest any) error {
 	vv := reflect.ValueOf(dest)
 	if vv.Kind() != reflect.Ptr || vv.Elem().Kind() != reflect.Struct {
@@ -132,17 +136,35 @@ func (rs *Rows) ScanStructByName(dest any) error {
 	}
 
 	newDest := make([]any, len(cols))
+	nulls := make(map[int]reflect.Value)
 	var v EmptyScanner
 	for j, name := range cols {
 		f := fieldByName(vv.Elem(), rs.db.Mapper.Table2Obj(name))
-		if f.IsValid() {
-			newDest[j] = f.Addr().Interface()
-		} else {
+		switch {
+		case !f.IsValid():
 			newDest[j] = &v
+		case f.Kind() == reflect.Ptr || f.Addr().Type().Implements(scannerType):
+			newDest[j] = f.Addr().Interface()
+		default:
+			// 非指针字段经 **T 中转：NULL 得 nil 指针后归零值，而非报 converting NULL 错误
+			holder := reflect.New(reflect.PointerTo(f.Type()))
+			nulls[j] = holder
+			newDest[j] = holder.Interface()
 		}
 	}
 
-	return rs.Rows.Scan(newDest...)
+	if err := rs.Rows.Scan(newDest...); err != nil {
+		return err
+	}
+	for j, holder := range nulls {
+		f := fieldByName(vv.Elem(), rs.db.Mapper.Table2Obj(cols[j]))
+		if ptr := holder.Elem(); ptr.IsNil() {
+			f.SetZero()
+		} else {
+			f.Set(ptr.Elem())
+		}
+	}
+	return nil
 }
 
 // ScanSlice scan data to a slice's pointer, slice's length should equal to columns' number
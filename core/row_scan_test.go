@@ -105,3 +105,43 @@ func TestScanMap_ConcurrentNoRace(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+// TestScanStructByName_NullToZero NULL 列扫描到非指针字段时得零值，指针字段得 nil
+func TestScanStructByName_NullToZero(t *testing.T) {
+	db, err := Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, parent_id INTEGER, note TEXT)`); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO t (id, name, parent_id, note) VALUES (1, NULL, NULL, NULL)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id, name, parent_id, note FROM t`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+
+	type Row struct {
+		Id       int64
+		Name     string
+		ParentId int64
+		Note     *string
+	}
+	if !rows.Next() {
+		t.Fatal("expected a row")
+	}
+	r := Row{Name: "stale", ParentId: 9}
+	if err := rows.ScanStructByName(&r); err != nil {
+		t.Fatalf("ScanStructByName: %v", err)
+	}
+	if r.Id != 1 || r.Name != "" || r.ParentId != 0 || r.Note != nil {
+		t.Fatalf("NULL columns should scan to zero values: %+v", r)
+	}
+}
@@ -39,6 +39,27 @@ func NewDataSource(dbtype, dbName, host, port, sslMode, userName, password strin
 		Password: password,
 	}
 }
+
+// inherit 以主库设置补全副本/分片的数据源：库类型一律沿用主库，未填写的库名、账号、SSL 与 schema 取主库
+func (self *TDataSource) inherit(primary *TDataSource) *TDataSource {
+	ds := *self
+	ds.DbType = primary.DbType
+	if ds.DbName == "" {
+		ds.DbName = primary.DbName
+	}
+	if ds.UserName == "" {
+		ds.UserName = primary.UserName
+		ds.Password = primary.Password
+	}
+	if ds.SSLMode == "" {
+		ds.SSLMode = primary.SSLMode
+	}
+	if ds.Schema == "" {
+		ds.Schema = primary.Schema
+	}
+	return &ds
+}
+
 func (self *TDataSource) validate() error {
 	if self.Host == "" {
 		self.Host = "127.0.0.1"
@@ -57,8 +57,19 @@ type (
 		DropColumnNotNullSql(schema, tableName string, col IField) string
 		DropColumnDefaultSql(schema, tableName string, col IField) string
 		ModifyColumnSql(schema, tableName string, col IField) string
-		ForUpdateSql(query string) string
+		DropColumnSql(schema, tableName string, col IField) string
+		RenameColumnSql(schema, tableName, oldName, newName string) string
+		// CheckTxOptions 校验事务的隔离级别与只读模式，不支持时返回 ErrTxOptionsNotSupported
+		CheckTxOptions(opts *sql.TxOptions) error
+		// LockSql 为查询语句追加行锁子句，不支持的锁模式返回 ErrLockNotSupported
+		LockSql(query string, lock *Lock) (string, error)
 		GenInsertSql(model string, fields, uniqueFields []string, idField string, onConflict *OnConflict) (sql string)
+		// GenBulkInsertSql 生成 rows 行的多行 INSERT；冲突更新取本行待插入值(EXCLUDED)而非绑定参数
+		GenBulkInsertSql(model string, fields []string, rows int, uniqueFields []string, idField string, onConflict *OnConflict) (sql string)
+		// GenBulkUpdateSql 生成一条语句内按记录取不同值的批量 UPDATE；
+		// rows[i] 为 ids[i] 记录按 fields 排列的新值，返回语句与按占位符排列的参数；
+		// version 非空时版本列递增，versions 非空时仅更新版本仍为 versions[i] 的记录
+		GenBulkUpdateSql(model string, idField IField, fields []IField, ids []any, rows [][]any, version IField, versions []any) (sql string, params []any)
 		GenAddColumnSQL(schema, tableName string, field IField) string
 		IsColumnExist(ctx context.Context, schema, tableName string, colName string) (bool, error)
 		IsDatabaseExist(ctx context.Context, name string) bool
@@ -75,6 +86,8 @@ type (
 		Fmter() []IFmter // TODO 考虑移除 由于无法满足query获得model对象
 		SetParams(params map[string]string)
 		SupportReturning() bool
+		// MaxParams 单条语句可绑定的参数上限，批量语句据此拆分
+		MaxParams() int
 
 		// MapError 把 driver 原生错误翻译为 errors 包定义的 sentinel
 		// 各 dialect 必须实现；session 层统一调用
@@ -158,6 +171,10 @@ func (db *TDialect) SupportReturning() bool {
 	return false
 }
 
+func (db *TDialect) MaxParams() int {
+	return 999
+}
+
 func (db *TDialect) AndStr() string {
 	return "AND"
 }
@@ -292,6 +309,18 @@ func (db *TDialect) ModifyColumnSql(schema, tableName string, col IField) string
 	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", db.quoter.QuoteTable(schema, tableName), s)
 }
 
+// DropColumnSql 生成删除列语句，作为补列(GenAddColumnSQL)的撤销语句供迁移回滚使用
+func (db *TDialect) DropColumnSql(schema, tableName string, col IField) string {
+	quoter := db.dialect.Quoter()
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoter.QuoteTable(schema, tableName), quoter.Quote(col.Name()))
+}
+
+func (db *TDialect) RenameColumnSql(schema, tableName, oldName, newName string) string {
+	quoter := db.dialect.Quoter()
+	return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
+		quoter.QuoteTable(schema, tableName), quoter.Quote(oldName), quoter.Quote(newName))
+}
+
 func (db *TDialect) CreateTableSql(session *TSession, model IModel, storeEngine, charset string) string {
 	_ = session // 基类（MySQL 风格）无 schema 概念；postgres 覆盖实现使用 session.Schema
 	quoter := db.dialect.Quoter()
@@ -337,8 +366,21 @@ func (db *TDialect) CreateTableSql(session *TSession, model IModel, storeEngine,
 	return b.String()
 }
 
-func (db *TDialect) ForUpdateSql(query string) string {
-	return query + " FOR UPDATE"
+// CheckTxOptions 支持 SQL 标准的四种隔离级别
+func (db *TDialect) CheckTxOptions(opts *sql.TxOptions) error {
+	switch opts.Isolation {
+	case sql.LevelDefault, sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelRepeatableRead, sql.LevelSerializable:
+		return nil
+	}
+	return fmt.Errorf("%w: %s does not support isolation level %s", ErrTxOptionsNotSupported, db.dialect.String(), opts.Isolation)
+}
+
+// LockSql 标准 SQL 仅有 FOR UPDATE
+func (db *TDialect) LockSql(query string, lock *Lock) (string, error) {
+	if lock.GetStrength() != LockUpdate || lock.NoWait || lock.SkipLocked || len(lock.Of) > 0 {
+		return "", fmt.Errorf("%w: %s does not support lock %+v", ErrLockNotSupported, db.dialect.String(), *lock)
+	}
+	return query + " FOR UPDATE", nil
 }
 
 // 生成插入SQL句子
@@ -373,6 +415,106 @@ func (db *TDialect) GenInsertSql(tableName string, fields []string, uniqueFields
 	return sql.String()
 }
 
+// 生成多行插入SQL句子
+func (db *TDialect) GenBulkInsertSql(tableName string, fields []string, rows int, uniqueFields []string, idField string, onConflict *OnConflict) string {
+	var sql strings.Builder
+	sql.WriteString("INSERT INTO ")
+	sql.WriteString(tableName)
+	sql.WriteString(bulkInsertValues(db.quoter, fields, rows))
+
+	if len(idField) > 0 {
+		sql.WriteString("RETURNING ")
+		sql.WriteString(db.quoter.Quote(idField))
+	}
+
+	return sql.String()
+}
+
+// 生成批量更新SQL句子：各列以 CASE id WHEN ? THEN ? 取本记录的值
+func (db *TDialect) GenBulkUpdateSql(tableName string, idField IField, fields []IField, ids []any, rows [][]any, version IField, versions []any) (string, []any) {
+	quoter := db.quoter.Quote
+	id := quoter(idField.Name())
+	params := make([]any, 0, len(fields)*len(ids)*2+len(ids))
+
+	var sql strings.Builder
+	sql.WriteString("UPDATE ")
+	sql.WriteString(tableName)
+	sql.WriteString(" SET ")
+	for i, field := range fields {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		col := quoter(field.Name())
+		sql.WriteString(col)
+		sql.WriteString(" = CASE ")
+		sql.WriteString(id)
+		for j := range ids {
+			sql.WriteString(" WHEN ? THEN ?")
+			params = append(params, ids[j], rows[j][i])
+		}
+		sql.WriteString(" ELSE ")
+		sql.WriteString(col)
+		sql.WriteString(" END")
+	}
+	if version != nil {
+		ver := quoter(version.Name())
+		sql.WriteString(fmt.Sprintf(",%s = %s+1", ver, ver))
+	}
+
+	sql.WriteString(" WHERE ")
+	if versions != nil {
+		ver := quoter(version.Name())
+		for j := range ids {
+			if j > 0 {
+				sql.WriteString(" OR ")
+			}
+			sql.WriteString(fmt.Sprintf("(%s = ? AND %s = ?)", id, ver))
+			params = append(params, ids[j], versions[j])
+		}
+		return sql.String(), params
+	}
+	sql.WriteString(id)
+	sql.WriteString(" IN (")
+	sql.WriteString(strings.Repeat("?,", len(ids)-1) + "?")
+	sql.WriteByte(')')
+
+	return sql.String(), append(params, ids...)
+}
+
+// bulkInsertValues 生成多行插入的 " (列,...) VALUES (?,...),(?,...) " 部分
+func bulkInsertValues(quoter dialect.Quoter, fields []string, rows int) string {
+	var sql strings.Builder
+	sql.WriteString(" (")
+	for i, name := range fields {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(quoter.Quote(name))
+	}
+	sql.WriteString(") VALUES ")
+
+	places := "(" + strings.Repeat("?,", len(fields)-1) + "?)"
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(places)
+	}
+	sql.WriteByte(' ')
+	return sql.String()
+}
+
+// conflictTarget 冲突判定列：优先 OnConflict.Fields，其次唯一字段，最后主键
+func conflictTarget(onConflict *OnConflict, uniqueFields []string, idField string) []string {
+	if len(onConflict.Fields) > 0 {
+		return onConflict.Fields
+	}
+	if len(uniqueFields) > 0 {
+		return uniqueFields
+	}
+	return []string{idField}
+}
+
 func (db *TDialect) TableCheckSql(schema, tableName string) (string, []any) {
 	_ = schema // 基类占位实现；具体方言各自覆盖并处理 schema
 	args := []any{tableName}
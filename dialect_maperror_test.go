@@ -26,8 +26,8 @@ func TestMySQLMapError_Deadlock(t *testing.T) {
 	d := &mysql{}
 	src := &mysqldriver.MySQLError{Number: 1213, Message: "Deadlock found"}
 	got := d.MapError(src)
-	if !stdErrors.Is(got, ormerr.ErrConflict) {
-		t.Fatalf("expected ErrConflict, got %v", got)
+	if !stdErrors.Is(got, ormerr.ErrSerialization) || !stdErrors.Is(got, ormerr.ErrConflict) {
+		t.Fatalf("expected ErrSerialization, got %v", got)
 	}
 }
 
@@ -38,6 +38,9 @@ func TestMySQLMapError_LockTimeout(t *testing.T) {
 	if !stdErrors.Is(got, ormerr.ErrConflict) {
 		t.Fatalf("expected ErrConflict, got %v", got)
 	}
+	if stdErrors.Is(got, ormerr.ErrSerialization) {
+		t.Fatalf("lock wait timeout should not be retried as serialization failure")
+	}
 }
 
 func TestMySQLMapError_ConnectionLost(t *testing.T) {
@@ -117,8 +120,8 @@ func TestPostgresMapError_Serialization(t *testing.T) {
 	d := &postgres{}
 	src := &pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"}
 	got := d.MapError(src)
-	if !stdErrors.Is(got, ormerr.ErrConflict) {
-		t.Fatalf("expected ErrConflict, got %v", got)
+	if !stdErrors.Is(got, ormerr.ErrSerialization) || !stdErrors.Is(got, ormerr.ErrConflict) {
+		t.Fatalf("expected ErrSerialization, got %v", got)
 	}
 }
 
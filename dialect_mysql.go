@@ -195,6 +195,31 @@ func (db *mysql) SupportReturning() bool {
 	return false
 }
 
+func (db *mysql) MaxParams() int {
+	return 65535
+}
+
+// LockSql MySQL 无键级锁，NO KEY UPDATE/KEY SHARE 升级为 UPDATE/SHARE；
+// OF/NOWAIT/SKIP LOCKED 与 FOR SHARE 需 8.0，无附加选项的共享锁沿用 LOCK IN SHARE MODE 兼容旧版本
+func (db *mysql) LockSql(query string, lock *Lock) (string, error) {
+	strength := lock.GetStrength()
+	switch strength {
+	case LockNoKeyUpdate:
+		strength = LockUpdate
+	case LockKeyShare:
+		strength = LockShare
+	}
+	if strength == LockShare && !lock.NoWait && !lock.SkipLocked && len(lock.Of) == 0 {
+		return query + " LOCK IN SHARE MODE", nil
+	}
+
+	clause, err := lockClause(db.quoter.Quote, strength, lock)
+	if err != nil {
+		return "", err
+	}
+	return query + clause, nil
+}
+
 // Alias returns a alias of column
 func (db *mysql) Alias(col string) string {
 	v, ok := mysqlColAliases[strings.ToLower(col)]
@@ -508,6 +533,12 @@ func (db *mysql) TableCheckSql(schema, tableName string) (string, []any) {
 	return "SELECT `TABLE_NAME` FROM `INFORMATION_SCHEMA`.`TABLES` WHERE `TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?", args
 }
 
+// MigrationLockSql 命名锁绑定连接；在迁移事务之外的独占连接上持有，事务结束后释放
+func (db *mysql) MigrationLockSql(key string) (string, string) {
+	key = strings.ReplaceAll(key, "'", "''")
+	return fmt.Sprintf("SELECT GET_LOCK('%s', -1)", key), fmt.Sprintf("SELECT RELEASE_LOCK('%s')", key)
+}
+
 func (db *mysql) GenAddColumnSQL(schema, tableName string, field IField) string {
 	quoter := db.dialect.Quoter()
 	s, _ := ColumnString(db.dialect, field, true)
@@ -568,6 +599,42 @@ func (db *mysql) GenInsertSql(tableName string, fields []string, uniqueFields []
 	return sql.String()
 }
 
+func (db *mysql) GenBulkInsertSql(tableName string, fields []string, rows int, uniqueFields []string, idField string, onConflict *OnConflict) string {
+	var sql strings.Builder
+	quoter := db.quoter.Quote
+
+	insertVerb := "INSERT"
+	if onConflict != nil && onConflict.DoNothing {
+		insertVerb = "INSERT IGNORE"
+	}
+
+	sql.WriteString(insertVerb)
+	sql.WriteString(" INTO ")
+	sql.WriteString(tableName)
+	sql.WriteString(bulkInsertValues(db.quoter, fields, rows))
+
+	if onConflict != nil && !onConflict.DoNothing {
+		sql.WriteString("ON DUPLICATE KEY UPDATE ")
+		if len(onConflict.DoUpdates) > 0 {
+			for idx, field := range onConflict.DoUpdates {
+				if idx > 0 {
+					sql.WriteByte(',')
+				}
+				sql.WriteString(quoter(field))
+				sql.WriteString(" = VALUES(")
+				sql.WriteString(quoter(field))
+				sql.WriteString(")")
+			}
+		} else {
+			sql.WriteString(quoter(idField))
+			sql.WriteString(" = ")
+			sql.WriteString(quoter(idField))
+		}
+	}
+
+	return sql.String()
+}
+
 func (db *mysql) GetFields(ctx context.Context, session *TSession, model IModel) ([]string, map[string]IField, error) {
 	args := []any{db.DbName, model.Table()}
 	alreadyQuoted := "(INSTR(VERSION(), 'maria') > 0 && " +
@@ -875,7 +942,9 @@ func (db *mysql) MapError(err error) error {
 		switch me.Number {
 		case 1062: // ER_DUP_ENTRY
 			return ormerr.New(ormerr.ErrDuplicate, err)
-		case 1213, 1205: // ER_LOCK_DEADLOCK / ER_LOCK_WAIT_TIMEOUT
+		case 1213: // ER_LOCK_DEADLOCK
+			return ormerr.New(ormerr.ErrSerialization, err)
+		case 1205: // ER_LOCK_WAIT_TIMEOUT
 			return ormerr.New(ormerr.ErrConflict, err)
 		case 2006, 2013: // CR_SERVER_GONE_ERROR / CR_SERVER_LOST
 			return ormerr.New(ormerr.ErrConnection, err)
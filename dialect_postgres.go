@@ -799,6 +799,20 @@ func (db *postgres) SupportReturning() bool {
 	return true
 }
 
+// MaxParams 协议中参数个数为 int16
+func (db *postgres) MaxParams() int {
+	return 65535
+}
+
+// LockSql 支持全部锁强度及 OF/NOWAIT/SKIP LOCKED
+func (db *postgres) LockSql(query string, lock *Lock) (string, error) {
+	clause, err := lockClause(db.quoter.Quote, lock.GetStrength(), lock)
+	if err != nil {
+		return "", err
+	}
+	return query + clause, nil
+}
+
 func (db *postgres) String() string {
 	return "postgres"
 }
@@ -1136,12 +1150,166 @@ func (db *postgres) GenInsertSql(tableName string, fields []string, uniqueFields
 	return sql.String()
 }
 
+func (db *postgres) GenBulkInsertSql(tableName string, fields []string, rows int, uniqueFields []string, idField string, onConflict *OnConflict) string {
+	var sql strings.Builder
+	quoter := db.quoter.Quote
+
+	sql.WriteString("INSERT INTO ")
+	sql.WriteString(tableName)
+	sql.WriteString(bulkInsertValues(db.quoter, fields, rows))
+
+	if onConflict != nil {
+		sql.WriteString("ON CONFLICT ")
+		if onConflict.OnConstraint != "" {
+			sql.WriteString("ON CONSTRAINT ")
+			sql.WriteString(onConflict.OnConstraint)
+			sql.WriteByte(' ')
+		} else {
+			/* 暂时只支持一个唯一字段作为冲突目标 */
+			target := conflictTarget(onConflict, uniqueFields[:min(len(uniqueFields), 1)], idField)
+			sql.WriteByte('(')
+			for idx, field := range target {
+				if idx > 0 {
+					sql.WriteByte(',')
+				}
+				sql.WriteString(quoter(field))
+			}
+			sql.WriteString(") ")
+		}
+
+		if onConflict.DoNothing {
+			sql.WriteString("DO NOTHING ")
+		} else {
+			sql.WriteString("DO UPDATE SET ")
+			if len(onConflict.DoUpdates) > 0 {
+				for idx, field := range onConflict.DoUpdates {
+					if idx > 0 {
+						sql.WriteByte(',')
+					}
+					sql.WriteString(quoter(field))
+					sql.WriteString("=EXCLUDED.")
+					sql.WriteString(quoter(field))
+				}
+			} else {
+				sql.WriteString(quoter(idField))
+				sql.WriteByte('=')
+				sql.WriteString(quoter(idField))
+			}
+			sql.WriteByte(' ')
+		}
+	}
+
+	if len(idField) > 0 {
+		sql.WriteString("RETURNING ")
+		sql.WriteString(quoter(idField))
+	}
+
+	return sql.String()
+}
+
+// GenBulkUpdateSql 以 UPDATE ... FROM (VALUES ...) 关联每条记录的新值。
+// VALUES 中的参数无从推断类型，按列类型显式转换；读取时的版本以 __version 列随值传入
+func (db *postgres) GenBulkUpdateSql(tableName string, idField IField, fields []IField, ids []any, rows [][]any, version IField, versions []any) (string, []any) {
+	quoter := db.quoter.Quote
+	id := quoter(idField.Name())
+	params := make([]any, 0, (len(fields)+1)*len(ids))
+
+	var sql strings.Builder
+	sql.WriteString("UPDATE ")
+	sql.WriteString(tableName)
+	sql.WriteString(" AS t SET ")
+	for i, field := range fields {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		col := quoter(field.Name())
+		sql.WriteString(col)
+		sql.WriteString(" = v.")
+		sql.WriteString(col)
+		sql.WriteString("::")
+		sql.WriteString(db.castType(field))
+	}
+	if version != nil {
+		ver := quoter(version.Name())
+		sql.WriteString(fmt.Sprintf(",%s = t.%s+1", ver, ver))
+	}
+
+	sql.WriteString(" FROM (VALUES ")
+	cols := len(fields)
+	if versions != nil {
+		cols++
+	}
+	places := "(" + strings.Repeat("?,", cols) + "?)"
+	for j := range ids {
+		if j > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(places)
+		params = append(params, ids[j])
+		params = append(params, rows[j]...)
+		if versions != nil {
+			params = append(params, versions[j])
+		}
+	}
+	sql.WriteString(") AS v (")
+	sql.WriteString(id)
+	for _, field := range fields {
+		sql.WriteByte(',')
+		sql.WriteString(quoter(field.Name()))
+	}
+	if versions != nil {
+		sql.WriteString(`,"__version"`)
+	}
+	sql.WriteString(") WHERE t.")
+	sql.WriteString(id)
+	sql.WriteString(" = v.")
+	sql.WriteString(id)
+	sql.WriteString("::")
+	sql.WriteString(db.castType(idField))
+	if versions != nil {
+		sql.WriteString(fmt.Sprintf(` AND t.%s = v."__version"::%s`, quoter(version.Name()), db.castType(version)))
+	}
+
+	return sql.String(), params
+}
+
+// castType 字段在表达式中的类型，自增类型只用于建表
+func (db *postgres) castType(field IField) string {
+	switch t := db.GetSqlType(field); t {
+	case SmallSerial:
+		return SmallInt
+	case Serial:
+		return Integer
+	case BigSerial:
+		return BigInt
+	default:
+		return t
+	}
+}
+
 func (db *postgres) ModifyColumnSql(schema, tableName string, field IField) string {
 	quoter := db.dialect.Quoter()
 	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s",
 		quoter.QuoteTable(db.schemaOr(schema), tableName), quoter.Quote(field.Name()), db.GetSqlType(field))
 }
 
+// MigrationLockSql 事务级 advisory lock，随迁移事务提交/回滚自动释放
+func (db *postgres) MigrationLockSql(key string) (string, string) {
+	return fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", migrationLockId(key)), ""
+}
+
+func (db *postgres) DropColumnSql(schema, tableName string, col IField) string {
+	quoter := db.dialect.Quoter()
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s",
+		quoter.QuoteTable(db.schemaOr(schema), tableName), quoter.Quote(col.Name()))
+}
+
+func (db *postgres) RenameColumnSql(schema, tableName, oldName, newName string) string {
+	quoter := db.dialect.Quoter()
+	return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
+		quoter.QuoteTable(db.schemaOr(schema), tableName), quoter.Quote(oldName), quoter.Quote(newName))
+}
+
 // DropColumnNotNullSql aligns NOT NULL constraint with col.Required().
 func (db *postgres) DropColumnNotNullSql(schema, tableName string, col IField) string {
 	quoter := db.dialect.Quoter()
@@ -1746,7 +1914,7 @@ func (db *postgres) MapError(err error) error {
 		case "23503", "23502": // foreign_key_violation / not_null_violation
 			return ormerr.New(ormerr.ErrValidation, err)
 		case "40001", "40P01": // serialization_failure / deadlock_detected
-			return ormerr.New(ormerr.ErrConflict, err)
+			return ormerr.New(ormerr.ErrSerialization, err)
 		case "08006", "08003", "08001": // connection_failure / connection_does_not_exist / sqlclient_unable_to_establish_sqlconnection
 			return ormerr.New(ormerr.ErrConnection, err)
 		}
@@ -80,6 +80,27 @@ func (db *sqlite) SupportReturning() bool {
 	return true
 }
 
+// MaxParams SQLITE_MAX_VARIABLE_NUMBER 自 3.32 起默认 32766
+func (db *sqlite) MaxParams() int {
+	return 32766
+}
+
+// CheckTxOptions SQLite 的事务总是可串行化的，不提供更低的隔离级别；
+// 只读仅作声明，驱动不据此拒绝写入
+func (db *sqlite) CheckTxOptions(opts *sql.TxOptions) error {
+	switch opts.Isolation {
+	case sql.LevelDefault, sql.LevelSerializable:
+		return nil
+	}
+	return fmt.Errorf("%w: sqlite transactions are always serializable, got %s", ErrTxOptionsNotSupported, opts.Isolation)
+}
+
+// LockSql SQLite 没有行锁，锁定读取一律返回 ErrLockNotSupported 而非静默忽略；
+// 读后再写的事务应以 _txlock=immediate 连接(BEGIN IMMEDIATE)在开始时即取得写锁
+func (db *sqlite) LockSql(query string, lock *Lock) (string, error) {
+	return "", fmt.Errorf("%w: sqlite has no row level lock, open the database with _txlock=immediate instead", ErrLockNotSupported)
+}
+
 func (db *sqlite) Alias(col string) string {
 	return col
 }
@@ -392,6 +413,54 @@ func (db *sqlite) GenInsertSql(tableName string, fields []string, uniqueFields [
 	return sqlStr.String()
 }
 
+func (db *sqlite) GenBulkInsertSql(tableName string, fields []string, rows int, uniqueFields []string, idField string, onConflict *OnConflict) string {
+	var sqlStr strings.Builder
+	quoter := db.quoter.Quote
+
+	insertVerb := "INSERT"
+	if onConflict != nil && onConflict.DoNothing {
+		insertVerb = "INSERT OR IGNORE"
+	}
+
+	sqlStr.WriteString(insertVerb)
+	sqlStr.WriteString(" INTO ")
+	sqlStr.WriteString(quoter(tableName))
+	sqlStr.WriteString(bulkInsertValues(db.quoter, fields, rows))
+
+	if onConflict != nil && !onConflict.DoNothing {
+		sqlStr.WriteString("ON CONFLICT (")
+		for idx, f := range conflictTarget(onConflict, uniqueFields, idField) {
+			if idx > 0 {
+				sqlStr.WriteString(",")
+			}
+			sqlStr.WriteString(quoter(f))
+		}
+		sqlStr.WriteString(") DO UPDATE SET ")
+		if len(onConflict.DoUpdates) > 0 {
+			for idx, field := range onConflict.DoUpdates {
+				if idx > 0 {
+					sqlStr.WriteByte(',')
+				}
+				sqlStr.WriteString(quoter(field))
+				sqlStr.WriteString(" = excluded.")
+				sqlStr.WriteString(quoter(field))
+			}
+		} else {
+			sqlStr.WriteString(quoter(idField))
+			sqlStr.WriteString(" = ")
+			sqlStr.WriteString(quoter(idField))
+		}
+		sqlStr.WriteByte(' ')
+	}
+
+	if len(idField) > 0 {
+		sqlStr.WriteString("RETURNING ")
+		sqlStr.WriteString(quoter(idField))
+	}
+
+	return sqlStr.String()
+}
+
 // MapError 把 SQLite driver 错误翻译为 ormerr sentinel
 // modernc.org/sqlite 错误暴露为字符串，靠 message 匹配
 func (db *sqlite) MapError(err error) error {
@@ -404,7 +473,7 @@ func (db *sqlite) MapError(err error) error {
 	case strings.Contains(msg, "UNIQUE constraint failed"):
 		return ormerr.New(ormerr.ErrDuplicate, err)
 	case strings.Contains(msg, "database is locked"):
-		return ormerr.New(ormerr.ErrConflict, err)
+		return ormerr.New(ormerr.ErrSerialization, err) // SQLITE_BUSY：写事务冲突，整体重试
 	case strings.Contains(msg, "no such table"),
 		strings.Contains(msg, "FOREIGN KEY constraint failed"),
 		strings.Contains(msg, "NOT NULL constraint failed"):
@@ -495,6 +495,21 @@ func (self *TDomainNode) Clone() *TDomainNode {
 	return node
 }
 
+// Copy 深拷贝节点及其全部子节点，修改副本(如 OP/Push/Clear)不影响原节点
+func (self *TDomainNode) Copy() *TDomainNode {
+	if self == nil {
+		return nil
+	}
+	node := &TDomainNode{nodeType: self.nodeType, Value: self.Value}
+	if self.children != nil {
+		node.children = make([]*TDomainNode, len(self.children))
+		for i, child := range self.children {
+			node.children[i] = child.Copy()
+		}
+	}
+	return node
+}
+
 func (self *TDomainNode) IsValueNode() bool {
 	return self.nodeType == VALUE_NODE
 }
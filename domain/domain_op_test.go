@@ -71,3 +71,26 @@ func TestOP_ImplicitAndList_Or(t *testing.T) {
 		t.Errorf("operator = %q, want %q", op, OR_OPERATOR)
 	}
 }
+
+// Copy 为深拷贝: 在副本上追加条件或清空, 原节点保持不变。
+func TestCopy_Independent(t *testing.T) {
+	self, err := Any2Domain([]any{[]any{"a", "=", 1}, []any{"b", "in", []any{1, 2}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	self.String() // String 会惰性标记叶子节点类型，先取一次使输出稳定
+	want := self.String()
+
+	cp := self.Copy()
+	cp.OP(AND_OPERATOR, New("c", "=", 3))
+	cp.Item(cp.Count() - 2).Clear()
+	if got := self.String(); got != want {
+		t.Fatalf("original changed: %s, want %s", got, want)
+	}
+
+	cp = self.Copy()
+	cp.Clear()
+	if got := self.String(); got != want || self.Count() != 2 {
+		t.Fatalf("original changed after Clear on copy: %s", got)
+	}
+}
@@ -1,11 +1,15 @@
 package orm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
 	"sort"
 	"strings"
+
+	"github.com/lib/pq"
+	"github.com/volts-dev/orm/core"
 )
 
 type pqDriver struct {
@@ -119,3 +123,28 @@ func (p *pqDriver) Parse(driverName, dataSourceName string) (*TDataSource, error
 	}
 	return db, nil
 }
+
+// pqCopyIn 以 COPY FROM STDIN 把 rows 写入表，每行的值与 fields 一一对应。
+// COPY 只能在事务内进行，且不支持 ON CONFLICT 与 RETURNING
+func pqCopyIn(ctx context.Context, tx *core.Tx, schema, table string, fields []string, rows [][]any) error {
+	query := pq.CopyIn(table, fields...)
+	if schema != "" {
+		query = pq.CopyInSchema(schema, table, fields...)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err = stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+
+	// 无参数的 Exec 结束数据流并提交本次 COPY
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
@@ -3,6 +3,9 @@ package orm
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	ormerr "github.com/volts-dev/orm/errors"
 )
 
 type (
@@ -10,20 +13,50 @@ type (
 		title  string
 		errors []error
 	}
+
+	// UnsafeSchemaError 结构同步含破坏性变更(收窄类型/长度、对有 NULL 的列加 NOT NULL、
+	// 改列名)时被默认守护拒绝，Changes 列出全部被拒绝的变更。errors.Is(err, ErrUnsafe)
+	// 为 true；确认无误后用 session.AllowUnsafe() 或 WithAllowDestructiveSync 放行。
+	UnsafeSchemaError struct {
+		Changes []*TSchemaChange
+	}
+
+	// AccessError 当前用户(见 WithUser)对 Model 的 Op 操作被拒绝。Ids 为记录规则
+	// 不允许的记录，Field 为无权写入的字段(见 IField.Groups)；errors.Is(err, ErrAccessDenied) 为 true。
+	AccessError struct {
+		Model string
+		Op    SessionOp
+		Field string
+		Ids   []any
+	}
+
+	// StaleRecordError 乐观锁更新时 Ids 记录的版本已不是调用方读取时的版本(已被他人修改或删除)，
+	// 更新未生效；errors.Is(err, ErrStaleRecord) 与 errors.Is(err, ErrConflict) 为 true。
+	StaleRecordError struct {
+		Model string
+		Ids   []any
+	}
 )
 
 var (
-	ErrNoMapPointer          = errors.New("mp should be a map's pointer")
-	ErrNoStructPointer       = errors.New("mp should be a struct's pointer")
-	ErrParamsType      error = errors.New("Params type error")
-	ErrTableNotFound   error = errors.New("Not found table")
-	ErrUnSupportedType error = errors.New("Unsupported type error")
-	ErrNotExist        error = errors.New("Not exist error")
-	ErrCacheFailed     error = errors.New("Cache failed")
-	ErrNeedDeletedCond error = errors.New("Delete need at least one condition")
-	ErrNotImplemented  error = errors.New("Not implemented.")
-	ErrDeleteFailed    error = errors.New("Delete Failed.")
-	ErrInvalidSession  error = errors.New("The session of query is invalid!")
+	ErrNoMapPointer                = errors.New("mp should be a map's pointer")
+	ErrNoStructPointer             = errors.New("mp should be a struct's pointer")
+	ErrParamsType            error = errors.New("Params type error")
+	ErrTableNotFound         error = errors.New("Not found table")
+	ErrUnSupportedType       error = errors.New("Unsupported type error")
+	ErrNotExist              error = errors.New("Not exist error")
+	ErrCacheFailed           error = errors.New("Cache failed")
+	ErrNeedDeletedCond       error = errors.New("Delete need at least one condition")
+	ErrNotImplemented        error = errors.New("Not implemented.")
+	ErrDeleteFailed          error = errors.New("Delete Failed.")
+	ErrInvalidSession        error = errors.New("The session of query is invalid!")
+	ErrInvalidCursor         error = errors.New("Invalid pagination cursor")
+	ErrLockNotSupported      error = errors.New("Lock mode not supported")
+	ErrTxOptionsNotSupported error = errors.New("Transaction options not supported")
+	ErrShardNotFound         error = errors.New("Shard not found")
+	ErrShardKeyMissing       error = errors.New("Shard key missing")
+	ErrShardRequired         error = errors.New("Shard must be specified")
+	ErrTenantRequired        error = errors.New("Tenant must be specified")
 )
 
 // 接受多个错误 如果0错误返回nil
@@ -49,3 +82,39 @@ func newSessionError(title string, errs ...error) error {
 func (self sessionError) Error() string {
 	return fmt.Sprintf("%s:%v ", self.title, self.errors)
 }
+
+func (self *UnsafeSchemaError) Error() string {
+	var b strings.Builder
+	b.WriteString("orm: schema sync refused unsafe changes")
+	for _, change := range self.Changes {
+		fmt.Fprintf(&b, "; %s.%s: %s", change.Table, change.Column, change.Reason)
+	}
+	return b.String()
+}
+
+func (self *UnsafeSchemaError) Unwrap() error {
+	return ormerr.ErrUnsafe
+}
+
+func (self *AccessError) Error() string {
+	msg := fmt.Sprintf("orm: access denied; %s on %s", self.Op, self.Model)
+	if self.Field != "" {
+		msg += fmt.Sprintf(" field %s", self.Field)
+	}
+	if len(self.Ids) > 0 {
+		msg += fmt.Sprintf(" records %v", self.Ids)
+	}
+	return msg
+}
+
+func (self *AccessError) Unwrap() error {
+	return ormerr.ErrAccessDenied
+}
+
+func (self *StaleRecordError) Error() string {
+	return fmt.Sprintf("orm: stale record version; %s records %v were modified by others", self.Model, self.Ids)
+}
+
+func (self *StaleRecordError) Unwrap() error {
+	return ormerr.ErrStaleRecord
+}
@@ -8,7 +8,7 @@ import (
 	"strings"
 )
 
-// 11 个 sentinel errors —— 调用方用 errors.Is(err, ErrXxx) 判断
+// 13 个 sentinel errors —— 调用方用 errors.Is(err, ErrXxx) 判断
 var (
 	// ErrNotFound 查询无结果
 	ErrNotFound = errors.New("orm: record not found")
@@ -31,6 +31,12 @@ var (
 	ErrNoSoftDelete = errors.New("orm: model has no 'deleted' tag field")
 	// ErrSoftDeleteMisconfigured 软删除相关：模型有多个 deleted tag 字段
 	ErrSoftDeleteMisconfigured = errors.New("orm: model has multiple 'deleted' tag fields")
+	// ErrAccessDenied 当前用户无权访问目标记录(记录规则/访问控制拒绝)
+	ErrAccessDenied = errors.New("orm: access denied")
+	// ErrStaleRecord 乐观锁版本冲突：记录在读取后已被他人修改；errors.Is(err, ErrConflict) 同样成立
+	ErrStaleRecord = fmt.Errorf("%w: stale record version", ErrConflict)
+	// ErrSerialization 事务因序列化失败或死锁被数据库中止，整体重试即可；errors.Is(err, ErrConflict) 同样成立
+	ErrSerialization = fmt.Errorf("%w: serialization failure", ErrConflict)
 )
 
 // ORMError 携带上下文的 ORM 错误，支持 errors.Is/As
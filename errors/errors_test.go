@@ -34,3 +34,21 @@ func TestSentinel_ErrSoftDeleteMisconfigured(t *testing.T) {
 		t.Fatal("ErrSoftDeleteMisconfigured must have message")
 	}
 }
+
+func TestSentinel_ErrStaleRecord(t *testing.T) {
+	if !errors.Is(ErrStaleRecord, ErrConflict) {
+		t.Fatal("ErrStaleRecord should be a kind of ErrConflict")
+	}
+	if errors.Is(ErrConflict, ErrStaleRecord) {
+		t.Fatal("ErrConflict should not match ErrStaleRecord")
+	}
+}
+
+func TestSentinel_ErrSerialization(t *testing.T) {
+	if !errors.Is(ErrSerialization, ErrConflict) {
+		t.Fatal("ErrSerialization should be a kind of ErrConflict")
+	}
+	if errors.Is(ErrStaleRecord, ErrSerialization) {
+		t.Fatal("ErrStaleRecord should not be retried as serialization failure")
+	}
+}
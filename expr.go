@@ -34,6 +34,8 @@ type (
 		stack      []*TExtendedLeaf
 		result     []*TExtendedLeaf
 		joins      []string //*utils.TStringList
+		lang       string   // 翻译字段搜索使用的语言，取自 context["lang"]
+		schema     string   // 翻译附表所在的 schema，取自 context["schema"]
 	}
 )
 
@@ -55,6 +57,8 @@ func NewExpression(orm *TOrm, model *TModel, dom *domain.TDomainNode, context ma
 		root_model: model,
 		joins:      make([]string, 0),
 	}
+	exp.lang, _ = context["lang"].(string)
+	exp.schema, _ = context["schema"].(string)
 
 	node, err := normalize_domain(dom)
 	if err != nil {
@@ -372,7 +376,7 @@ func generate_table_alias(src_table_alias string, joined_tables [][]string, sche
 		log.Errf("Table alias name %s is longer than the 64 characters size accepted by default in postgresql.", srcTableName)
 	}
 
-	return srcTableName, fmt.Sprintf("%s as %s", quoteTableWithSchema(schema, joined_tables[0][0]), quoteStr(srcTableName))
+	return srcTableName, fmt.Sprintf("%s as %s", quoteTableWithSchema(schema, joined_tables[len(joined_tables)-1][0]), quoteStr(srcTableName))
 }
 
 func idsToSqlHolder(ids ...any) string {
@@ -608,6 +612,27 @@ func (self *TExpression) parse(context map[string]any) error {
 			// FIELD NOT FOUND
 			return log.Errf("Invalid field <%s>@<%s> in leaf <%s>", left.String(), model.String(), domain.Domain2String(ex_leaf.leaf))
 
+		} else if related := field.Base().relatedPath; related != "" && !field.Store() {
+			// 非存储关联字段：沿 many2one 链逐级 JOIN，条件落到链末端模型的字段上
+			relPath := strings.Split(related, ".")
+			for _, name := range relPath[:len(relPath)-1] {
+				rel := ex_leaf.model.GetFieldByName(name)
+				if rel == nil || rel.TypeName() != TYPE_M2O {
+					return fmt.Errorf("related %s of %s: %s is not a many2one field", related, model.String(), name)
+				}
+				next_model, err := model.Orm().GetModel(rel.RelatedModelName())
+				if err != nil {
+					return err
+				}
+				ex_leaf.add_join_context(next_model.GetBase(), name, next_model.IdField(), name)
+			}
+
+			newLeft := relPath[len(relPath)-1]
+			if len(path) > 1 {
+				newLeft += "." + path[1]
+			}
+			self.push(create_substitution_leaf(ex_leaf, domain.New(newLeft, operator.String(), right), ex_leaf.model, false))
+
 		} else if field.IsInherited() {
 			// ----------------------------------------
 			// FIELD NOT FOUND
@@ -1120,6 +1145,9 @@ func (self *TExpression) leaf_to_sql(eleaf *TExtendedLeaf, params []any) (res_qu
 
 			//unaccent = self._unaccent if sql_operator.endswith('like') else lambda x: x
 			column := fmt.Sprintf("%s.%s", aliasTable, quoter.Quote(left.String()))
+			if field.Translate() && field.Store() {
+				column = self.orm._translatedColumn(self.schema, self.lang, model, aliasTable, field)
+			}
 			res_query = fmt.Sprintf("(%s %s %s)", column+cast, sql_operator, format)
 
 		} else if left.ValueIn(MAGIC_COLUMNS) {
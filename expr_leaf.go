@@ -71,11 +71,15 @@ func NewExtendedLeaf(leaf *domain.TDomainNode, model *TModel, context []TJoinCon
 	ex_leaf := &TExtendedLeaf{
 		leaf:         leaf,
 		model:        model,
-		join_context: context,
+		join_context: append([]TJoinContext(nil), context...), // 复制，避免与来源叶子共用底层数组
 	}
 
 	ex_leaf.normalize_leaf()
 
+	// 沿 JOIN 链保留起始模型，别名与 JOIN 条件均以 models[0] 为根
+	for _, ctx := range context {
+		ex_leaf.models = append(ex_leaf.models, ctx.SourceModel)
+	}
 	ex_leaf.models = append(ex_leaf.models, model)
 	//# check validity
 	ex_leaf.check_leaf(internal)
@@ -243,9 +243,10 @@ type (
 		outputAs         string              // 读取时将值伪装为哪种类型（char/int/bool 等）
 
 		name        string   // 字段在数据库中的列名
+		oldName     string   // 改名前的列名(oldname tag)，结构同步据此改列名
 		store       bool     // 是否将字段值持久化到数据库
 		manual      bool     // 是否手动管理（非框架自动）
-		depends     []string // 依赖的其他字段名列表
+		depends     []string // 依赖的其他字段名列表(depends tag)，支持经 many2one/one2many 的点路径
 		readonly    bool     // 是否只读
 		writeonly   bool     // 是否只写
 		required    bool     // 是否非空
@@ -273,6 +274,8 @@ type (
 		setterMethod   string    // 写入方法名
 		getterMethod   string    // 读取方法名
 		computeAsAdmin bool      // 计算字段是否以 admin 权限重新计算
+		computeMethod  string    // 存储型计算字段的计算方法名
+		computeFunc    FieldFunc // 存储型计算字段的计算函数，依赖变更时重算并写回
 
 		oneToManyFK        string // one-to-many 关系中对端的外键字段名
 		m2mLimit           int64  // many-to-many 关系单次取回的上限
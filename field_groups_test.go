@@ -0,0 +1,88 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ormerr "github.com/volts-dev/orm/errors"
+)
+
+type AccessSalaryModel struct {
+	TModel `table:"name('access_salary')"`
+	Id     int64  `field:"pk autoincr"`
+	Name   string `field:"varchar() size(64)"`
+	Salary int    `field:"int() groups('hr','hr_manager')"`
+}
+
+func TestFieldGroups_TagParsed(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(AccessSalaryModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	model, err := o.GetModel("access.salary")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if groups := model.GetFieldByName("salary").Groups(); groups != "hr,hr_manager" {
+		t.Fatalf("groups tag not parsed, got %q", groups)
+	}
+}
+
+func TestFieldGroups_EnforcedOnModelRequests(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(AccessSalaryModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	hr := WithUser(context.Background(), 1, "hr")
+	staff := WithUser(context.Background(), 2, "staff")
+
+	model, err := o.GetModel("access.salary")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	ids, err := model.Create(&CreateRequest{Context: hr, Data: []any{map[string]any{"name": "alice", "salary": 100}}})
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("hr should create with salary: %v, %v", ids, err)
+	}
+
+	// 写入受限字段被拒绝
+	_, err = model.Create(&CreateRequest{Context: staff, Data: []any{map[string]any{"name": "bob", "salary": 1}}})
+	var accessErr *AccessError
+	if !errors.As(err, &accessErr) || accessErr.Field != "salary" || !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("staff create with salary should be denied, got %v", err)
+	}
+	if _, err = model.Update(&UpdateRequest{Context: staff, Ids: ids, Data: []any{map[string]any{"salary": 1}}}); !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("staff update of salary should be denied, got %v", err)
+	}
+
+	// struct 零值的受限字段不报错，也不会被写入
+	if _, err = model.Update(&UpdateRequest{Context: staff, Ids: ids, Data: []any{&AccessSalaryModel{Name: "alicia"}}}); err != nil {
+		t.Fatalf("staff update without salary should pass: %v", err)
+	}
+
+	// 读取时静默剔除
+	model, _ = o.GetModel("access.salary", WithContext(staff))
+	ds, err := model.Read(&ReadRequest{Ids: ids, Fields: []string{"name", "salary"}})
+	if err != nil || ds.Count() != 1 {
+		t.Fatalf("staff read: %v", err)
+	}
+	if ds.HasField("salary") {
+		t.Fatal("salary should be omitted for staff")
+	}
+	if name := ds.FieldByName("name").AsString(); name != "alicia" {
+		t.Fatalf("name should be updated, got %q", name)
+	}
+
+	model, _ = o.GetModel("access.salary", WithContext(hr))
+	ds, err = model.Read(&ReadRequest{Ids: ids})
+	if err != nil || ds.Count() != 1 {
+		t.Fatalf("hr read: %v", err)
+	}
+	if salary := ds.FieldByName("salary").AsInteger(); salary != 100 {
+		t.Fatalf("salary must be untouched and visible to hr, got %d", salary)
+	}
+}
@@ -521,37 +521,11 @@ func (self *TMany2ManyField) UpdateDb(ctx *TTagContext) {
 		schema = ctx.Session.Schema
 	}
 
-	middle_model := strings.Replace(field.JoinModelName(), ".", "_", -1)
+	middle_model, stmts := self.relationTableSql(orm, ctx.Model, schema)
 
 	// DDL：按 (schema, 表) 每进程一次；CREATE ... IF NOT EXISTS 本身幂等，重启安全。
 	ddlKey := schema + "|" + middle_model
 	if _, done := orm.osv.middleModelDDL.LoadOrStore(ddlKey, true); !done {
-		idField := ctx.Model.GetFieldByName(ctx.Model.IdField())
-		sqlType := orm.dialect.GetSqlType(idField)
-		id1 := field.RelatedKeyName()
-		id2 := field.JoinSourceKey()
-
-		// 关联表引用必须带 schema 前缀（qualifiedMiddle），否则落到 search_path
-		// 默认 schema。索引名不可加前缀（PG 索引天然归属其表所在 schema）。
-		qualifiedMiddle := `"` + middle_model + `"`
-		if schema != "" {
-			qualifiedMiddle = `"` + schema + `"."` + middle_model + `"`
-		}
-
-		// Run each DDL statement independently — SQLite/MySQL don't support
-		// multi-statement Exec, unnamed CREATE INDEX, or PG's COMMENT ON TABLE.
-		stmts := []string{
-			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s ("%s" %s NOT NULL, "%s" %s NOT NULL, UNIQUE("%s","%s"))`,
-				qualifiedMiddle, id1, sqlType, id2, sqlType, id1, id2),
-			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_%s_idx" ON %s ("%s")`,
-				middle_model, id1, qualifiedMiddle, id1),
-			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_%s_idx" ON %s ("%s")`,
-				middle_model, id2, qualifiedMiddle, id2),
-		}
-		if strings.EqualFold(orm.dialect.DBType(), "postgres") {
-			stmts = append(stmts, fmt.Sprintf(`COMMENT ON TABLE %s IS '%s'`,
-				qualifiedMiddle, fmt.Sprintf("RELATION BETWEEN %s AND %s", self.modelName, middle_model)))
-		}
 		for _, q := range stmts {
 			if _, err := orm.Exec(q); err != nil {
 				log.Errf("m2m create table '%s' failure : SQL:%s,\nError:%s", ctx.Field.RelatedModelName(), q, err.Error())
@@ -580,6 +554,38 @@ func (self *TMany2ManyField) UpdateDb(ctx *TTagContext) {
 	}
 }
 
+// relationTableSql 返回 m2m 关联表名及其建表/建索引语句(不执行)，供 UpdateDb 与同步计划共用
+func (self *TMany2ManyField) relationTableSql(orm *TOrm, model IModel, schema string) (string, []string) {
+	middle_model := strings.Replace(self.JoinModelName(), ".", "_", -1)
+	idField := model.GetFieldByName(model.IdField())
+	sqlType := orm.dialect.GetSqlType(idField)
+	id1 := self.RelatedKeyName()
+	id2 := self.JoinSourceKey()
+
+	// 关联表引用必须带 schema 前缀（qualifiedMiddle），否则落到 search_path
+	// 默认 schema。索引名不可加前缀（PG 索引天然归属其表所在 schema）。
+	qualifiedMiddle := `"` + middle_model + `"`
+	if schema != "" {
+		qualifiedMiddle = `"` + schema + `"."` + middle_model + `"`
+	}
+
+	// Run each DDL statement independently — SQLite/MySQL don't support
+	// multi-statement Exec, unnamed CREATE INDEX, or PG's COMMENT ON TABLE.
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s ("%s" %s NOT NULL, "%s" %s NOT NULL, UNIQUE("%s","%s"))`,
+			qualifiedMiddle, id1, sqlType, id2, sqlType, id1, id2),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_%s_idx" ON %s ("%s")`,
+			middle_model, id1, qualifiedMiddle, id1),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_%s_idx" ON %s ("%s")`,
+			middle_model, id2, qualifiedMiddle, id2),
+	}
+	if strings.EqualFold(orm.dialect.DBType(), "postgres") {
+		stmts = append(stmts, fmt.Sprintf(`COMMENT ON TABLE %s IS '%s'`,
+			qualifiedMiddle, fmt.Sprintf("RELATION BETWEEN %s AND %s", self.modelName, middle_model)))
+	}
+	return middle_model, stmts
+}
+
 // 设置字段获得的值
 // TODO :未完成
 func (self *TMany2ManyField) OnRead(ctx *TFieldContext) error {
@@ -0,0 +1,129 @@
+package orm
+
+type (
+	// THookContext 记录级钩子的参数。
+	// Ids 为受影响记录：BeforeCreate 时记录尚未插入，Ids 为空；
+	// Values 为提交的值：Create 每条记录一项，Write 只有一项，Unlink 为空。
+	THookContext struct {
+		Session *TSession // 触发钩子的会话，勿在钩子内复用其 Statement
+		Model   IModel
+		Op      SessionOp
+		Ids     []any
+		Values  []map[string]any
+	}
+
+	// 可选记录级钩子：模型实现后由 Create/Write/Delete 在同一事务内调用，
+	// 返回错误即否决本次操作并整体回滚。
+	IBeforeCreate interface {
+		BeforeCreate(ctx *THookContext) error
+	}
+	IAfterCreate interface {
+		AfterCreate(ctx *THookContext) error
+	}
+	IBeforeWrite interface {
+		BeforeWrite(ctx *THookContext) error
+	}
+	IAfterWrite interface {
+		AfterWrite(ctx *THookContext) error
+	}
+	IBeforeUnlink interface {
+		BeforeUnlink(ctx *THookContext) error
+	}
+	IAfterUnlink interface {
+		AfterUnlink(ctx *THookContext) error
+	}
+)
+
+// GetModel 获取沿用钩子会话事务的模型，钩子内的派生读写与触发操作同进同退
+func (self *THookContext) GetModel(modelName string, options ...ModelOption) (IModel, error) {
+	return self.Session._getModel(modelName, options...)
+}
+
+// _hooked 会话模型是否实现了 op 的任一记录级钩子
+func (self *TSession) _hooked(op SessionOp) bool {
+	model := self.Statement.Model
+	if model == nil {
+		return false
+	}
+
+	switch op {
+	case OpCreate:
+		_, before := model.(IBeforeCreate)
+		_, after := model.(IAfterCreate)
+		return before || after
+	case OpWrite:
+		_, before := model.(IBeforeWrite)
+		_, after := model.(IAfterWrite)
+		return before || after
+	case OpDelete:
+		_, before := model.(IBeforeUnlink)
+		_, after := model.(IAfterUnlink)
+		return before || after
+	}
+	return false
+}
+
+// _atomic 写操作是否须在单个事务内完成：审计记录、记录级钩子、翻译附表与计算字段重算都要与写入同进同退；
+// 带版本字段的更新遇到版本冲突时已更新的记录也须回滚
+func (self *TSession) _atomic(op SessionOp) bool {
+	return self._auditEnabled() || self._hooked(op) || self._translationSideTable() || (op != OpDelete && self._computeTriggered()) ||
+		(op == OpWrite && self.Statement.Model.Obj().VersionField != "")
+}
+
+// _hookValues 把新建提交的值规整为字段名到值的映射供钩子读取，与 _createValues 实际写入的一致(含 Sets)
+func (self *TSession) _hookValues(src ...any) ([]map[string]any, error) {
+	values := make([]map[string]any, 0, len(src))
+	for _, item := range src {
+		if item == nil && len(self.Statement.Sets) == 0 {
+			continue
+		}
+		ds, _, err := self._mergeSets(item)
+		if err != nil {
+			return nil, err
+		}
+		self._stampTenant(ds.Record(), true)
+		values = append(values, ds.Record().AsMap())
+	}
+	return values, nil
+}
+
+// _callHook 调用会话模型的 Before*/After* 钩子；模型未实现时直接返回
+func (self *TSession) _callHook(op SessionOp, before bool, ids []any, values []map[string]any) error {
+	model := self.Statement.Model
+	if model == nil {
+		return nil
+	}
+
+	ctx := &THookContext{
+		Session: self,
+		Model:   model,
+		Op:      op,
+		Ids:     ids,
+		Values:  values,
+	}
+
+	switch op {
+	case OpCreate:
+		if hook, ok := model.(IBeforeCreate); ok && before {
+			return hook.BeforeCreate(ctx)
+		}
+		if hook, ok := model.(IAfterCreate); ok && !before {
+			return hook.AfterCreate(ctx)
+		}
+	case OpWrite:
+		if hook, ok := model.(IBeforeWrite); ok && before {
+			return hook.BeforeWrite(ctx)
+		}
+		if hook, ok := model.(IAfterWrite); ok && !before {
+			return hook.AfterWrite(ctx)
+		}
+	case OpDelete:
+		if hook, ok := model.(IBeforeUnlink); ok && before {
+			return hook.BeforeUnlink(ctx)
+		}
+		if hook, ok := model.(IAfterUnlink); ok && !before {
+			return hook.AfterUnlink(ctx)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var errHookVeto = errors.New("vetoed by hook")
+
+type HookedPartner struct {
+	TModel `table:"name('hook_partner')"`
+	Id     int64  `field:"pk autoincr"`
+	Name   string `field:"varchar() size(64)"`
+	Age    int    `field:"int()"`
+}
+
+// hookCalls 记录钩子调用顺序；测试串行执行
+var hookCalls []string
+
+func (self *HookedPartner) BeforeCreate(ctx *THookContext) error {
+	hookCalls = append(hookCalls, fmt.Sprintf("before_create %d %v", len(ctx.Ids), ctx.Values[0]["name"]))
+	if ctx.Values[0]["name"] == "veto" {
+		return errHookVeto
+	}
+	return nil
+}
+
+func (self *HookedPartner) AfterCreate(ctx *THookContext) error {
+	hookCalls = append(hookCalls, fmt.Sprintf("after_create %d", len(ctx.Ids)))
+	if ctx.Values[0]["name"] == "late_veto" {
+		return errHookVeto
+	}
+	return nil
+}
+
+func (self *HookedPartner) BeforeWrite(ctx *THookContext) error {
+	hookCalls = append(hookCalls, fmt.Sprintf("before_write %v %v", ctx.Ids, ctx.Values[0]["age"]))
+	if age, _ := ctx.Values[0]["age"].(int); age < 0 {
+		return errHookVeto
+	}
+	return nil
+}
+
+func (self *HookedPartner) AfterWrite(ctx *THookContext) error {
+	hookCalls = append(hookCalls, fmt.Sprintf("after_write %v", ctx.Ids))
+	return nil
+}
+
+func (self *HookedPartner) AfterUnlink(ctx *THookContext) error {
+	hookCalls = append(hookCalls, fmt.Sprintf("after_unlink %v", ctx.Ids))
+	return errHookVeto
+}
+
+func TestHooks_InvokedAndVetoInTransaction(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	names, err := o.SyncModel("", new(HookedPartner))
+	if err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	modelName := names[0]
+	count := func() int {
+		t.Helper()
+		cnt, err := o.NewSession().Model(modelName).Count()
+		if err != nil {
+			t.Fatalf("Count: %v", err)
+		}
+		return cnt
+	}
+
+	hookCalls = nil
+	ids, err := o.NewSession().Model(modelName).Create(map[string]any{"name": "alice", "age": 20})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(hookCalls) != 2 || hookCalls[0] != "before_create 0 alice" || hookCalls[1] != "after_create 1" {
+		t.Fatalf("unexpected create hooks %v", hookCalls)
+	}
+
+	if _, err = o.NewSession().Model(modelName).Create(map[string]any{"name": "veto"}); !errors.Is(err, errHookVeto) {
+		t.Fatalf("BeforeCreate should veto, got %v", err)
+	}
+	// 以 Set 提交的值同样传给钩子，且可被否决
+	if _, err = o.NewSession().Model(modelName).Set("name", "veto").Create(); !errors.Is(err, errHookVeto) {
+		t.Fatalf("BeforeCreate should see Set values and veto, got %v", err)
+	}
+	if _, err = o.NewSession().Model(modelName).Create(map[string]any{"name": "late_veto"}); !errors.Is(err, errHookVeto) {
+		t.Fatalf("AfterCreate should veto, got %v", err)
+	}
+	if cnt := count(); cnt != 1 {
+		t.Fatalf("vetoed creates must be rolled back, got %d records", cnt)
+	}
+
+	// 按条件更新同样触发钩子并带上定位到的 ids
+	hookCalls = nil
+	if _, err = o.NewSession().Model(modelName).Domain(`[('name', '=', 'alice')]`).Write(map[string]any{"age": 21}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := []string{fmt.Sprintf("before_write %v 21", ids), fmt.Sprintf("after_write %v", ids)}
+	if fmt.Sprint(hookCalls) != fmt.Sprint(want) {
+		t.Fatalf("unexpected write hooks %v, want %v", hookCalls, want)
+	}
+	if _, err = o.NewSession().Model(modelName).Ids(ids...).Write(map[string]any{"age": -1}); !errors.Is(err, errHookVeto) {
+		t.Fatalf("BeforeWrite should veto, got %v", err)
+	}
+
+	// 模型层请求同样经过钩子；AfterUnlink 否决后删除整体回滚
+	model, err := o.GetModel(modelName)
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if _, err = model.Delete(&DeleteRequest{Ids: ids}); !errors.Is(err, errHookVeto) {
+		t.Fatalf("AfterUnlink should veto, got %v", err)
+	}
+	if cnt := count(); cnt != 1 {
+		t.Fatalf("vetoed delete must be rolled back, got %d records", cnt)
+	}
+	ds, err := o.NewSession().Model(modelName).Ids(ids...).Read()
+	if err != nil || ds.FieldByName("age").AsInteger() != 21 {
+		t.Fatalf("vetoed write must not change the record: %v", err)
+	}
+}
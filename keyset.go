@@ -0,0 +1,227 @@
+package orm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type (
+	// tKeyset 一次键集分页：cursor 为上一页游标；terms/limit 由 _readSql 生成语句时记下，用于产出下一页游标
+	tKeyset struct {
+		cursor string
+		model  IModel
+		terms  []tOrderTerm
+		limit  int64
+	}
+
+	// tCursor 游标内容：排序签名与上一页末行的排序值，编码后对调用方不透明
+	tCursor struct {
+		Order  string `json:"o"`
+		Values []any  `json:"v"`
+	}
+)
+
+// ReadPage 键集(seek)分页读取：按上一页末行的排序值定位下一页，而非用 OFFSET 跳过，深翻页不退化。
+// cursor 为上一页返回的游标，空表示第一页；返回本页记录与下一页游标，已是最后一页时游标为空。
+// 排序取 OrderBy/Asc/Desc 并自动追加主键使顺序唯一，升降序可混用；排序字段应为非空列
+func (self *TSession) ReadPage(cursor string) (*TDataset, string, error) {
+	keyset := &tKeyset{cursor: cursor}
+	self.Statement.keyset = keyset
+	ds, err := self.Read()
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := keyset.next(self, ds)
+	if err != nil {
+		return nil, "", err
+	}
+	return ds, next, nil
+}
+
+// SearchPage 同 ReadPage 但只返回本页记录的 id
+func (self *TSession) SearchPage(cursor string) ([]any, string, error) {
+	if self.IsDeprecated {
+		return nil, "", ErrInvalidSession
+	}
+	// 只取 id 的翻页不跨分片，须先以 Shard 指定分片；ReadPage 可跨分片
+	if self._shardRouted() {
+		return nil, "", ErrShardRequired
+	}
+
+	defer func() {
+		self._resetStatement()
+		if self.IsAutoClose {
+			self.Close()
+		}
+	}()
+
+	if err := self.CheckAccessRights(OpRead); err != nil {
+		return nil, "", err
+	}
+
+	defer self._onReplica()()
+	model := self.Statement.Model
+	keyset := &tKeyset{cursor: cursor}
+	self.Statement.keyset = keyset
+	sql, params, _, err := self._readSql([]string{model.IdField()}, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	ds, err := self._query(sql, params...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := keyset.next(self, ds)
+	if err != nil {
+		return nil, "", err
+	}
+	return ds.Keys(model.IdField()), next, nil
+}
+
+// _keysetClause 生成键集分页的 ORDER BY 与定位条件；条件按排序项展开为行值比较，升降序混用时逐项展开：
+//
+//	a ASC, b DESC, id ASC  =>  a > ? OR (a = ? AND b < ?) OR (a = ? AND b = ? AND id > ?)
+func (self *TSession) _keysetClause(query *TQuery, keyset *tKeyset) (order, cond string, params []any, err error) {
+	model := self.Statement.Model
+	idField := model.IdField()
+	terms := self.Statement.generate_order_terms(model.Table(), self.Statement.OrderByClause, query)
+	hasId := false
+	for _, term := range terms {
+		if term.field == idField {
+			hasId = true
+			break
+		}
+	}
+	if !hasId {
+		terms = append(terms, tOrderTerm{field: idField, expr: fmt.Sprintf(`"%s"."%s"`, model.Table(), idField), direction: "ASC"})
+	}
+	keyset.model = model
+	keyset.terms = terms
+
+	elements := make([]string, len(terms))
+	for i, term := range terms {
+		elements[i] = fmt.Sprintf(`%s %s`, term.expr, term.direction)
+	}
+	order = fmt.Sprintf(` ORDER BY %s `, strings.Join(elements, ","))
+
+	if keyset.cursor == "" {
+		return order, "", nil, nil
+	}
+	values, err := decodeCursor(keyset.cursor, terms)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// 同向排序直接用行值比较，便于使用联合索引
+	sameDirection := true
+	for _, term := range terms[1:] {
+		if term.desc() != terms[0].desc() {
+			sameDirection = false
+			break
+		}
+	}
+	if sameDirection {
+		exprs := make([]string, len(terms))
+		holders := make([]string, len(terms))
+		for i, term := range terms {
+			exprs[i] = term.expr
+			holders[i] = "?"
+		}
+		return order, fmt.Sprintf("(%s) %s (%s)", strings.Join(exprs, ", "), terms[0].seekOp(), strings.Join(holders, ", ")), values, nil
+	}
+
+	ors := make([]string, len(terms))
+	for i, term := range terms {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, terms[j].expr+" = ?")
+			params = append(params, values[j])
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", term.expr, term.seekOp()))
+		params = append(params, values[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	return order, "(" + strings.Join(ors, " OR ") + ")", params, nil
+}
+
+func (self tOrderTerm) desc() bool {
+	return self.direction == "DESC"
+}
+
+// seekOp 取排在当前值之后的记录所用的比较符
+func (self tOrderTerm) seekOp() string {
+	if self.desc() {
+		return "<"
+	}
+	return ">"
+}
+
+// orderSignature 排序签名，游标只能用于产生它的同一排序
+func orderSignature(terms []tOrderTerm) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		direction := term.direction
+		if direction == "" {
+			direction = "ASC"
+		}
+		parts[i] = term.field + " " + direction
+	}
+	return strings.Join(parts, ",")
+}
+
+// next 由本页末行的排序值生成下一页游标；本页不足一页时已无下一页
+func (self *tKeyset) next(session *TSession, ds *TDataset) (string, error) {
+	if self.model == nil || self.limit <= 0 || int64(ds.Count()) < self.limit {
+		return "", nil
+	}
+
+	last := ds.Data[ds.Count()-1]
+	values := make([]any, len(self.terms))
+	for i, term := range self.terms {
+		value := last.GetByField(term.field)
+		if field := self.model.GetFieldByName(term.field); field != nil {
+			value = field.onConvertToWrite(session, value)
+		}
+		values[i] = value
+	}
+
+	data, err := json.Marshal(&tCursor{Order: orderSignature(self.terms), Values: values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 解出游标中的排序值，游标须由同一排序产生
+func decodeCursor(token string, terms []tOrderTerm) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor tCursor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // 保持大整数 id 精度
+	if err = decoder.Decode(&cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Order != orderSignature(terms) || len(cursor.Values) != len(terms) {
+		return nil, ErrInvalidCursor
+	}
+
+	for i, value := range cursor.Values {
+		if number, ok := value.(json.Number); ok {
+			if n, err := number.Int64(); err == nil {
+				cursor.Values[i] = n
+			} else if f, err := number.Float64(); err == nil {
+				cursor.Values[i] = f
+			}
+		}
+	}
+	return cursor.Values, nil
+}
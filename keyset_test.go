@@ -0,0 +1,121 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/volts-dev/orm/dialect"
+)
+
+func TestSession_ReadPageKeyset(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(RelatedCountry), new(RepoAuthor), new(RepoBook)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	// 年龄有重复，降序排列后同龄者按姓名升序，最后由 id 保证顺序唯一
+	values := make([]any, 0)
+	for i, age := range []int{30, 40, 30, 50, 40, 30, 20} {
+		values = append(values, map[string]any{"name": fmt.Sprintf("author%d", 7-i), "age": age})
+	}
+	if _, err := o.NewSession().Model("repo_author").Create(values...); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	want, err := o.NewSession().Model("repo_author").Limit(-1).Desc("age").Asc("name").Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	got := make([]any, 0)
+	cursor, pages := "", 0
+	for {
+		ds, next, err := o.NewSession().Model("repo_author").Desc("age").Asc("name").Limit(3).ReadPage(cursor)
+		if err != nil {
+			t.Fatalf("ReadPage: %v", err)
+		}
+		pages++
+		got = append(got, ds.Keys("id")...)
+		if next == "" {
+			break
+		}
+		cursor = next
+		if pages > 5 {
+			t.Fatalf("ReadPage did not stop")
+		}
+	}
+	if pages != 3 || fmt.Sprint(got) != fmt.Sprint(want.Keys("id")) {
+		t.Fatalf("keyset pages should follow the mixed order, got %v in %d pages, want %v", got, pages, want.Keys("id"))
+	}
+
+	// 同向排序走行值比较；只取 id
+	ids, next, err := o.NewSession().Model("repo_author").OrderBy("age").Limit(4).SearchPage("")
+	if err != nil || len(ids) != 4 || next == "" {
+		t.Fatalf("SearchPage first page: %v %q %v", ids, next, err)
+	}
+	rest, last, err := o.NewSession().Model("repo_author").OrderBy("age").Limit(4).SearchPage(next)
+	if err != nil || len(rest) != 3 || last != "" {
+		t.Fatalf("SearchPage last page: %v %q %v", rest, last, err)
+	}
+
+	// 游标只能用于产生它的排序
+	if _, _, err = o.NewSession().Model("repo_author").OrderBy("name").Limit(4).ReadPage(next); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("cursor with another order should be rejected, got %v", err)
+	}
+	if _, _, err = o.NewSession().Model("repo_author").ReadPage("not-a-cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("malformed cursor should be rejected, got %v", err)
+	}
+
+	// ReadRequest 回填下一页游标
+	model, err := o.GetModel("repo_author")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	req := &ReadRequest{Fields: []string{"name"}, OrderBy: []string{"age DESC"}, Limit: 5, Keyset: true}
+	ds, err := model.Read(req)
+	if err != nil || ds.Count() != 5 || req.NextCursor == "" {
+		t.Fatalf("ReadRequest keyset first page: %v %q %v", ds, req.NextCursor, err)
+	}
+	req.Cursor = req.NextCursor
+	if ds, err = model.Read(req); err != nil || ds.Count() != 2 || req.NextCursor != "" {
+		t.Fatalf("ReadRequest keyset last page: %v %q %v", ds, req.NextCursor, err)
+	}
+}
+
+// bracketDialect 以 SQLite 同样接受的方括号引用标识符，用于检查语句是否按方言引用
+type bracketDialect struct {
+	IDialect
+}
+
+func (self *bracketDialect) Quoter() dialect.Quoter {
+	return dialect.Quoter{Prefix: '[', Suffix: ']', IsReserved: dialect.AlwaysReserve}
+}
+
+func TestSession_ReadPageKeysetQuotesAlias(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(RelatedCountry), new(RepoAuthor), new(RepoBook)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	if _, err := o.NewSession().Model("repo_author").Create(
+		map[string]any{"name": "a", "age": 30},
+		map[string]any{"name": "b", "age": 40},
+	); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 排序字段未在读取字段中时追加的别名须按方言引用
+	o.dialect = &bracketDialect{IDialect: o.dialect}
+	recorder := &sqlRecorder{}
+	o.db.AddHook(recorder)
+	ds, next, err := o.NewSession().Model("repo_author").Select("name").Desc("age").Limit(1).ReadPage("")
+	if err != nil || ds.Count() != 1 || next == "" {
+		t.Fatalf("ReadPage: %v %q %v", ds, next, err)
+	}
+	sql := strings.Join(recorder.sqls, ";")
+	if !strings.Contains(sql, "AS [age]") || strings.Contains(sql, `AS "age"`) {
+		t.Fatalf("keyset alias should use the dialect quoter, got %s", sql)
+	}
+}
@@ -0,0 +1,61 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// LockStrength 行锁强度，即 SELECT ... FOR <strength>
+	LockStrength string
+
+	// Lock 悲观锁：读取的记录加行锁直至事务结束，须在事务中使用。
+	// 各数据库支持程度不同，不支持的组合由 IDialect.LockSql 返回 ErrLockNotSupported
+	Lock struct {
+		Strength   LockStrength // 为空时为 LockUpdate
+		Of         []string     // 仅锁定这些表的记录；联表查询未指定时只锁模型本表
+		NoWait     bool         // 记录已被锁定时立即报错而不等待
+		SkipLocked bool         // 跳过已被锁定的记录，用于多消费者的任务队列
+	}
+)
+
+const (
+	LockUpdate      LockStrength = "UPDATE"
+	LockNoKeyUpdate LockStrength = "NO KEY UPDATE" // 不阻塞引用本记录的外键检查(Postgres)
+	LockShare       LockStrength = "SHARE"
+	LockKeyShare    LockStrength = "KEY SHARE" // 仅阻止修改主键与删除(Postgres)
+)
+
+// GetStrength 返回锁强度，未指定时为 LockUpdate
+func (self *Lock) GetStrength() LockStrength {
+	if self.Strength == "" {
+		return LockUpdate
+	}
+	return self.Strength
+}
+
+// lockClause 生成 FOR <strength> [OF ...] [NOWAIT|SKIP LOCKED]
+func lockClause(quoter func(string) string, strength LockStrength, lock *Lock) (string, error) {
+	if lock.NoWait && lock.SkipLocked {
+		return "", fmt.Errorf("%w: NOWAIT and SKIP LOCKED are exclusive", ErrLockNotSupported)
+	}
+
+	var sql strings.Builder
+	sql.WriteString(" FOR ")
+	sql.WriteString(string(strength))
+	for i, table := range lock.Of {
+		if i == 0 {
+			sql.WriteString(" OF ")
+		} else {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(quoter(table))
+	}
+	if lock.NoWait {
+		sql.WriteString(" NOWAIT")
+	}
+	if lock.SkipLocked {
+		sql.WriteString(" SKIP LOCKED")
+	}
+	return sql.String(), nil
+}
@@ -0,0 +1,93 @@
+package orm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPGLockSql(t *testing.T) {
+	d := newPgDialectForTest(t)
+	query := `SELECT "id" FROM "job"`
+
+	cases := []struct {
+		lock *Lock
+		want string
+	}{
+		{&Lock{}, query + " FOR UPDATE"},
+		{&Lock{Strength: LockShare, NoWait: true}, query + " FOR SHARE NOWAIT"},
+		{&Lock{Strength: LockNoKeyUpdate, SkipLocked: true}, query + " FOR NO KEY UPDATE SKIP LOCKED"},
+		{&Lock{Strength: LockKeyShare, Of: []string{"job", "job_type"}}, query + ` FOR KEY SHARE OF "job","job_type"`},
+	}
+	for _, c := range cases {
+		got, err := d.LockSql(query, c.lock)
+		if err != nil || got != c.want {
+			t.Errorf("LockSql(%+v) = %q %v, want %q", *c.lock, got, err, c.want)
+		}
+	}
+
+	if _, err := d.LockSql(query, &Lock{NoWait: true, SkipLocked: true}); !errors.Is(err, ErrLockNotSupported) {
+		t.Errorf("NOWAIT with SKIP LOCKED should be rejected, got %v", err)
+	}
+}
+
+func TestMysqlLockSql(t *testing.T) {
+	d := QueryDialect("mysql")
+	if err := d.Init(nil, &TDataSource{DbType: "mysql", DbName: "testdb"}); err != nil {
+		t.Fatalf("init dialect: %v", err)
+	}
+	query := "SELECT `id` FROM `job`"
+
+	cases := []struct {
+		lock *Lock
+		want string
+	}{
+		{&Lock{Strength: LockNoKeyUpdate, SkipLocked: true}, query + " FOR UPDATE SKIP LOCKED"},
+		{&Lock{Strength: LockKeyShare}, query + " LOCK IN SHARE MODE"},
+		{&Lock{Strength: LockShare, NoWait: true}, query + " FOR SHARE NOWAIT"},
+	}
+	for _, c := range cases {
+		got, err := d.LockSql(query, c.lock)
+		if err != nil || got != c.want {
+			t.Errorf("LockSql(%+v) = %q %v, want %q", *c.lock, got, err, c.want)
+		}
+	}
+}
+
+func TestSession_LockModes(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	if _, err := o.NewSession().Model("bulk_item").Create(map[string]any{"code": "J1"}, map[string]any{"code": "J2"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// SQLite 没有行锁，锁定读取报错而非静默忽略
+	session := o.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := session.Model("bulk_item").Where("state=?", "draft").ForUpdate().Read(); !errors.Is(err, ErrLockNotSupported) {
+		t.Fatalf("FOR UPDATE on sqlite should be rejected, got %v", err)
+	}
+	if session.Statement.Lock != nil {
+		t.Fatalf("lock should be reset with the statement")
+	}
+	if _, _, err := session.Model("bulk_item").ForShare().Search(); !errors.Is(err, ErrLockNotSupported) {
+		t.Fatalf("FOR SHARE on sqlite should be rejected, got %v", err)
+	}
+	if _, err := session.Model("bulk_item").ForUpdate().SkipLocked().Read(); !errors.Is(err, ErrLockNotSupported) {
+		t.Fatalf("SKIP LOCKED on sqlite should be rejected, got %v", err)
+	}
+
+	// 不加锁的读取照常执行
+	ds, err := session.Model("bulk_item").Where("state=?", "draft").Read()
+	if err != nil || ds.Count() != 2 {
+		t.Fatalf("read: %v %v", ds, err)
+	}
+	if err = session.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+}
@@ -0,0 +1,394 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/volts-dev/utils"
+)
+
+// MigrationTable 记录已应用迁移版本的跟踪表
+const MigrationTable = "orm_migration"
+
+type (
+	// TMigration 一个有序、可审阅的结构迁移。Up 按序执行，Down 按序撤销(已是逆序)。
+	// Version 按字符串排序决定应用顺序，Generate 默认用 UTC 时间戳 20060102150405。
+	TMigration struct {
+		Version string
+		Name    string
+		Up      []string
+		Down    []string
+	}
+
+	// TMigrator 迁移入口：从模型与数据库现状的差异生成迁移，并在锁内应用/回滚，
+	// 应用记录写入 MigrationTable。是 Config.DisableSchemaSync 下替代启动期自动同步的正式途径。
+	TMigrator struct {
+		orm    *TOrm
+		Schema string
+	}
+
+	// IMigrationLocker 是可选的 dialect 能力：返回取得/释放跨进程互斥锁的语句，
+	// 保证多实例同时部署时只有一个在应用迁移。unlock 为空表示在迁移事务内取锁、随事务结束自动释放；
+	// 非空表示锁绑定连接，在独占连接上取得，迁移事务提交/回滚后释放。
+	// 不实现的 dialect(sqlite)依靠数据库写锁串行化。
+	IMigrationLocker interface {
+		MigrationLockSql(key string) (lock string, unlock string)
+	}
+)
+
+func (self *TOrm) Migrator() *TMigrator {
+	return &TMigrator{
+		orm:    self,
+		Schema: self.Schema,
+	}
+}
+
+// SetSchema sets the schema namespace the migrator inspects and migrates
+func (self *TMigrator) SetSchema(schema string) *TMigrator {
+	self.Schema = schema
+	return self
+}
+
+func (self *TMigrator) newSession() *TSession {
+	session := NewSession(self.orm)
+	session.Schema = self.Schema
+	return session
+}
+
+// Generate 对比模型与数据库现状生成一个迁移，不执行任何 DDL。
+// 即 PlanSyncModel 的计划加上逐条撤销语句，无差异时返回 Up 为空的迁移。
+func (self *TMigrator) Generate(region, name string, models ...IModel) (*TMigration, error) {
+	session := self.newSession()
+	defer session.Close()
+
+	plan, err := session.PlanSyncModel(region, models...)
+	if err != nil {
+		return nil, err
+	}
+	changes := plan.Changes
+
+	migration := &TMigration{
+		Version: time.Now().UTC().Format("20060102150405"),
+		Name:    name,
+		Up:      make([]string, 0, len(changes)),
+		Down:    make([]string, 0, len(changes)),
+	}
+	for _, change := range changes {
+		if change.Up != "" {
+			migration.Up = append(migration.Up, change.Up)
+		}
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Down != "" {
+			migration.Down = append(migration.Down, changes[i].Down)
+		}
+	}
+
+	return migration, nil
+}
+
+// Applied 返回已应用的迁移版本(升序)
+func (self *TMigrator) Applied() ([]string, error) {
+	session := self.newSession()
+	defer session.Close()
+
+	if err := self._ensureTable(session); err != nil {
+		return nil, err
+	}
+	return self._applied(session)
+}
+
+// Pending 返回 migrations 中尚未应用的部分(按版本升序)
+func (self *TMigrator) Pending(migrations ...*TMigration) ([]*TMigration, error) {
+	versions, err := self.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+
+	pending := make([]*TMigration, 0)
+	for _, m := range sortMigrations(migrations) {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Up 按版本升序应用所有未应用的迁移，返回本次应用的版本。
+// 每个迁移在独立事务内执行：取锁→复查是否已应用→执行 Up→写入跟踪表。
+// 注意 mysql 的 DDL 会隐式提交，失败时已执行的语句无法回滚。
+func (self *TMigrator) Up(migrations ...*TMigration) (versions []string, err error) {
+	session := self.newSession()
+	defer session.Close()
+
+	if err = self._ensureTable(session); err != nil {
+		return nil, err
+	}
+
+	versions = make([]string, 0)
+	for _, m := range sortMigrations(migrations) {
+		applied, err := self._run(session, m, true)
+		if err != nil {
+			return versions, fmt.Errorf("migration %s_%s: %w", m.Version, m.Name, err)
+		}
+		if applied {
+			versions = append(versions, m.Version)
+			log.Infof("Migration %s_%s applied!", m.Version, m.Name)
+		}
+	}
+
+	return versions, nil
+}
+
+// Down 回滚 migrations 中最近一个已应用的迁移，返回其版本；无可回滚时返回空串。
+func (self *TMigrator) Down(migrations ...*TMigration) (string, error) {
+	versions, err := self.Applied()
+	if err != nil {
+		return "", err
+	}
+
+	applied := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+
+	sorted := sortMigrations(migrations)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		m := sorted[i]
+		if !applied[m.Version] {
+			continue
+		}
+
+		session := self.newSession()
+		_, err := self._run(session, m, false)
+		session.Close()
+		if err != nil {
+			return "", fmt.Errorf("migration %s_%s: %w", m.Version, m.Name, err)
+		}
+
+		log.Infof("Migration %s_%s rolled back!", m.Version, m.Name)
+		return m.Version, nil
+	}
+
+	return "", nil
+}
+
+// releaseConnLock 释放绑定连接的迁移锁。迁移常因 context 取消而失败，解锁不沿用会话的 context；
+// 解锁失败时丢弃该物理连接(关闭连接即释放锁)，不能带着锁回到连接池阻塞之后的迁移
+func releaseConnLock(conn *sql.Conn, unlock string) {
+	if _, err := conn.ExecContext(context.Background(), unlock); err != nil {
+		log.Warnf("release migration lock failed, discard the connection: %v", err)
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
+
+// _run 在事务与迁移锁内执行单个迁移的 Up(up=true)或 Down，返回是否实际执行
+func (self *TMigrator) _run(session *TSession, m *TMigration, up bool) (done bool, err error) {
+	var lock, unlock string
+	if locker, ok := self.orm.dialect.(IMigrationLocker); ok {
+		lock, unlock = locker.MigrationLockSql(self._lockKey())
+	}
+
+	// 需显式释放的锁(mysql GET_LOCK)绑定连接：在独占连接上持有，待事务提交/回滚后再释放。
+	// 先于事务释放会让其他实例在版本记录提交前取得锁，重复执行同一迁移
+	if unlock != "" {
+		conn, err := session.db.Conn(session.context)
+		if err != nil {
+			return false, err
+		}
+		defer conn.Close()
+		if _, err = conn.ExecContext(session.context, lock); err != nil {
+			return false, err
+		}
+		defer releaseConnLock(conn, unlock)
+		lock = ""
+	}
+
+	if err = session.Begin(); err != nil {
+		return false, err
+	}
+	// 晚于解锁 defer 注册 → 先执行：提交/回滚之后才释放锁
+	defer func() {
+		if err != nil {
+			session.Rollback(err)
+			return
+		}
+		err = session.Commit()
+	}()
+
+	// 事务级锁(postgres advisory xact lock)随事务结束自动释放
+	if lock != "" {
+		if _, err = session._query(lock); err != nil {
+			return false, err
+		}
+	}
+
+	// 取锁后复查：等锁期间其他实例可能已经应用/回滚了同一版本
+	versions, err := self._applied(session)
+	if err != nil {
+		return false, err
+	}
+	if applied := utils.IndexOf(m.Version, versions...) > -1; applied == up {
+		return false, nil
+	}
+
+	quoter := self.orm.dialect.Quoter()
+	table := quoter.QuoteTable(self.Schema, MigrationTable)
+	stmts := m.Down
+	if up {
+		stmts = m.Up
+	}
+	for _, sql := range stmts {
+		if _, err = session._exec(sql); err != nil {
+			return false, err
+		}
+	}
+
+	if up {
+		_, err = session._exec(fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?)", table,
+			quoter.Quote("version"), quoter.Quote("name"), quoter.Quote("applied_at")),
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+	} else {
+		_, err = session._exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, quoter.Quote("version")), m.Version)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (self *TMigrator) _ensureTable(session *TSession) error {
+	quoter := self.orm.dialect.Quoter()
+	_, err := session._exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s VARCHAR(64) NOT NULL PRIMARY KEY, %s VARCHAR(255), %s VARCHAR(64))",
+		quoter.QuoteTable(self.Schema, MigrationTable),
+		quoter.Quote("version"), quoter.Quote("name"), quoter.Quote("applied_at")))
+	return err
+}
+
+func (self *TMigrator) _applied(session *TSession) ([]string, error) {
+	quoter := self.orm.dialect.Quoter()
+	ds, err := session._query(fmt.Sprintf("SELECT %s FROM %s ORDER BY %s",
+		quoter.Quote("version"), quoter.QuoteTable(self.Schema, MigrationTable), quoter.Quote("version")))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, ds.Count())
+	ds.First()
+	for !ds.Eof() {
+		versions = append(versions, ds.FieldByName("version").AsString())
+		ds.Next()
+	}
+	return versions, nil
+}
+
+// _lockKey 迁移锁按 schema 区分，不同 schema(如 per-tenant)可并行迁移
+func (self *TMigrator) _lockKey() string {
+	return MigrationTable + ":" + self.Schema
+}
+
+// WriteFiles 把迁移写成 <version>_<name>.up.sql / .down.sql 两个文件供审阅与入库，
+// 每条语句独占一段并以 ";" 结尾。
+func (self *TMigration) WriteFiles(dir string) error {
+	base := filepath.Join(dir, self.Version+"_"+self.Name)
+	if err := os.WriteFile(base+".up.sql", []byte(joinStatements(self.Up)), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(base+".down.sql", []byte(joinStatements(self.Down)), 0o644)
+}
+
+// LoadMigrations 读取 dir 下由 WriteFiles 写出(或手写的同格式)迁移文件，按版本升序返回。
+// .down.sql 缺失时该迁移不可回滚(Down 为空)。
+func LoadMigrations(dir string) ([]*TMigration, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]*TMigration, 0, len(files))
+	for _, file := range files {
+		base := strings.TrimSuffix(filepath.Base(file), ".up.sql")
+		version, name, _ := strings.Cut(base, "_")
+
+		up, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m := &TMigration{Version: version, Name: name, Up: splitStatements(string(up))}
+		down, err := os.ReadFile(filepath.Join(dir, base+".down.sql"))
+		if err == nil {
+			m.Down = splitStatements(string(down))
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		migrations = append(migrations, m)
+	}
+
+	return sortMigrations(migrations), nil
+}
+
+func sortMigrations(migrations []*TMigration) []*TMigration {
+	sorted := make([]*TMigration, len(migrations))
+	copy(sorted, migrations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted
+}
+
+func joinStatements(stmts []string) string {
+	var b strings.Builder
+	for _, s := range stmts {
+		b.WriteString(strings.TrimSuffix(strings.TrimSpace(s), ";"))
+		b.WriteString(";\n\n")
+	}
+	return b.String()
+}
+
+// splitStatements 按行尾的 ";" 切分语句。行内的 ";"(如 postgres 补列附带的
+// "; COMMENT ON ...")不切分，保持与生成时的单条语句一致。
+func splitStatements(content string) []string {
+	stmts := make([]string, 0)
+	var b strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(line)
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(b.String()), ";"))
+			b.Reset()
+		}
+	}
+	if s := strings.TrimSpace(b.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+func migrationLockId(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,204 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// BenchModelV2 是 BenchModel 的下一版结构：同表新增一列
+type BenchModelV2 struct {
+	TModel `table:"name('bench_model')"`
+	Id     int64  `field:"pk autoincr title('ID')"`
+	Name   string `field:"varchar() size(64) index"`
+	Age    int    `field:"int()"`
+	Email  string `field:"varchar() size(128)"`
+}
+
+func setupMigrationOrm(t *testing.T) *TOrm {
+	t.Helper()
+	// 文件库而非 :memory:——迁移在事务连接与普通连接间切换，内存库每条连接各自独立
+	ds := &TDataSource{DbType: "sqlite", DbName: filepath.Join(t.TempDir(), "migration.db")}
+	o, err := New(WithDataSource(ds))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return o
+}
+
+func TestMigrator_GenerateApplyAndRollback(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+
+	migrator := o.Migrator()
+	initial, err := migrator.Generate("", "init", new(BenchModel))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	initial.Version = "0001"
+	if len(initial.Up) == 0 || !strings.HasPrefix(initial.Up[0], "CREATE TABLE") {
+		t.Fatalf("initial migration should start with CREATE TABLE, got %v", initial.Up)
+	}
+	if len(initial.Down) != 1 || !strings.HasPrefix(initial.Down[0], "DROP TABLE") {
+		t.Fatalf("initial migration should roll back with a single DROP TABLE, got %v", initial.Down)
+	}
+	if exist, _ := o.IsTableExist("bench_model"); exist {
+		t.Fatal("Generate must not touch the database")
+	}
+
+	versions, err := migrator.Up(initial)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(versions) != 1 || versions[0] != "0001" {
+		t.Fatalf("Up should apply 0001, got %v", versions)
+	}
+	if exist, _ := o.IsTableExist("bench_model"); !exist {
+		t.Fatal("bench_model should exist after Up")
+	}
+
+	// 再次应用同一版本是幂等的
+	if versions, err = migrator.Up(initial); err != nil || len(versions) != 0 {
+		t.Fatalf("re-applying should be a no-op, got %v, %v", versions, err)
+	}
+
+	next, err := migrator.Generate("", "add_email", new(BenchModelV2))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	next.Version = "0002"
+	if len(next.Up) != 1 || !strings.Contains(next.Up[0], "email") {
+		t.Fatalf("second migration should only add the email column, got %v", next.Up)
+	}
+	if len(next.Down) != 1 || !strings.Contains(next.Down[0], "DROP COLUMN") {
+		t.Fatalf("second migration should roll back with DROP COLUMN, got %v", next.Down)
+	}
+
+	// 落盘再读回，走审阅后的文件应用
+	dir := t.TempDir()
+	for _, m := range []*TMigration{initial, next} {
+		if err := m.WriteFiles(dir); err != nil {
+			t.Fatalf("WriteFiles: %v", err)
+		}
+	}
+	loaded, err := LoadMigrations(dir)
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	if len(loaded) != 2 || loaded[1].Name != "add_email" || len(loaded[1].Up) != 1 {
+		t.Fatalf("LoadMigrations round trip mismatch: %+v", loaded)
+	}
+
+	pending, err := migrator.Pending(loaded...)
+	if err != nil || len(pending) != 1 || pending[0].Version != "0002" {
+		t.Fatalf("only 0002 should be pending, got %v, %v", pending, err)
+	}
+	if _, err = migrator.Up(loaded...); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if applied, _ := migrator.Applied(); len(applied) != 2 {
+		t.Fatalf("both migrations should be recorded, got %v", applied)
+	}
+
+	version, err := migrator.Down(loaded...)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if version != "0002" {
+		t.Fatalf("Down should roll back the latest migration, got %q", version)
+	}
+	if applied, _ := migrator.Applied(); len(applied) != 1 || applied[0] != "0001" {
+		t.Fatalf("only 0001 should remain applied, got %v", applied)
+	}
+}
+
+func TestSplitStatements_KeepsInlineSemicolons(t *testing.T) {
+	content := "ALTER TABLE a ADD b INT; COMMENT ON COLUMN a.b IS 'x';\n\n-- note\nCREATE INDEX i ON a (b);\n"
+	stmts := splitStatements(content)
+	if len(stmts) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %q", len(stmts), stmts)
+	}
+	if stmts[0] != "ALTER TABLE a ADD b INT; COMMENT ON COLUMN a.b IS 'x'" {
+		t.Fatalf("unexpected first statement %q", stmts[0])
+	}
+}
+
+// connLockDialect 模拟绑定连接、需显式释放的迁移锁(如 mysql GET_LOCK)
+type connLockDialect struct {
+	IDialect
+	unlock string
+}
+
+func (self *connLockDialect) MigrationLockSql(key string) (string, string) {
+	return "SELECT 1", self.unlock
+}
+
+func TestMigrator_ReleaseLockAfterCommit(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.db.Exec("CREATE TABLE mig_lock_log (applied INT)"); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	// 释放锁时记下其他连接可见的已应用版本数：提交之后释放才能看到本次写入的版本
+	o.dialect = &connLockDialect{IDialect: o.dialect, unlock: "INSERT INTO mig_lock_log SELECT count(1) FROM " + MigrationTable}
+
+	m := &TMigration{Version: "0001", Name: "noop", Up: []string{"CREATE TABLE mig_noop (id INT)"}}
+	if _, err := o.Migrator().Up(m); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	ds, err := o.NewSession().Query("SELECT applied FROM mig_lock_log")
+	if err != nil || ds.Count() != 1 {
+		t.Fatalf("unlock should run once: %v", err)
+	}
+	if n := ds.FieldByName("applied").AsInteger(); n != 1 {
+		t.Fatalf("lock released before the version row was committed, saw %d versions", n)
+	}
+}
+
+func TestMigrator_ReleaseLockAfterCancel(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.db.Exec("CREATE TABLE mig_lock_log (applied INT)"); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if _, err := o.Migrator().Applied(); err != nil {
+		t.Fatalf("Applied: %v", err)
+	}
+	o.dialect = &connLockDialect{IDialect: o.dialect, unlock: "INSERT INTO mig_lock_log VALUES (0)"}
+
+	// 迁移执行中 context 被取消，锁仍须释放
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	m := &TMigration{Version: "0001", Name: "endless", Up: []string{
+		"WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(1) FROM c",
+	}}
+	session := o.NewSession().WithContext(ctx)
+	defer session.Close()
+	if _, err := o.Migrator()._run(session, m, true); !errors.Is(err, context.Canceled) {
+		t.Fatalf("migration should fail with the cancelled context, got %v", err)
+	}
+	ds, err := o.NewSession().Query("SELECT applied FROM mig_lock_log")
+	if err != nil || ds.Count() != 1 {
+		t.Fatalf("unlock should run after the context is cancelled: %v", err)
+	}
+}
+
+func TestReleaseConnLock_DiscardsConnOnFailure(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+
+	conn, err := o.db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer conn.Close()
+	releaseConnLock(conn, "SELECT * FROM no_such_lock")
+	if _, err = conn.ExecContext(context.Background(), "SELECT 1"); !errors.Is(err, sql.ErrConnDone) {
+		t.Fatalf("connection should be discarded after a failed unlock, got %v", err)
+	}
+}
@@ -156,10 +156,11 @@ type (
 
 	ModelOption  func(*ModelOptions)
 	ModelOptions struct {
-		Model   IModel // 模型自己
-		Module  string //TODO 更名称 属于哪个模块所有
-		Order   []string
-		Context context.Context
+		Model         IModel // 模型自己
+		Module        string //TODO 更名称 属于哪个模块所有
+		Order         []string
+		Context       context.Context
+		ShardResolver IShardResolver // 分片解析器，见 WithShardResolver
 	}
 
 	// 所有成员都是Unexportable 小写,避免映射JSON,XML,ORM等时发生错误
@@ -320,6 +321,7 @@ func (self *TModel) Orm() *TOrm {
 // Provide api to query records from cache or database
 func (self *TModel) Db() *TSession {
 	session := NewSession(self.orm)
+	session.WithContext(self.options.Context) // 携带调用方身份(WithUser)供记录规则使用
 	/* 提供参考Model*/
 	session.Statement.Model = self.prototype
 	/* 从Model获取必要信息 */
@@ -389,6 +391,7 @@ func (self *TModel) Builder() *ModelBuilder {
 // Provide api to query records from cache or database
 func (self *TModel) Records() *TSession {
 	session := NewSession(self.orm)
+	session.WithContext(self.options.Context) // 携带调用方身份(WithUser)供记录规则使用
 	/* 提供参考Model*/
 	session.Statement.Model = self.prototype
 	/* 从Model获取必要信息 */
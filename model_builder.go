@@ -86,6 +86,17 @@ func (self *ModelBuilder) TableOrder(field string) *ModelBuilder {
 	return self
 }
 
+// Audit 开启字段级审计，等同 table 标签 audit
+func (self *ModelBuilder) Audit() *ModelBuilder {
+	if err := tag_table_audit(&TTagContext{
+		Orm:   self.Orm,
+		Model: self.model,
+	}); err != nil {
+		log.Warn(err.Error())
+	}
+	return self
+}
+
 func (self *ModelBuilder) TableRelate(modelName, relateField string) *ModelBuilder {
 	if err := tag_table_relate(&TTagContext{
 		Orm:    self.Orm,
@@ -314,6 +325,54 @@ func (self *fieldStatment) Setter(fn func(ctx *TFieldContext) error) *fieldStatm
 	return self
 }
 
+// 存储型计算字段：依赖(Depends)写入时重算并写回数据库
+// 最终结果由Ctx。SetValue 返回，[]any 与 Ctx.Ids 逐条对应
+func (self *fieldStatment) Compute(fn func(ctx *TFieldContext) error) *fieldStatment {
+	field := self.field
+	field.Base().computeFunc = fn
+	builder := self.builder
+
+	if err := tag_compute(&TTagContext{
+		Orm:        builder.Orm,
+		Model:      builder.model,
+		Field:      field,
+		ModelValue: builder.model.modelValue,
+	}); err != nil {
+		log.Warn(err.Error())
+	}
+
+	return self
+}
+
+// 计算字段的依赖，支持 many2one/one2many 点路径如 "partner_id.name"
+func (self *fieldStatment) Depends(paths ...string) *fieldStatment {
+	if err := tag_depends(&TTagContext{
+		Orm:    self.builder.Orm,
+		Model:  self.builder.model,
+		Field:  self.field,
+		Params: paths,
+	}); err != nil {
+		log.Warn(err.Error())
+	}
+
+	return self
+}
+
+// 关联字段，值沿 many2one 点路径取得如 "partner_id.country_id.name"
+// 默认不存储，需落库时再调用 Store(true)
+func (self *fieldStatment) Related(path string) *fieldStatment {
+	if err := tag_related(&TTagContext{
+		Orm:    self.builder.Orm,
+		Model:  self.builder.model,
+		Field:  self.field,
+		Params: []string{path},
+	}); err != nil {
+		log.Warn(err.Error())
+	}
+
+	return self
+}
+
 func (self *fieldStatment) Size(v int) *fieldStatment {
 	self.field.Base().size = v
 	return self
@@ -553,7 +612,33 @@ func (self *fieldStatment) Named() *fieldStatment {
 }
 
 func (self *fieldStatment) OldName(name string) *fieldStatment {
-	// TODO: schema migration rename tracking is not yet implemented (tag_old_name is also a no-op)
+	field := self.field
+	builder := self.builder
+	if err := tag_old_name(&TTagContext{
+		Orm:        builder.Orm,
+		Model:      builder.model,
+		Field:      field,
+		ModelValue: builder.model.modelValue,
+		Params:     []string{name},
+	}); err != nil {
+		log.Warn(err.Error())
+	}
+	return self
+}
+
+// 限定可访问该字段的权限组，未声明时所有用户可访问
+func (self *fieldStatment) Groups(groups ...string) *fieldStatment {
+	field := self.field
+	builder := self.builder
+	if err := tag_groups(&TTagContext{
+		Orm:        builder.Orm,
+		Model:      builder.model,
+		Field:      field,
+		ModelValue: builder.model.modelValue,
+		Params:     groups,
+	}); err != nil {
+		log.Warn(err.Error())
+	}
 	return self
 }
 
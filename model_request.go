@@ -34,7 +34,7 @@ type (
 
 	CreateRequest struct {
 		Context    context.Context
-		Data       []any  // * 多条数据记录
+		Data       []any  // * 多条数据记录，按批合并插入(见 TSession._bulkCreate)
 		Model      string // *
 		Method     string
 		OnConflict OnConflict
@@ -72,6 +72,15 @@ type (
 		// Limit 每页多少条记录，-1则无限制
 		Limit int64 `json:"limit"`
 
+		// Keyset 为 true 时按键集(seek)分页读取，忽略 Offset；Cursor 非空时同样启用
+		Keyset bool
+
+		// Cursor 上一页返回的 NextCursor，空表示第一页
+		Cursor string
+
+		// NextCursor 由 Read 回填的下一页游标，已是最后一页时为空
+		NextCursor string
+
 		// Model 是一个字符串，用于指定查询的模型
 		Model string
 
@@ -101,7 +110,7 @@ type (
 	UpdateRequest struct {
 		Context context.Context
 		Ids     []any    // 多条数据记录
-		Data    []any    // 多条数据记录
+		Data    []any    // 多条数据记录，无 Ids/Domain 时按各自主键批量更新
 		Fields  []string // 指定查询和返回字段
 		Domain  any      // update 支持查询条件
 		Model   string   // *
@@ -140,6 +149,20 @@ func (self *TModel) Create(req *CreateRequest) ([]any, error) {
 		defer session.Close()
 	}
 
+	if req.Context != nil {
+		session.WithContext(req.Context)
+	}
+	if err := session.CheckAccessRights(OpCreate); err != nil {
+		return nil, err
+	}
+	for _, data := range req.Data {
+		if err := session._checkFieldWrite(OpCreate, data); err != nil {
+			return nil, err
+		}
+	}
+
+	session._omitDeniedFields()
+
 	if req.OnConflict.Fields != nil || req.OnConflict.DoUpdates != nil || req.OnConflict.DoNothing || req.OnConflict.UpdateAll || req.OnConflict.OnConstraint != "" {
 		session.OnConflict(&req.OnConflict)
 	}
@@ -160,6 +183,9 @@ func (self *TModel) Read(req *ReadRequest) (*dataset.TDataSet, error) {
 	if session.IsAutoClose {
 		defer session.Close()
 	}
+	if err := session.CheckAccessRights(OpRead); err != nil {
+		return nil, err
+	}
 	// Offset is a direct SQL offset (0-based); negative values are treated as 0.
 	if req.Offset < 0 {
 		req.Offset = 0
@@ -217,6 +243,7 @@ func (self *TModel) Read(req *ReadRequest) (*dataset.TDataSet, error) {
 	}
 
 	session.Select(fields...)
+	session._omitDeniedFields() // 无权访问的字段静默剔除
 
 	if len(req.Ids) > 0 {
 		session.Ids(req.Ids...)
@@ -228,6 +255,11 @@ func (self *TModel) Read(req *ReadRequest) (*dataset.TDataSet, error) {
 	if req.ClassicRead {
 		rs = rs.Classic()
 	}
+	if req.Keyset || req.Cursor != "" {
+		ds, next, err := rs.ReadPage(req.Cursor)
+		req.NextCursor = next
+		return ds, err
+	}
 	return rs.Read()
 }
 
@@ -283,6 +315,18 @@ func (self *TModel) Update(req *UpdateRequest) (int64, error) {
 		defer session.Close()
 	}
 
+	if req.Context != nil {
+		session.WithContext(req.Context)
+	}
+	if err := session.CheckAccessRights(OpWrite); err != nil {
+		return 0, err
+	}
+	for _, data := range req.Data {
+		if err := session._checkFieldWrite(OpWrite, data); err != nil {
+			return 0, err
+		}
+	}
+
 	var effectCount int64
 
 	// 更新多个ID上的数据
@@ -292,6 +336,7 @@ func (self *TModel) Update(req *UpdateRequest) (int64, error) {
 		}
 		data := req.Data[0]
 		for _, id := range req.Ids {
+			session._omitDeniedFields()
 			id, err := session.Ids(id).Write(data)
 			if err != nil {
 				return 0, err
@@ -303,9 +348,14 @@ func (self *TModel) Update(req *UpdateRequest) (int64, error) {
 
 	if req.Domain != nil && req.Domain != "" {
 		session.Domain(req.Domain)
+	} else if len(req.Data) > 1 && !session._shardRouted() {
+		// 各条数据按自带的主键更新，合并为批量语句；分片模型的批量更新不跨分片，逐条按主键路由
+		session._omitDeniedFields()
+		return session.WriteMany(req.Data...)
 	}
 
 	for _, d := range req.Data {
+		session._omitDeniedFields()
 		id, err := session.Write(d)
 		if err != nil {
 			return 0, err
@@ -328,6 +378,13 @@ func (self *TModel) Delete(req *DeleteRequest) (int64, error) {
 		defer session.Close()
 	}
 
+	if req.Context != nil {
+		session.WithContext(req.Context)
+	}
+	if err := session.CheckAccessRights(OpDelete); err != nil {
+		return 0, err
+	}
+
 	effectCount, err := session.Delete(req.Ids...)
 	if err != nil {
 		return 0, err
@@ -784,7 +841,7 @@ func (self *TModel) ManyToMany(ctx *TFieldContext) (*dataset.TDataSet, error) {
 	// # 获取字段关联表的字符
 	// the table name in cacher
 	cacher_table_name := midTableName + "_" + relTableName
-	group := orm.Cacher.GetBySql(cacher_table_name, query, params)
+	group := sess._getBySql(cacher_table_name, query, params)
 	if group == nil {
 		// TODO 只查询缺的记录不查询所有
 		// # 如果缺省缓存记录重新查询
@@ -795,7 +852,7 @@ func (self *TModel) ManyToMany(ctx *TFieldContext) (*dataset.TDataSet, error) {
 		}
 
 		// # store result in cache
-		orm.Cacher.PutBySql(cacher_table_name, query, params, group) // # 添加Sql查询结果
+		sess._putBySql(cacher_table_name, query, params, group) // # 添加Sql查询结果，关联表或中间表变动时失效
 	}
 
 	return group, nil
@@ -1034,6 +1091,9 @@ func (self *TModel) GroupBy(req *ReadRequest) (*dataset.TDataSet, error) {
 	if session.IsAutoClose {
 		defer session.Close()
 	}
+	if err := session.CheckAccessRights(OpRead); err != nil {
+		return nil, err
+	}
 	// Offset is a direct SQL offset (0-based); negative values are treated as 0.
 	if req.Offset < 0 {
 		req.Offset = 0
@@ -52,7 +52,9 @@ type (
 		config    *Config
 		dialect   IDialect
 		db        *core.DB
-		osv       *TOsv // 对象管理
+		replicas  *tReplicaPool // 只读副本，未配置时为 nil
+		shards    []*TOrm       // 水平分片，按下标定位，见 Config.Shards
+		osv       *TOsv         // 对象管理
 		nameIndex map[string]*TModel
 		connected bool
 		Schema    string // Schema namespace
@@ -72,6 +74,19 @@ type (
 		metaMu    sync.Mutex
 		metaCache map[string]dbMetaEntry
 		metaEpoch atomic.Uint64
+
+		// 事务中写入引起的缓存失效推迟到提交后执行，按事务登记：见 TSession._invalidate
+		txInvalidMu sync.Mutex
+		txInvalid   map[*core.Tx][]func(*cacher.TCacher)
+
+		// 记录规则与模型访问控制注册表，按模型名索引：见 AddRecordRule/AddModelAccess
+		ruleLock    sync.RWMutex
+		recordRules map[string][]*TRecordRule
+		modelAccess map[string][]*TModelAccess
+
+		// 存储型计算字段的重算触发表，按被写模型名索引；nil 表示待重建，见 _computeTriggers
+		computeLock     sync.RWMutex
+		computeTriggers map[string][]*tComputeTrigger
 	}
 
 	dbMetaEntry struct {
@@ -114,8 +129,25 @@ func New(opt ...Option) (*TOrm, error) {
 		nameIndex: make(map[string]*TModel),
 	}
 
+	if len(cfg.Replicas) > 0 {
+		if orm.replicas, err = newReplicaPool(cfg.DataSource, cfg.Replicas, cfg.ReplicaCheckInterval); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if len(cfg.Shards) > 0 {
+		if orm.shards, err = newShards(cfg); err != nil {
+			if orm.replicas != nil {
+				orm.replicas.close()
+			}
+			db.Close()
+			return nil, err
+		}
+	}
+
 	// Cacher
-	orm.Cacher, err = cacher.New()
+	orm.Cacher, err = cacher.New(cacher.WithBackend(cfg.CacheBackend))
 	if err != nil {
 		log.Trace(err)
 		return nil, err
@@ -173,6 +205,15 @@ func (self *TOrm) Ping() error {
 // close the entire orm engine
 func (self *TOrm) Close() error {
 	// TODO more
+	if self.replicas != nil {
+		self.replicas.close()
+	}
+	for _, shard := range self.shards {
+		shard.Close()
+	}
+	if self.Cacher != nil {
+		self.Cacher.Close()
+	}
 	return self.db.Close()
 }
 
@@ -236,6 +277,9 @@ func (self *TOrm) SyncModel(region string, models ...IModel) (modelNames []strin
 	}
 
 	session := NewSession(self)
+	if self.config.AllowDestructiveSync {
+		session.AllowUnsafe()
+	}
 	session.Begin()
 	defer func() {
 		session.Rollback(err)
@@ -246,8 +290,25 @@ func (self *TOrm) SyncModel(region string, models ...IModel) (modelNames []strin
 	if err != nil {
 		return nil, err
 	}
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+
+	// 各分片上的表结构与主库保持一致
+	for idx, shard := range self.shards {
+		if _, err = shard.SyncModel(region, models...); err != nil {
+			return nil, fmt.Errorf("sync shard %d: %w", idx, err)
+		}
+	}
+	return modelNames, nil
+}
 
-	return modelNames, session.Commit()
+// PlanSyncModel returns the DDL SyncModel would run for the models without executing it.
+// 不受 Config.DisableSchemaSync 影响。
+func (self *TOrm) PlanSyncModel(region string, models ...IModel) (*TSyncPlan, error) {
+	session := NewSession(self)
+	defer session.Close()
+	return session.PlanSyncModel(region, models...)
 }
 
 func (self *TOrm) HasModel(name string) bool {
@@ -283,6 +344,12 @@ func (self *TOrm) GetModels() []string {
 // SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
 func (self *TOrm) SetConnMaxLifetime(d time.Duration) {
 	self.db.SetConnMaxLifetime(d)
+	if self.replicas != nil {
+		self.replicas.setConnMaxLifetime(d)
+	}
+	for _, shard := range self.shards {
+		shard.SetConnMaxLifetime(d)
+	}
 }
 
 // Import SQL DDL file
@@ -743,6 +810,17 @@ func (self *TOrm) _mapping(model any) (*TModel, error) {
 				return nil, err
 			}
 
+			// 关联字段在所有Tag执行后统一定夺存储，与Tag先后无关：仅显式的 store 标签可令其落库
+			if field.Base().relatedPath != "" {
+				field.Base().store = false
+				if params, has := tagMaps[TAG_STORE]; has {
+					tagCtx.Params = params
+					if err = tag_store(tagCtx); err != nil {
+						return nil, err
+					}
+				}
+			}
+
 			if field.Base().isAutoIncrement && field.Base().isPrimaryKey {
 				res_model.idField = field.Name()
 			}
@@ -30,6 +30,8 @@ type (
 		DeletedField       string
 		VersionField       string
 		AutoIncrementField string
+		ShardResolver      IShardResolver // 按分片键把记录路由到 Config.Shards 之一，见 WithShardResolver
+		Audit              bool           // 字段变更写入审计表
 		// SQL 参数
 		columnsSeq []string //TODO 存储COl名称考虑Remove
 
@@ -62,6 +64,7 @@ type (
 		orm         *TOrm
 		models      sync.Map // map[string]*TModelObject // 为每个Model存储BaseModel // TODO 名称或许为Objects
 		middleModel sync.Map // 标识中间表
+		tables      sync.Map // map[string]string 表名→模型名，供缓存识别语句涉及的表
 		// middleModelDDL 按 "schema|表名" 记录 m2m 关联表 DDL 已执行——关联表须在每个
 		// schema 各建一份(见 TMany2ManyField.UpdateDb)，故不能复用按模型名全局去重的
 		// models/middleModel 作 DDL 守卫。
@@ -297,8 +300,110 @@ func (self *TOsv) newObject(name string) *TModelObject {
 	return obj
 }
 
+// _clone 复制对象元数据供计划模式在副本上合并注册；字段实例共享，合并时只替换不改写
+func (self *TModelObject) _clone() *TModelObject {
+	obj := &TModelObject{
+		name:               self.name,
+		Charset:            self.Charset,
+		Comment:            self.Comment,
+		PrimaryKeys:        append([]string(nil), self.PrimaryKeys...),
+		CreatedField:       make(map[string]bool, len(self.CreatedField)),
+		UpdatedField:       self.UpdatedField,
+		DeletedField:       self.DeletedField,
+		VersionField:       self.VersionField,
+		AutoIncrementField: self.AutoIncrementField,
+		ShardResolver:      self.ShardResolver,
+		Audit:              self.Audit,
+		columnsSeq:         append([]string(nil), self.columnsSeq...),
+		pkgName:            self.pkgName,
+		isCustomModel:      self.isCustomModel,
+		uidFieldName:       self.uidFieldName,
+		nameField:          self.nameField,
+		orderFields:        self.orderFields,
+		indexes:            make(map[string]*TIndex),
+		relatedFields:      make(map[string]*TRelatedField),
+		commonFields:       make(map[string]map[string]IField),
+		methods:            make(map[string]reflect.Type),
+		object_val:         make(map[reflect.Type]*TModel),
+		object_types:       make(map[string]map[string]reflect.Type),
+	}
+	for k, v := range self.CreatedField {
+		obj.CreatedField[k] = v
+	}
+
+	self.indexesLock.RLock()
+	for k, v := range self.indexes {
+		obj.indexes[k] = v
+	}
+	self.indexesLock.RUnlock()
+
+	self.relatedFieldsLock.RLock()
+	for k, v := range self.relatedFields {
+		obj.relatedFields[k] = v
+	}
+	self.relatedFieldsLock.RUnlock()
+
+	self.commonFieldsLock.RLock()
+	for k, v := range self.commonFields {
+		obj.commonFields[k] = v
+	}
+	self.commonFieldsLock.RUnlock()
+
+	self.metaLock.RLock()
+	for k, v := range self.methods {
+		obj.methods[k] = v
+	}
+	for k, v := range self.object_val {
+		obj.object_val[k] = v
+	}
+	for region, types := range self.object_types {
+		obj.object_types[region] = make(map[string]reflect.Type, len(types))
+		for k, v := range types {
+			obj.object_types[region][k] = v
+		}
+	}
+	self.metaLock.RUnlock()
+
+	copySyncMap(&obj.fields, &self.fields)
+	copySyncMap(&obj.relations, &self.relations)
+	copySyncMap(&obj.defaultValues, &self.defaultValues)
+	return obj
+}
+
+// _scratch 复制注册表到 orm 名下，计划模式在副本上映射注册，不改动现有模型与计算触发表
+func (self *TOsv) _scratch(orm *TOrm) *TOsv {
+	osv := newOsv(orm)
+	osv.resolver = self.resolver
+	osv.strictMode = self.strictMode
+	osv.frozen.Store(self.frozen.Load())
+
+	self.models.Range(func(key, value any) bool {
+		if obj, ok := value.(*TModelObject); ok {
+			value = obj._clone()
+		}
+		osv.models.Store(key, value)
+		return true
+	})
+	copySyncMap(&osv.tables, &self.tables)
+	copySyncMap(&osv.middleModel, &self.middleModel)
+	return osv
+}
+
+func copySyncMap(dst, src *sync.Map) {
+	src.Range(func(key, value any) bool {
+		dst.Store(key, value)
+		return true
+	})
+}
+
 // register new model to the object service
 func (self *TOsv) RegisterModel(region string, model *TModel) error {
+	return self._registerModel(region, model, true)
+}
+
+// _registerModel updateDb 为 false 时只注册元数据，不执行字段衍生 DDL(m2m 关联表)，
+// 供 PlanSyncModel/迁移生成这类"只出计划不碰库"的入口使用。
+func (self *TOsv) _registerModel(region string, model *TModel, updateDb bool) error {
 	if self.frozen.Load() {
 		// 冻结仅锁定“模型集合”不再变化：重复注册启动时已注册的同名模型
 		// （如运行期 SyncModel 把既有模型的表物化到另一个 schema）是幂等
@@ -315,6 +420,9 @@ func (self *TOsv) RegisterModel(region string, model *TModel) error {
 		}
 		return ErrOsvFrozen
 	}
+	// 字段集合可能变化，计算字段触发表待重建
+	self.orm._resetComputeTriggers()
+
 	/* 初始化ModelObject */
 	//获得Object 检查是否存在，不存在则创建
 	var obj *TModelObject
@@ -364,6 +472,10 @@ func (self *TOsv) RegisterModel(region string, model *TModel) error {
 			obj.VersionField = new_obj.VersionField
 		}
 
+		if new_obj.Audit {
+			obj.Audit = true
+		}
+
 		// 覆盖默认值
 		new_obj.defaultValues.Range(func(key, value any) bool {
 			obj.defaultValues.Store(key, value)
@@ -451,8 +563,12 @@ func (self *TOsv) RegisterModel(region string, model *TModel) error {
 	for _, opt := range self.orm.config.ModelTemplate.options {
 		opt(model.options)
 	}
+	if model.options.ShardResolver != nil {
+		obj.ShardResolver = model.options.ShardResolver
+	}
 
 	self.models.Store(model.name, obj)
+	self.tables.Store(model.Table(), model.name)
 
 	/* 初始化原型 */
 	{
@@ -464,12 +580,14 @@ func (self *TOsv) RegisterModel(region string, model *TModel) error {
 		}
 
 		/* 更新字段/创建关联中间表 */
-		for _, field := range m.GetFields() {
-			field.UpdateDb(&TTagContext{
-				Orm:   self.orm,
-				Field: field,
-				Model: m,
-			})
+		if updateDb {
+			for _, field := range m.GetFields() {
+				field.UpdateDb(&TTagContext{
+					Orm:   self.orm,
+					Field: field,
+					Model: m,
+				})
+			}
 		}
 	}
 
@@ -553,6 +671,28 @@ func (self *TOsv) GetModels() []string {
 	return models
 }
 
+// _modelNameByType 按注册时的结构体类型查找模型名，未注册返回空字符串
+func (self *TOsv) _modelNameByType(modelType reflect.Type) (name string) {
+	self.models.Range(func(key, value any) bool {
+		obj, ok := value.(*TModelObject)
+		if !ok {
+			return true
+		}
+		obj.metaLock.RLock()
+		defer obj.metaLock.RUnlock()
+		for _, types := range obj.object_types {
+			for _, t := range types {
+				if t == modelType {
+					name = key.(string)
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return name
+}
+
 // @ name
 // @ Session
 // @ Registry
@@ -292,9 +292,8 @@ func (self *TQuery) inherits_join_calc(fieldName string, model IModel) (result s
 	field := model.GetFieldByName(fieldName)
 	dialect := self.session.orm.dialect
 	fieldName = dialect.Quoter().Quote(fieldName)
-	if field != nil && field.Translate() { //  if translate and not callable(translate):
-		// return model.generate_translated_field(alias, field, query)
-		return fmt.Sprintf(`"%s".%s`, alias, fieldName)
+	if field != nil && field.Translate() && field.Store() { //  if translate and not callable(translate):
+		return self.session.orm._translatedColumn(self.session.Schema, self.session.Lang(), model, alias, field)
 	}
 
 	return fmt.Sprintf(`"%s".%s`, alias, fieldName)
@@ -0,0 +1,111 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/utils"
+)
+
+// relatedGetter 非存储关联字段的读取：沿 many2one 链一次 JOIN 取值并填入数据集
+func relatedGetter(ctx *TFieldContext) error {
+	if ctx.Dataset == nil || ctx.Dataset.Count() == 0 {
+		return nil
+	}
+
+	idField := ctx.Model.IdField()
+	ids := ctx.Dataset.Keys(idField)
+	values, err := ctx.Session._relatedValues(ctx.Model, ctx.Field.Base().relatedPath, ids)
+	if err != nil {
+		return err
+	}
+
+	name := ctx.Field.Name()
+	return ctx.Dataset.Range(func(pos int, record *dataset.TRecordSet) error {
+		record.SetByField(name, values[utils.ToString(record.GetByField(idField))])
+		return nil
+	})
+}
+
+// relatedCompute 存储型关联字段的计算函数，由重算机制在源字段变化时调用
+func relatedCompute(ctx *TFieldContext) error {
+	values, err := ctx.Session._relatedValues(ctx.Model, ctx.Field.Base().relatedPath, ctx.Ids)
+	if err != nil {
+		return err
+	}
+
+	result := make([]any, len(ctx.Ids))
+	for i, id := range ctx.Ids {
+		result[i] = values[utils.ToString(id)]
+	}
+	return ctx.SetValue(result)
+}
+
+// _relatedModels 解析关联路径：返回路径上逐级经过的模型(首项为 model)与末端字段
+func (self *TSession) _relatedModels(model IModel, relatedPath string) ([]IModel, IField, error) {
+	path := strings.Split(relatedPath, ".")
+	models := []IModel{model}
+	current := model
+	for _, name := range path[:len(path)-1] {
+		rel := current.GetFieldByName(name)
+		if rel == nil || rel.TypeName() != TYPE_M2O {
+			return nil, nil, fmt.Errorf("related %s of %s: %s is not a many2one field", relatedPath, model.String(), name)
+		}
+
+		next, err := self.orm.osv.GetModel(rel.RelatedModelName())
+		if err != nil {
+			return nil, nil, err
+		}
+		models = append(models, next)
+		current = next
+	}
+
+	last := current.GetFieldByName(path[len(path)-1])
+	if last == nil || !last.Store() {
+		return nil, nil, fmt.Errorf("related %s of %s: %s is not a stored field of %s", relatedPath, model.String(), path[len(path)-1], current.String())
+	}
+	return models, last, nil
+}
+
+// _relatedValues 按 ids 读取关联路径末端的值，以 id 字符串为键；链上任一级为空时值为 nil
+func (self *TSession) _relatedValues(model IModel, relatedPath string, ids []any) (map[string]any, error) {
+	values := make(map[string]any, len(ids))
+	if len(ids) == 0 {
+		return values, nil
+	}
+
+	models, last, err := self._relatedModels(model, relatedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	quoter := self.orm.dialect.Quoter()
+	path := strings.Split(relatedPath, ".")
+	alias := func(i int) string { return quoter.Quote(fmt.Sprintf("t%d", i)) }
+
+	from := quoter.QuoteTable(self.Schema, model.Table()) + " " + alias(0)
+	for i := 1; i < len(models); i++ {
+		from += fmt.Sprintf(" LEFT JOIN %s %s ON %s.%s = %s.%s",
+			quoter.QuoteTable(self.Schema, models[i].Table()), alias(i),
+			alias(i), quoter.Quote(models[i].IdField()),
+			alias(i-1), quoter.Quote(path[i-1]))
+	}
+
+	sql := fmt.Sprintf("SELECT %s.%s AS %s, %s.%s AS %s FROM %s WHERE %s.%s IN (%s)",
+		alias(0), quoter.Quote(model.IdField()), quoter.Quote("res_id"),
+		alias(len(models)-1), quoter.Quote(last.Name()), quoter.Quote("value"),
+		from, alias(0), quoter.Quote(model.IdField()), idsToSqlHolder(ids...))
+
+	// 关联字段按 sudo 取值，与记录规则无关
+	ds, err := self._computeSession()._query(sql, ids...)
+	if err != nil {
+		return nil, err
+	}
+	ds.Range(func(pos int, record *dataset.TRecordSet) error {
+		values[utils.ToString(record.GetByField("res_id"))] = record.GetByField("value")
+		return nil
+	})
+
+	return values, nil
+}
@@ -0,0 +1,140 @@
+package orm
+
+import (
+	"testing"
+)
+
+type (
+	RelatedCountry struct {
+		TModel `table:"name('rl_country')"`
+		Id     int64  `field:"pk autoincr"`
+		Name   string `field:"varchar() size(64)"`
+	}
+
+	RelatedPartner struct {
+		TModel    `table:"name('rl_partner')"`
+		Id        int64  `field:"pk autoincr"`
+		Name      string `field:"varchar() size(64)"`
+		CountryId int64  `field:"many2one(rl_country)"`
+	}
+
+	RelatedOrder struct {
+		TModel      `table:"name('rl_order')"`
+		Id          int64  `field:"pk autoincr"`
+		PartnerId   int64  `field:"many2one(rl_partner)"`
+		CountryName string `field:"varchar() related('partner_id.country_id.name')"`
+		PartnerName string `field:"varchar() size(64) related('partner_id.name') store"`
+	}
+
+	RelatedTagOrder struct {
+		TModel       `table:"name('rl_tag_order')"`
+		Id           int64  `field:"pk autoincr"`
+		PartnerId    int64  `field:"many2one(rl_partner)"`
+		StoredBefore string `field:"varchar() size(64) store related('partner_id.name')"`
+		StoredAfter  string `field:"varchar() size(64) related('partner_id.name') store"`
+		NotStored    string `field:"varchar() size(64) store(false) related('partner_id.name')"`
+		Plain        string `field:"varchar() size(64) related('partner_id.name')"`
+	}
+)
+
+func TestRelated_ReadSearchAndStore(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(RelatedCountry), new(RelatedPartner), new(RelatedOrder)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	model, err := o.GetModel("rl_order")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if field := model.GetFieldByName("country_name"); field == nil || field.Store() {
+		t.Fatalf("related field should not be stored by default")
+	}
+
+	countries, err := o.NewSession().Model("rl_country").Create(map[string]any{"name": "France"}, map[string]any{"name": "Japan"})
+	if err != nil {
+		t.Fatalf("Create countries: %v", err)
+	}
+	partners, err := o.NewSession().Model("rl_partner").Create(
+		map[string]any{"name": "ACME", "country_id": countries[0]},
+		map[string]any{"name": "Initech", "country_id": countries[1]},
+		map[string]any{"name": "Nobody"},
+	)
+	if err != nil {
+		t.Fatalf("Create partners: %v", err)
+	}
+	orders, err := o.NewSession().Model("rl_order").Create(
+		map[string]any{"partner_id": partners[0]},
+		map[string]any{"partner_id": partners[1]},
+		map[string]any{"partner_id": partners[2]},
+	)
+	if err != nil {
+		t.Fatalf("Create orders: %v", err)
+	}
+
+	// 读取：非存储关联字段沿链取值，链上为空时无值
+	if name := computedValue(t, o, "rl_order", orders[0], "country_name").AsString(); name != "France" {
+		t.Fatalf("country_name should read through the chain, got %q", name)
+	}
+	if name := computedValue(t, o, "rl_order", orders[2], "country_name").AsString(); name != "" {
+		t.Fatalf("partner without country should give no country_name, got %q", name)
+	}
+	if name := computedValue(t, o, "rl_order", orders[1], "partner_name").AsString(); name != "Initech" {
+		t.Fatalf("stored partner_name should be computed on create, got %q", name)
+	}
+
+	// 搜索：关联字段条件转为 JOIN
+	ds, err := o.NewSession().Model("rl_order").Domain(`[('country_name', '=', 'Japan')]`).Read()
+	if err != nil {
+		t.Fatalf("Search related: %v", err)
+	}
+	if ds.Count() != 1 || ds.FieldByName("id").AsInteger() != orders[1].(int64) {
+		t.Fatalf("search on related field should match order %v, got %d records", orders[1], ds.Count())
+	}
+
+	// 源字段变更：非存储值即时反映，存储值随之重算
+	if _, err = o.NewSession().Model("rl_country").Ids(countries[1]).Write(map[string]any{"name": "Nippon"}); err != nil {
+		t.Fatalf("Write country: %v", err)
+	}
+	if name := computedValue(t, o, "rl_order", orders[1], "country_name").AsString(); name != "Nippon" {
+		t.Fatalf("country_name should follow the country, got %q", name)
+	}
+	if _, err = o.NewSession().Model("rl_partner").Ids(partners[0]).Write(map[string]any{"name": "ACME Ltd"}); err != nil {
+		t.Fatalf("Write partner: %v", err)
+	}
+	if name := computedValue(t, o, "rl_order", orders[0], "partner_name").AsString(); name != "ACME Ltd" {
+		t.Fatalf("stored partner_name should be recomputed, got %q", name)
+	}
+	cnt, err := o.NewSession().Model("rl_order").Domain(`[('partner_name', '=', 'ACME Ltd')]`).Count()
+	if err != nil || cnt != 1 {
+		t.Fatalf("stored related field should be searchable as a column, got %d: %v", cnt, err)
+	}
+}
+
+func TestRelated_StoreIgnoresTagOrder(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(RelatedCountry), new(RelatedPartner), new(RelatedTagOrder)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	model, err := o.GetModel("rl_tag_order")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	for name, stored := range map[string]bool{
+		"stored_before": true,
+		"stored_after":  true,
+		"not_stored":    false,
+		"plain":         false,
+	} {
+		field := model.GetFieldByName(name)
+		if field == nil {
+			t.Fatalf("field %s missing", name)
+		}
+		if field.Store() != stored {
+			t.Fatalf("field %s store = %v, want %v", name, field.Store(), stored)
+		}
+	}
+}
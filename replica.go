@@ -0,0 +1,218 @@
+package orm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/volts-dev/orm/core"
+)
+
+type (
+	// TReplica 只读副本
+	TReplica struct {
+		DataSource *TDataSource // 未填写的库类型、库名、账号与 SSL 设置沿用主库
+		Weight     int          // 轮询权重，<=0 视为 1；各副本权重相同即为简单轮询
+	}
+
+	// tReplicaPool 副本连接池：按权重平滑轮询健康的副本，定期 Ping 检查健康状态
+	tReplicaPool struct {
+		lock     sync.Mutex
+		nodes    []*tReplicaNode
+		interval time.Duration
+		stop     chan struct{}
+		done     chan struct{}
+	}
+
+	tReplicaNode struct {
+		db      *core.DB
+		name    string
+		weight  int
+		current int // 平滑加权轮询的当前权重
+		healthy bool
+	}
+)
+
+// newReplicaPool 连接各副本；连接失败的副本先标记为不可用，由健康检查恢复
+func newReplicaPool(primary *TDataSource, replicas []*TReplica, interval time.Duration) (*tReplicaPool, error) {
+	pool := &tReplicaPool{
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	for _, replica := range replicas {
+		ds := replica.DataSource.inherit(primary)
+		dsStr, err := ds.toString()
+		if err != nil {
+			pool.close()
+			return nil, err
+		}
+		db, err := core.Open(ds.DbType, dsStr)
+		if err != nil {
+			pool.close()
+			return nil, err
+		}
+
+		weight := replica.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		node := &tReplicaNode{db: db, name: ds.Host + ":" + ds.Port + "/" + ds.DbName, weight: weight}
+		node.healthy = pool.ping(node) == nil
+		if !node.healthy {
+			log.Warnf("replica %s is unavailable", node.name)
+		}
+		pool.nodes = append(pool.nodes, node)
+	}
+
+	if interval > 0 {
+		go pool.run()
+	} else {
+		close(pool.done)
+	}
+	return pool, nil
+}
+
+// pick 平滑加权轮询选出一个健康的副本，全部不可用时返回 nil
+func (self *tReplicaPool) pick() *tReplicaNode {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	var best *tReplicaNode
+	total := 0
+	for _, node := range self.nodes {
+		if !node.healthy {
+			continue
+		}
+		node.current += node.weight
+		total += node.weight
+		if best == nil || node.current > best.current {
+			best = node
+		}
+	}
+	if best != nil {
+		best.current -= total
+	}
+	return best
+}
+
+// markDown 查询时发现副本连接失败，在下次健康检查恢复前不再分发
+func (self *tReplicaPool) markDown(node *tReplicaNode) {
+	self.lock.Lock()
+	if node.healthy {
+		log.Warnf("replica %s is unavailable, reads fall back to the primary", node.name)
+	}
+	node.healthy = false
+	self.lock.Unlock()
+}
+
+func (self *tReplicaPool) ping(node *tReplicaNode) error {
+	timeout := self.interval
+	if timeout <= 0 || timeout > 5*time.Second {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return node.db.PingContext(ctx)
+}
+
+// check Ping 全部副本并更新健康状态
+func (self *tReplicaPool) check() {
+	for _, node := range self.nodes {
+		err := self.ping(node)
+		self.lock.Lock()
+		if healthy := err == nil; healthy != node.healthy {
+			if healthy {
+				log.Infof("replica %s is available again", node.name)
+			} else {
+				log.Warnf("replica %s is unavailable: %v", node.name, err)
+			}
+			node.healthy = healthy
+		}
+		self.lock.Unlock()
+	}
+}
+
+func (self *tReplicaPool) run() {
+	defer close(self.done)
+	ticker := time.NewTicker(self.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-self.stop:
+			return
+		case <-ticker.C:
+			self.check()
+		}
+	}
+}
+
+func (self *tReplicaPool) setConnMaxLifetime(d time.Duration) {
+	for _, node := range self.nodes {
+		node.db.SetConnMaxLifetime(d)
+	}
+}
+
+// close 停止健康检查并关闭全部副本连接
+func (self *tReplicaPool) close() error {
+	select {
+	case <-self.stop:
+	default:
+		close(self.stop)
+	}
+	if self.interval > 0 && len(self.nodes) > 0 {
+		<-self.done
+	}
+
+	var err error
+	for _, node := range self.nodes {
+		if e := node.db.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Primary 本会话的读取一律走主库，用于须读到最新数据的场景
+func (self *TSession) Primary() *TSession {
+	self.usePrimary = true
+	return self
+}
+
+// _onReplica 标记本次读取可由副本执行；写操作进行中(其内部的读取)不受影响。返回恢复函数
+func (self *TSession) _onReplica() func() {
+	if self.writing > 0 {
+		return func() {}
+	}
+	prev := self.replicaRead
+	self.replicaRead = true
+	return func() {
+		self.replicaRead = prev
+	}
+}
+
+// _onPrimary 标记写操作进行中，其间的读取一律走主库。返回恢复函数
+func (self *TSession) _onPrimary() func() {
+	self.writing++
+	return func() {
+		self.writing--
+		self.lastWrite = time.Now()
+	}
+}
+
+// _replica 为非事务查询选出副本：仅限可由副本执行的读取，且不加锁、未强制主库、
+// 未绑定分片、不在写入后的读己之写窗口内；返回 nil 表示走主库
+func (self *TSession) _replica(sql string) *tReplicaNode {
+	pool := self.orm.replicas
+	if pool == nil || !self.replicaRead || self.usePrimary || self.shard >= 0 || self.Statement.Lock != nil {
+		return nil
+	}
+	if window := self.orm.config.ReplicaStickyWindow; window > 0 && time.Since(self.lastWrite) < window {
+		return nil
+	}
+	if firstKeyword(sql) != "SELECT" {
+		return nil
+	}
+	return pool.pick()
+}
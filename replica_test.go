@@ -0,0 +1,174 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/volts-dev/orm/core"
+)
+
+// setupReplicaOrm 主库与副本为两个独立的库文件，副本中预置不同的数据以区分读取走向
+func setupReplicaOrm(t *testing.T, opts ...Option) *TOrm {
+	t.Helper()
+	dir := t.TempDir()
+	replica := &TDataSource{DbType: "sqlite", DbName: filepath.Join(dir, "replica.db")}
+	ro, err := New(WithDataSource(replica))
+	if err != nil {
+		t.Fatalf("New replica: %v", err)
+	}
+	if _, err = ro.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel replica: %v", err)
+	}
+	if _, err = ro.NewSession().Model("bulk_item").Create(map[string]any{"code": "R"}); err != nil {
+		t.Fatalf("Create on replica: %v", err)
+	}
+	ro.Close()
+
+	primary := &TDataSource{DbType: "sqlite", DbName: filepath.Join(dir, "primary.db")}
+	opts = append([]Option{WithDataSource(primary), WithReplicas(&TReplica{DataSource: &TDataSource{DbName: replica.DbName}})}, opts...)
+	o, err := New(opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err = o.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	return o
+}
+
+func readCodes(t *testing.T, session *TSession) string {
+	t.Helper()
+	ds, err := session.Model("bulk_item").Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return fmt.Sprint(ds.Keys("code"))
+}
+
+func TestSession_ReplicaRouting(t *testing.T) {
+	o := setupReplicaOrm(t)
+	defer o.Close()
+
+	writer := o.NewSession()
+	if _, err := writer.Model("bulk_item").Create(map[string]any{"code": "P"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 非事务读取走副本
+	if got := readCodes(t, o.NewSession()); got != "[R]" {
+		t.Fatalf("read should be served by the replica, got %s", got)
+	}
+	ds, err := o.NewSession().Query("SELECT code FROM bulk_item")
+	if err != nil || fmt.Sprint(ds.Keys("code")) != "[R]" {
+		t.Fatalf("raw select should be served by the replica: %v %v", ds, err)
+	}
+
+	// 写入后的读己之写窗口、强制主库、加锁读取与事务内读取走主库
+	if got := readCodes(t, writer); got != "[P]" {
+		t.Fatalf("session should read its own writes from the primary, got %s", got)
+	}
+	if got := readCodes(t, o.NewSession().Primary()); got != "[P]" {
+		t.Fatalf("Primary should read from the primary, got %s", got)
+	}
+	// SQLite 不支持锁定读取，直接检查路由
+	if node := o.NewSession().ForUpdate()._replica("SELECT code FROM bulk_item"); node != nil {
+		t.Fatalf("locking read should go to the primary")
+	}
+	tx := o.NewSession()
+	tx.Begin()
+	if got := readCodes(t, tx); got != "[P]" {
+		t.Fatalf("read in transaction should go to the primary, got %s", got)
+	}
+	tx.Commit()
+
+	// 副本不可用时回落主库，健康检查恢复后重新分发
+	pool := o.replicas
+	pool.markDown(pool.nodes[0])
+	if got := readCodes(t, o.NewSession()); got != "[P]" {
+		t.Fatalf("read should fall back to the primary when replicas are down, got %s", got)
+	}
+	pool.check()
+	if got := readCodes(t, o.NewSession()); got != "[R]" {
+		t.Fatalf("recovered replica should serve reads again, got %s", got)
+	}
+}
+
+func TestSession_ReplicaStickyWindow(t *testing.T) {
+	o := setupReplicaOrm(t, WithReplicaStickyWindow(50*time.Millisecond), WithReplicaCheckInterval(-1))
+	defer o.Close()
+
+	session := o.NewSession()
+	if _, err := session.Model("bulk_item").Create(map[string]any{"code": "P"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := readCodes(t, session); got != "[P]" {
+		t.Fatalf("read within the sticky window should go to the primary, got %s", got)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := readCodes(t, session); got != "[R]" {
+		t.Fatalf("read after the sticky window should go to the replica, got %s", got)
+	}
+}
+
+func TestReplicaPool_WeightedPick(t *testing.T) {
+	a := &tReplicaNode{name: "a", weight: 1, healthy: true}
+	b := &tReplicaNode{name: "b", weight: 2, healthy: true}
+	pool := &tReplicaPool{nodes: []*tReplicaNode{a, b}}
+
+	counts := map[string]int{}
+	for i := 0; i < 30; i++ {
+		counts[pool.pick().name]++
+	}
+	if counts["a"] != 10 || counts["b"] != 20 {
+		t.Fatalf("picks should follow the weights 1:2, got %v", counts)
+	}
+
+	b.healthy = false
+	for i := 0; i < 3; i++ {
+		if node := pool.pick(); node != a {
+			t.Fatalf("unhealthy replica should not be picked, got %s", node.name)
+		}
+	}
+	a.healthy = false
+	if node := pool.pick(); node != nil {
+		t.Fatalf("no replica should be picked when all are down, got %s", node.name)
+	}
+}
+
+// deadReplicaHook 模拟失联的副本：所有语句返回坏连接
+type deadReplicaHook struct{}
+
+func (deadReplicaHook) BeforeProcess(c *core.ContextHook) (context.Context, error) {
+	return nil, driver.ErrBadConn
+}
+
+func (deadReplicaHook) AfterProcess(c *core.ContextHook) error { return c.Err }
+
+func TestSession_ReplicaStreamingFallback(t *testing.T) {
+	o := setupReplicaOrm(t, WithReplicaCheckInterval(-1))
+	defer o.Close()
+	if _, err := o.NewSession().Model("bulk_item").Create(map[string]any{"code": "P"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 流式读取遇到失联副本时标记不可用并改走主库
+	node := o.replicas.nodes[0]
+	node.db.AddHook(deadReplicaHook{})
+	codes := make([]any, 0)
+	for rec, err := range o.NewSession().Model("bulk_item").ReadIter() {
+		if err != nil {
+			t.Fatalf("ReadIter: %v", err)
+		}
+		codes = append(codes, rec.GetByField("code"))
+	}
+	if fmt.Sprint(codes) != "[P]" {
+		t.Fatalf("streaming read should fall back to the primary, got %v", codes)
+	}
+	if node.healthy {
+		t.Fatal("failed replica should be marked down")
+	}
+}
@@ -0,0 +1,403 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/utils"
+)
+
+// TRepository 绑定到模型结构体 T 的类型化仓库：读写直接以 *T 进出，条件仍用 domain 表达，
+// 访问控制、记录规则、计算/关联/翻译字段与 TModel.Read 一致
+type TRepository[T any] struct {
+	orm     *TOrm
+	model   string
+	session *TSession
+}
+
+// NewRepository 创建 T 的仓库。modelName 可显式指定模型名，否则按注册时的结构体类型查找，
+// 找不到时按 ModelName 推导；模型须已注册(SyncModel)
+func NewRepository[T any](orm *TOrm, modelName ...string) (*TRepository[T], error) {
+	modelType := reflect.TypeOf((*T)(nil)).Elem()
+	if modelType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("orm: repository type %s is not a struct", modelType)
+	}
+
+	name := ""
+	if len(modelName) > 0 {
+		name = modelName[0]
+	}
+	if name == "" {
+		name = orm.osv._modelNameByType(modelType)
+	}
+	if name == "" {
+		name = ModelName(reflect.New(modelType).Interface())
+	}
+	if _, err := orm.GetModel(name); err != nil {
+		return nil, err
+	}
+
+	return &TRepository[T]{orm: orm, model: name}, nil
+}
+
+// Model 返回仓库绑定的模型名
+func (self *TRepository[T]) Model() string {
+	return self.model
+}
+
+// With 返回在 session 上执行的仓库副本，用于共享事务与 context(用户、语言等)
+func (self *TRepository[T]) With(session *TSession) *TRepository[T] {
+	return &TRepository[T]{orm: self.orm, model: self.model, session: session}
+}
+
+// Session 返回已指定模型的会话，可继续链式构建条件后交给 Find/First 执行：
+//
+//	Find[T](repo.Session().Domain(`[('name','ilike','a')]`).OrderBy("name").Limit(10))
+func (self *TRepository[T]) Session() *TSession {
+	if self.session != nil {
+		return self.session.Model(self.model)
+	}
+	return self.orm.Model(self.model)
+}
+
+// Find 读取满足 domain 的全部记录(不受 DefaultLimit 限制)，domain 为 nil 时读取全部
+func (self *TRepository[T]) Find(domain any, args ...any) ([]*T, error) {
+	session := self.Session().Limit(-1)
+	if domain != nil {
+		session.Domain(domain, args...)
+	}
+	return Find[T](session)
+}
+
+// First 读取满足 domain 的第一条记录，没有时返回 ErrNotExist
+func (self *TRepository[T]) First(domain any, args ...any) (*T, error) {
+	session := self.Session()
+	if domain != nil {
+		session.Domain(domain, args...)
+	}
+	return First[T](session)
+}
+
+// Get 按主键读取记录，不存在或不可读时返回 ErrNotExist
+func (self *TRepository[T]) Get(id any) (*T, error) {
+	return First[T](self.Session().Ids(id))
+}
+
+// Create 新建记录并把数据库中的结果(主键、默认值、计算字段)回填到 record。
+// 与结构体写入一致，零值字段视为未提供
+func (self *TRepository[T]) Create(record *T) error {
+	ids, err := self.Session().Create(record)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrNotExist
+	}
+
+	created, err := self.Get(ids[0])
+	if err != nil {
+		return err
+	}
+	*record = *created
+	return nil
+}
+
+// Update 按 record 的主键更新记录，零值字段视为未提供、不会写回
+func (self *TRepository[T]) Update(record *T) (int64, error) {
+	model, err := self.orm.GetModel(self.model)
+	if err != nil {
+		return 0, err
+	}
+
+	id := structFieldValue(reflect.ValueOf(record).Elem(), self.orm.config.FieldIdentifier, model.IdField())
+	if !id.IsValid() || id.IsZero() {
+		return 0, fmt.Errorf("orm: update %s requires a non-zero %s", self.model, model.IdField())
+	}
+	return self.Session().Ids(id.Interface()).Write(record)
+}
+
+// Find 按会话已构建的条件(Domain/Ids/Select/OrderBy/Limit 等)读取记录并扫描为 T。
+// 存储列经 core.Rows.ScanStructByName 直接扫入结构体；非存储的计算/关联字段与
+// one2many/many2many 字段再按 id 批量读取后回填，x2many 回填为关联记录 id
+func Find[T any](session *TSession) ([]*T, error) {
+	model := session.Statement.Model
+	if model == nil {
+		return nil, ErrTableNotFound
+	}
+	session.Op = OpRead
+	if _, err := model.BeforeSession(session); err != nil {
+		return nil, err
+	}
+	defer func() {
+		model.AfterSession(session)
+		session._resetStatement()
+	}()
+
+	if session.IsAutoClose {
+		defer session.Close()
+	}
+
+	if session.IsDeprecated {
+		return nil, ErrInvalidSession
+	}
+	// 与 TModel.Read 相同的访问控制：模型 ACL 拒绝读取时报错，无权访问的字段静默剔除
+	if err := session.CheckAccessRights(OpRead); err != nil {
+		return nil, err
+	}
+	session._omitDeniedFields()
+
+	return _findStructs[T](session)
+}
+
+// First 同 Find 但只取一条，没有记录时返回 ErrNotExist
+func First[T any](session *TSession) (*T, error) {
+	session.Limit(1)
+	records, err := Find[T](session)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrNotExist
+	}
+	return records[0], nil
+}
+
+func _findStructs[T any](session *TSession) ([]*T, error) {
+	model := session.Statement.Model
+	modelType := reflect.TypeOf((*T)(nil)).Elem()
+	if modelType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("orm: find type %s is not a struct", modelType)
+	}
+
+	// 结构体上能对应到模型字段的字段：模型字段名 -> 结构体字段下标。
+	// 主键不受 Select/Omit 影响，总是读取并扫描，用于回填非存储字段
+	idField := model.IdField()
+	indexes := make(map[string]int)
+	for _, info := range structFieldInfos(modelType, session.orm.config.FieldIdentifier) {
+		if info.skip || info.isExtends || model.GetFieldByName(info.ormName) == nil {
+			continue
+		}
+		if info.ormName != idField {
+			if session.Statement.IsOmit(info.ormName) {
+				continue
+			}
+			if len(session.Statement.Fields) > 0 && utils.IndexOf(info.ormName, session.Statement.Fields...) == -1 {
+				continue
+			}
+		}
+		indexes[info.ormName] = info.reflectIdx
+	}
+
+	// 与 _read 相同的字段分类
+	storeFields := []string{idField}
+	relateFields := make([]string, 0, 8)
+	computedFields := make([]string, 0, 8)
+	for name := range indexes {
+		field := model.GetFieldByName(name)
+		switch {
+		case field.IsRelated():
+			relateFields = append(relateFields, name)
+		case !field.Store() && field.HasGetter():
+			computedFields = append(computedFields, name)
+		case name != idField:
+			storeFields = append(storeFields, name)
+		}
+	}
+
+	sql, params, selected, err := session._readSql(storeFields, relateFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// 列名经 Mapper 对应到结构体字段名；对不上的(如 name() 改名的字段)与非存储字段一起回填
+	mapper := session.db.Mapper
+	scanned := make(map[string]bool, len(selected))
+	for _, name := range selected {
+		if idx, ok := indexes[name]; ok && modelType.Field(idx).Name == mapper.Table2Obj(name) {
+			scanned[name] = true
+		}
+	}
+
+	rows, err := session._queryRows(sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*T, 0)
+	for rows.Next() {
+		record := new(T)
+		if err = rows.ScanStructByName(record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	fills := make([]string, 0, len(computedFields)+len(relateFields))
+	for name := range indexes {
+		if !scanned[name] {
+			fills = append(fills, name)
+		}
+	}
+	if len(fills) == 0 || len(records) == 0 {
+		return records, nil
+	}
+	if !scanned[idField] {
+		return nil, fmt.Errorf("orm: find %s: struct %s has no field scanned from %s to fill %v", model.String(), modelType, idField, fills)
+	}
+	return records, _fillStructs(session, model, records, indexes, fills)
+}
+
+// _fillStructs 按 id 分批(DefaultBatchSize)读取 fields 并回填到 records，x2many 字段取关联记录 id
+func _fillStructs[T any](session *TSession, model IModel, records []*T, indexes map[string]int, fields []string) error {
+	idField := model.IdField()
+	byId := make(map[string]reflect.Value, len(records))
+	ids := make([]any, 0, len(records))
+	for _, record := range records {
+		value := reflect.ValueOf(record).Elem()
+		id := value.Field(indexes[idField]).Interface()
+		byId[utils.ToString(id)] = value
+		ids = append(ids, id)
+	}
+
+	// 回填沿用本会话的用户、事务与权限，不以 sudo 绕过规则
+	sub := session.Clone()
+	sub.IsAutoCommit = session.IsAutoCommit
+	sub.IsAutoClose = false
+	for start := 0; start < len(ids); start += DefaultBatchSize {
+		if err := _fillBatch(sub, model, byId, ids[start:min(start+DefaultBatchSize, len(ids))], indexes, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// _fillBatch 读取并回填一批记录
+func _fillBatch(sub *TSession, model IModel, byId map[string]reflect.Value, ids []any, indexes map[string]int, fields []string) error {
+	idField := model.IdField()
+	ds, err := sub.Model(model.String()).Ids(ids...).Select(append([]string{idField}, fields...)...).Limit(-1).Read()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range fields {
+		field := model.GetFieldByName(name)
+		if field.TypeName() != TYPE_O2M && field.TypeName() != TYPE_M2M {
+			continue
+		}
+		ctx := &TFieldContext{
+			Session: sub,
+			Model:   model,
+			Field:   field,
+			Dataset: ds,
+		}
+		if err = field.OnRead(ctx); err != nil {
+			return err
+		}
+	}
+
+	return ds.Range(func(pos int, rec *dataset.TRecordSet) error {
+		value, ok := byId[utils.ToString(rec.GetByField(idField))]
+		if !ok {
+			return nil
+		}
+		for _, name := range fields {
+			val := rec.GetByField(name)
+			if field := model.GetFieldByName(name); field.TypeName() == TYPE_O2M || field.TypeName() == TYPE_M2M {
+				if val, err = relatedIds(sub.orm, field, val); err != nil {
+					return err
+				}
+			}
+			if err := setStructValue(value.Field(indexes[name]), val); err != nil {
+				return fmt.Errorf("orm: fill %s.%s: %w", model.String(), name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// relatedIds 把 x2many 字段读出的关联记录(id 或记录 map)统一为 id 列表
+func relatedIds(orm *TOrm, field IField, value any) ([]any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return v, nil
+	case []map[string]any:
+		relModel, err := orm.GetModel(field.RelatedModelName())
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]any, 0, len(v))
+		for _, rec := range v {
+			ids = append(ids, rec[relModel.IdField()])
+		}
+		return ids, nil
+	}
+	return nil, fmt.Errorf("unsupported %s value %T", field.TypeName(), value)
+}
+
+// structFieldValue 返回结构体上对应模型字段 name 的字段值，没有时返回无效 Value
+func structFieldValue(value reflect.Value, fieldIdentifier, name string) reflect.Value {
+	for _, info := range structFieldInfos(value.Type(), fieldIdentifier) {
+		if !info.skip && info.ormName == name {
+			return value.Field(info.reflectIdx)
+		}
+	}
+	return reflect.Value{}
+}
+
+// setStructValue 把数据集中的值按目标字段类型写入结构体字段
+func setStructValue(dst reflect.Value, value any) error {
+	if value == nil {
+		dst.SetZero()
+		return nil
+	}
+
+	src := reflect.ValueOf(value)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(utils.ToString(value))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.SetInt(utils.ToInt64(value))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		dst.SetUint(uint64(utils.ToInt64(value)))
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(utils.ToFloat64(value))
+	case reflect.Bool:
+		dst.SetBool(utils.ToBool(value))
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := setStructValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		dst.Set(elem)
+	case reflect.Slice:
+		if src.Kind() != reflect.Slice {
+			return fmt.Errorf("cannot assign %T to %s", value, dst.Type())
+		}
+		out := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := setStructValue(out.Index(i), src.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+	default:
+		if !src.Type().ConvertibleTo(dst.Type()) {
+			return fmt.Errorf("cannot assign %T to %s", value, dst.Type())
+		}
+		dst.Set(src.Convert(dst.Type()))
+	}
+	return nil
+}
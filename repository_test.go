@@ -0,0 +1,249 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	ormerr "github.com/volts-dev/orm/errors"
+)
+
+type (
+	RepoBook struct {
+		TModel   `table:"name('repo_book')"`
+		Id       int64  `field:"pk autoincr"`
+		Title    string `field:"varchar() size(64)"`
+		AuthorId int64  `field:"many2one(repo_author)"`
+	}
+
+	RepoAuthor struct {
+		TModel      `table:"name('repo_author')"`
+		Id          int64   `field:"pk autoincr"`
+		Name        string  `field:"varchar() size(64)"`
+		Age         int     `field:"int()"`
+		Nickname    *string `field:"varchar() size(32)"`
+		CountryId   int64   `field:"many2one(rl_country)"`
+		CountryName string  `field:"varchar() related('country_id.name')"`
+		BookIds     []int64 `field:"one2many(repo_book,author_id)"`
+	}
+)
+
+func TestRepository_TypedFindCreateUpdate(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(RelatedCountry), new(RepoAuthor), new(RepoBook)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	repo, err := NewRepository[RepoAuthor](o)
+	if err != nil {
+		t.Fatalf("NewRepository: %v", err)
+	}
+	if repo.Model() != "repo.author" {
+		t.Fatalf("model should be resolved from the registered struct, got %q", repo.Model())
+	}
+
+	countries, err := o.NewSession().Model("rl_country").Create(map[string]any{"name": "France"})
+	if err != nil {
+		t.Fatalf("Create country: %v", err)
+	}
+
+	// 新建：主键与数据库中的值回填到结构体
+	hugo := &RepoAuthor{Name: "Hugo", Age: 83, CountryId: countries[0].(int64)}
+	if err = repo.Create(hugo); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if hugo.Id == 0 || hugo.CountryName != "France" {
+		t.Fatalf("Create should fill back the id and related fields: %+v", hugo)
+	}
+	zola := &RepoAuthor{Name: "Zola", Age: 62}
+	if err = repo.Create(zola); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err = o.NewSession().Model("repo_book").Create(
+		map[string]any{"title": "Les Misérables", "author_id": hugo.Id},
+		map[string]any{"title": "Notre-Dame", "author_id": hugo.Id},
+	); err != nil {
+		t.Fatalf("Create books: %v", err)
+	}
+
+	// 读取：存储列直接扫描，NULL 得零值；关联与 x2many 字段回填
+	got, err := repo.Get(hugo.Id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Name != "Hugo" || got.Age != 83 || got.Nickname != nil || got.CountryName != "France" {
+		t.Fatalf("Get returned %+v", got)
+	}
+	if len(got.BookIds) != 2 {
+		t.Fatalf("one2many field should be filled with ids, got %v", got.BookIds)
+	}
+	if other, _ := repo.Get(zola.Id); other.CountryId != 0 || other.CountryName != "" || len(other.BookIds) != 0 {
+		t.Fatalf("empty relations should read as zero values, got %+v", other)
+	}
+
+	// domain 照常可用，包括关联字段条件
+	found, err := repo.Find(`[('country_name', '=', 'France')]`)
+	if err != nil || len(found) != 1 || found[0].Id != hugo.Id {
+		t.Fatalf("Find by related field: %v %v", found, err)
+	}
+	all, err := Find[RepoAuthor](repo.Session().OrderBy("age"))
+	if err != nil || len(all) != 2 || all[0].Name != "Zola" {
+		t.Fatalf("Find with ordered session: %v %v", all, err)
+	}
+	if _, err = repo.First(`[('name', '=', 'Balzac')]`); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("First without match should return ErrNotExist, got %v", err)
+	}
+
+	// 更新：零值字段不写回
+	nick := "Toto"
+	if _, err = repo.Update(&RepoAuthor{Id: zola.Id, Age: 63, Nickname: &nick}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ = repo.Get(zola.Id); got.Age != 63 || got.Name != "Zola" || got.Nickname == nil || *got.Nickname != "Toto" {
+		t.Fatalf("Update should write non-zero fields only, got %+v", got)
+	}
+	if _, err = repo.Update(&RepoAuthor{Name: "Nobody"}); err == nil {
+		t.Fatalf("Update without id should fail")
+	}
+
+	// 绑定会话：在调用方事务内读写
+	session := o.NewSession()
+	if err = session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if err = repo.With(session).Create(&RepoAuthor{Name: "Balzac"}); err != nil {
+		t.Fatalf("Create in tx: %v", err)
+	}
+	if err = session.Rollback(nil); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if _, err = repo.First(`[('name', '=', 'Balzac')]`); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("rolled back record should not be visible, got %v", err)
+	}
+}
+
+// RepoAuthorView 不含主键的只读视图结构
+type RepoAuthorView struct {
+	Name        string
+	CountryName string
+}
+
+func TestRepository_FindBeyondDefaultLimit(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(RelatedCountry), new(RepoAuthor), new(RepoBook)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	countries, err := o.NewSession().Model("rl_country").Create(map[string]any{"name": "France"})
+	if err != nil {
+		t.Fatalf("Create country: %v", err)
+	}
+
+	const n = DefaultLimit + 100
+	src := make([]any, n)
+	for i := range src {
+		src[i] = map[string]any{"name": fmt.Sprintf("A%03d", i), "country_id": countries[0]}
+	}
+	if _, err = o.NewSession().Model("repo_author").Create(src...); err != nil {
+		t.Fatalf("Create authors: %v", err)
+	}
+
+	repo, err := NewRepository[RepoAuthor](o)
+	if err != nil {
+		t.Fatalf("NewRepository: %v", err)
+	}
+	all, err := repo.Find(nil)
+	if err != nil || len(all) != n {
+		t.Fatalf("Find should read all %d records, got %d: %v", n, len(all), err)
+	}
+	for _, author := range all {
+		if author.CountryName != "France" {
+			t.Fatalf("related field of %s should be filled, got %+v", author.Name, author)
+		}
+	}
+
+	// Select 未含主键时仍读取主键以回填非存储字段
+	selected, err := Find[RepoAuthor](repo.Session().Select("name", "country_name").Limit(-1))
+	if err != nil || len(selected) != n {
+		t.Fatalf("Find with Select: %d %v", len(selected), err)
+	}
+	if last := selected[n-1]; last.Id == 0 || last.CountryName != "France" {
+		t.Fatalf("Select without id should still fill related fields, got %+v", last)
+	}
+
+	// 结构体没有主键字段时无法回填，报错而非静默跳过
+	if _, err = Find[RepoAuthorView](repo.Session()); err == nil {
+		t.Fatalf("Find into a struct without id should fail when non-stored fields need filling")
+	}
+}
+
+func TestRepository_AccessControl(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(AccessSalaryModel), new(RelatedCountry), new(RepoAuthor), new(RepoBook)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	if _, err := o.NewSession().Model("access.salary").Create(map[string]any{"name": "alice", "salary": 100}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	hr := WithUser(context.Background(), 1, "hr")
+	staff := WithUser(context.Background(), 2, "staff")
+	repo, err := NewRepository[AccessSalaryModel](o)
+	if err != nil {
+		t.Fatalf("NewRepository: %v", err)
+	}
+
+	// 受限字段与 TModel.Read 一样静默剔除
+	found, err := repo.With(o.NewSession().WithContext(staff)).Find(nil)
+	if err != nil || len(found) != 1 || found[0].Name != "alice" || found[0].Salary != 0 {
+		t.Fatalf("staff should read alice without salary, got %+v %v", found, err)
+	}
+	if found, err = repo.With(o.NewSession().WithContext(hr)).Find(nil); err != nil || len(found) != 1 || found[0].Salary != 100 {
+		t.Fatalf("hr should read the salary, got %+v %v", found, err)
+	}
+
+	// 模型 ACL 拒绝读取
+	if err = o.AddModelAccess(&TModelAccess{Name: "hr_read", Model: "access.salary", Group: "hr", PermRead: true}); err != nil {
+		t.Fatalf("AddModelAccess: %v", err)
+	}
+	if _, err = repo.With(o.NewSession().WithContext(staff)).Find(nil); !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("staff Find should be denied by the model ACL, got %v", err)
+	}
+
+	// 非存储字段以调用者的会话回填，而非 sudo
+	countries, err := o.NewSession().Model("rl_country").Create(map[string]any{"name": "France"})
+	if err != nil {
+		t.Fatalf("Create country: %v", err)
+	}
+	authors, err := o.NewSession().Model("repo_author").Create(map[string]any{"name": "Hugo", "country_id": countries[0]})
+	if err != nil {
+		t.Fatalf("Create author: %v", err)
+	}
+	model, err := o.GetModel("repo_author")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	field := model.GetFieldByName("country_name").Base()
+	getter := field.getterFunc
+	defer func() { field.getterFunc = getter }()
+	var sudo []bool
+	field.getterFunc = func(ctx *TFieldContext) error {
+		sudo = append(sudo, ctx.Session.isSudo())
+		return getter(ctx)
+	}
+	authorRepo, err := NewRepository[RepoAuthor](o)
+	if err != nil {
+		t.Fatalf("NewRepository: %v", err)
+	}
+	author, err := authorRepo.With(o.NewSession().WithContext(staff)).Get(authors[0])
+	if err != nil || author.CountryName != "France" {
+		t.Fatalf("staff should read the related field, got %v", err)
+	}
+	if len(sudo) == 0 || sudo[0] {
+		t.Fatalf("non-stored fields should be filled with the caller's session, sudo calls %v", sudo)
+	}
+}
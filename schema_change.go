@@ -0,0 +1,682 @@
+package orm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/volts-dev/utils"
+)
+
+type (
+	// SchemaChangeKind 标记一条结构变更的类别
+	SchemaChangeKind uint8
+
+	// TSchemaChange 描述结构同步中的一条 DDL 变更。
+	// SyncModel 的改表分支与迁移生成(TMigrator.Generate)都从同一份变更列表出发，
+	// 保证"自动同步会执行的"与"迁移文件里写下的"是同一组语句。
+	TSchemaChange struct {
+		Kind   SchemaChangeKind
+		Model  string
+		Table  string
+		Column string // 列变更时的字段名
+		Index  string // 索引变更时的索引名
+		Up     string // 正向语句
+		Down   string // 撤销语句；随表一并删除或无法撤销时为空
+		// Unsafe 标记可能丢数据或在现有数据上失败的变更(收窄类型/长度、对含 NULL 的列
+		// 加 NOT NULL、改列名)，Reason 说明原因。SyncModel 默认拒绝执行此类变更。
+		Unsafe bool
+		Reason string
+		// tolerant 为 true 时执行失败只记日志不中断——沿用 _alterTable 历来对
+		// 改类型/改长度/补列的容错口径(失败不阻断启动)。
+		tolerant bool
+	}
+
+	// TSyncPlan 是 SyncModel 的执行计划：按执行顺序排列、尚未与库一致的全部 DDL。
+	// 由 PlanSyncModel 生成，只读库不写库，供 DBA 审阅后再执行或转为迁移。
+	TSyncPlan struct {
+		Dialect string // 生成语句所针对的数据库类型
+		Schema  string
+		Changes []*TSchemaChange
+	}
+)
+
+const (
+	ChangeCreateTable SchemaChangeKind = iota + 1
+	ChangeAddColumn
+	ChangeAlterColumn
+	ChangeDropIndex
+	ChangeAddIndex
+	ChangeRenameColumn
+)
+
+// intTypeRank 整数类型按宽度排序，用于识别 int->smallint 这类收窄
+var intTypeRank = map[string]int{
+	TinyInt:   1,
+	SmallInt:  2,
+	MediumInt: 3,
+	Int:       4,
+	Integer:   4,
+	BigInt:    5,
+}
+
+func (self SchemaChangeKind) String() string {
+	switch self {
+	case ChangeCreateTable:
+		return "create_table"
+	case ChangeAddColumn:
+		return "add_column"
+	case ChangeAlterColumn:
+		return "alter_column"
+	case ChangeDropIndex:
+		return "drop_index"
+	case ChangeAddIndex:
+		return "add_index"
+	case ChangeRenameColumn:
+		return "rename_column"
+	}
+	return "unknown"
+}
+
+// Models 返回计划涉及的模型名(按首次出现顺序)
+func (self *TSyncPlan) Models() []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, change := range self.Changes {
+		if !seen[change.Model] {
+			seen[change.Model] = true
+			names = append(names, change.Model)
+		}
+	}
+	return names
+}
+
+// ByModel 返回某个模型的变更(保持执行顺序)
+func (self *TSyncPlan) ByModel(model string) []*TSchemaChange {
+	changes := make([]*TSchemaChange, 0)
+	for _, change := range self.Changes {
+		if change.Model == model {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// SQL 返回全部待执行语句
+func (self *TSyncPlan) SQL() []string {
+	sqls := make([]string, 0, len(self.Changes))
+	for _, change := range self.Changes {
+		if change.Up != "" {
+			sqls = append(sqls, change.Up)
+		}
+	}
+	return sqls
+}
+
+// IsEmpty reports whether the database already matches the models
+func (self *TSyncPlan) IsEmpty() bool {
+	return len(self.SQL()) == 0
+}
+
+// String 输出可直接审阅的 SQL 脚本，按模型分段并注明数据库类型
+func (self *TSyncPlan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- dialect: %s\n", self.Dialect)
+	if self.Schema != "" {
+		fmt.Fprintf(&b, "-- schema: %s\n", self.Schema)
+	}
+	for _, model := range self.Models() {
+		fmt.Fprintf(&b, "\n-- model: %s\n", model)
+		for _, change := range self.ByModel(model) {
+			if change.Up != "" {
+				if change.Unsafe {
+					fmt.Fprintf(&b, "-- UNSAFE: %s\n", change.Reason)
+				}
+				b.WriteString(strings.TrimSuffix(strings.TrimSpace(change.Up), ";"))
+				b.WriteString(";\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// _scratch 共享连接与方言、持有注册表副本的 orm，供计划模式映射注册模型
+func (self *TOrm) _scratch() *TOrm {
+	orm := &TOrm{
+		context:   self.context,
+		config:    self.config,
+		dialect:   self.dialect,
+		db:        self.db,
+		nameIndex: make(map[string]*TModel),
+		connected: self.connected,
+		Schema:    self.Schema,
+		Cacher:    self.Cacher,
+	}
+	orm.osv = self.osv._scratch(orm)
+	return orm
+}
+
+// _planModels 按 SyncModel 的口径映射并注册模型，再对比数据库现状，返回全部结构变更
+// 但不执行。表不存在→建表/唯一/索引；表已存在→_diffTable。不在 models 里的库表不动。
+// 映射注册都在注册表副本上进行，现有模型与计算触发表保持不变。
+func (self *TSession) _planModels(region string, models ...IModel) ([]*TSchemaChange, error) {
+	models = unique(models)
+
+	exitsModels, err := self.orm.DBMetas(self)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按表名匹配已存在的表，理由见 SyncModel
+	existsByTable := make(map[string]IModel, len(exitsModels))
+	for _, m := range exitsModels {
+		existsByTable[m.Table()] = m
+	}
+
+	plan := NewSession(self.orm._scratch())
+	plan.Schema = self.Schema
+	defer plan.Close()
+
+	changes := make([]*TSchemaChange, 0)
+	for _, mod := range models {
+		model, err := plan.orm._mapping(mod)
+		if err != nil {
+			return nil, err
+		}
+
+		if model == nil {
+			continue
+		}
+
+		// 只注册元数据：RegisterModel 会顺带执行 m2m 关联表 DDL，计划阶段不能碰库
+		if err = plan.orm.osv._registerModel(region, model, false); err != nil {
+			return nil, err
+		}
+
+		plan.Model(model.String(), WithModuleName(region))
+		current := plan.Statement.Model
+		var lst []*TSchemaChange
+		if exitsModel := existsByTable[model.Table()]; exitsModel == nil {
+			lst, err = plan._planCreateTable(current)
+		} else {
+			lst, err = plan._diffTable(model, exitsModel.(*TModel))
+		}
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, lst...)
+
+		// 审计表：库中尚不存在且有模型开启审计时计入一次
+		if current.Obj().Audit && existsByTable[AuditTable] == nil {
+			existsByTable[AuditTable] = current
+			changes = append(changes, &TSchemaChange{
+				Kind:  ChangeCreateTable,
+				Model: current.String(),
+				Table: AuditTable,
+				Up:    auditTableSql(plan.orm.dialect.Quoter(), plan.Schema),
+				Down:  plan.orm.dialect.DropTableSql(plan.Schema, AuditTable),
+			})
+		}
+
+		// 翻译附表：非 Postgres 库中尚不存在且有模型含翻译字段时计入一次
+		if !plan.orm._translateJsonb() && existsByTable[TranslationTable] == nil && hasTranslatable(current) {
+			existsByTable[TranslationTable] = current
+			changes = append(changes, &TSchemaChange{
+				Kind:  ChangeCreateTable,
+				Model: current.String(),
+				Table: TranslationTable,
+				Up:    translationTableSql(plan.orm.dialect.Quoter(), plan.Schema),
+				Down:  plan.orm.dialect.DropTableSql(plan.Schema, TranslationTable),
+			})
+		}
+
+		// m2m 关联表：库中尚不存在的才计入(同一关联表只计一次)
+		for _, field := range current.GetFields() {
+			m2m, ok := field.(*TMany2ManyField)
+			if !ok {
+				continue
+			}
+
+			table, stmts := m2m.relationTableSql(plan.orm, current, plan.Schema)
+			if existsByTable[table] != nil {
+				continue
+			}
+			existsByTable[table] = current
+
+			// 索引/注释同属建关联表，随表删除，只有首条带 Down
+			for i, sql := range stmts {
+				change := &TSchemaChange{Kind: ChangeCreateTable, Model: m2m.JoinModelName(), Table: table, Up: sql}
+				if i == 0 {
+					change.Down = plan.orm.dialect.DropTableSql(plan.Schema, table)
+				}
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// _planCreateTable 生成新建表所需的 CREATE TABLE 及唯一/普通索引语句，
+// 与 SyncModel 新建表分支(createTableImpl/CreateUniques/createIndexesImpl)同源。
+// 索引随表删除，故只有建表变更带 Down。
+func (self *TSession) _planCreateTable(model IModel) ([]*TSchemaChange, error) {
+	defer self.Statement.Init()
+
+	modelName := model.String()
+	tableName := model.Table()
+	self.Statement.Model = model
+
+	changes := []*TSchemaChange{{
+		Kind:  ChangeCreateTable,
+		Model: modelName,
+		Table: tableName,
+		Up:    self.Statement.generate_create_table(),
+		Down:  self.orm.dialect.DropTableSql(self.Schema, tableName),
+	}}
+
+	for _, sql := range self.Statement.generate_unique() {
+		changes = append(changes, &TSchemaChange{Kind: ChangeAddIndex, Model: modelName, Table: tableName, Up: sql})
+	}
+
+	sqls, err := self.Statement.generate_index()
+	if err != nil {
+		return nil, err
+	}
+	for _, sql := range sqls {
+		changes = append(changes, &TSchemaChange{Kind: ChangeAddIndex, Model: modelName, Table: tableName, Up: sql})
+	}
+
+	return changes, nil
+}
+
+/* #
+* _diffTable 对比映射后的新表结构与数据库现状，按执行顺序返回结构变更。
+* @newModel:Model映射后的新表结构
+* @oldModel:当前数据库的表结构
+ */
+func (self *TSession) _diffTable(newModel, oldModel *TModel) ([]*TSchemaChange, error) {
+	orm := self.orm
+	modelName := newModel.String()
+	tableName := newModel.table
+	changes := make([]*TSchemaChange, 0)
+
+	// alter 记录一条改列变更
+	alter := func(field IField, up, down string, tolerant bool) *TSchemaChange {
+		if up == "" {
+			return nil
+		}
+		change := &TSchemaChange{
+			Kind:     ChangeAlterColumn,
+			Model:    modelName,
+			Table:    tableName,
+			Column:   field.Name(),
+			Up:       up,
+			Down:     down,
+			tolerant: tolerant,
+		}
+		changes = append(changes, change)
+		return change
+	}
+
+	// modify 以 ModifyColumnSql 整列重定义，需检查是否收窄
+	modify := func(field, cur_field IField, tolerant bool) *TSchemaChange {
+		change := alter(field,
+			orm.dialect.ModifyColumnSql(self.Schema, tableName, field),
+			orm.dialect.ModifyColumnSql(self.Schema, tableName, cur_field), tolerant)
+		if change != nil {
+			if reason := self._narrowingReason(field, cur_field); reason != "" {
+				change.Unsafe = true
+				change.Reason = reason
+			}
+		}
+		return change
+	}
+
+	{ // 字段修改
+		var cur_field IField
+		var fieldName string
+		for _, field := range newModel.GetFields() {
+			fieldName = field.Name()
+			cur_field = oldModel.GetFieldByName(fieldName)
+
+			if cur_field != nil {
+				/* 忽略关系 */
+				if field.IsRelated() {
+					continue
+				}
+
+				expectedType := orm.dialect.GetSqlType(field)
+				curType := orm.dialect.GetSqlType(cur_field)
+				typeChanges := len(changes) // 改类型已整列重定义(含长度)时不再另发改长度
+
+				// Postgres 上已有的字符串列改为翻译字段：转为 JSONB 并把原值作为默认语言的取值，
+				// 不走下面的改类型/改长度
+				if field.Translate() && orm._translateJsonb() && sqlTypeName(curType) != Jsonb {
+					alter(field, orm._translateColumnSql(self.Schema, tableName, field), orm._translateColumnSql(self.Schema, tableName, cur_field), false)
+				} else if expectedType != curType {
+					//TODO 修改数据类型
+					// 如果是修改字符串到
+					if expectedType == Text && strings.HasPrefix(curType, Varchar) ||
+						expectedType == Varchar && strings.HasPrefix(curType, Char) {
+						log.Warnf("Table <%s> column <%s> change type from %s to %s", tableName, fieldName, curType, expectedType)
+						modify(field, cur_field, true)
+
+					} else if strings.HasPrefix(curType, Char) && strings.HasPrefix(expectedType, Varchar) {
+						// 如果是同是字符串 则检查长度变化 for mysql
+						if cur_field.Size() != field.Size() {
+							log.Warnf("Table <%s> column <%s> change type from varchar(%d) to varchar(%d)", tableName, fieldName, cur_field.Size(), field.Size())
+							modify(field, cur_field, true)
+						}
+					} else if from, ok := intTypeRank[sqlTypeName(curType)]; ok && intTypeRank[sqlTypeName(expectedType)] > 0 && intTypeRank[sqlTypeName(expectedType)] != from {
+						// 整数宽度变化：放宽照常执行，收窄由 _narrowingReason 标记为 Unsafe
+						log.Warnf("Table <%s> column <%s> change type from %s to %s", tableName, fieldName, curType, expectedType)
+						modify(field, cur_field, true)
+
+						//其他
+					} else {
+						if !strings.HasPrefix(curType, expectedType) || curType[len(expectedType)] != '(' {
+							log.Warnf("Table <%s> column <%s> db type is <%s>, struct type is %s", tableName, fieldName, curType, expectedType)
+						}
+					}
+				}
+
+				// 如果是同是字符串 则检查长度变化 for mysql
+				if len(changes) == typeChanges && cur_field.Size() != field.Size() && sqlTypeName(expectedType) != Jsonb {
+					log.Warnf("Table <%s> column <%s> change size from %s(%d) to %s(%d)",
+						tableName, fieldName, cur_field.SQLType().Name, cur_field.Size(), field.SQLType().Name, field.Size())
+					modify(field, cur_field, true)
+				}
+
+				// 两侧 Default() 的动态类型不一致(struct 侧数值型是 int64,DB 内省侧
+				// 因转换代码被注释掉而一直是 string),直接用 any 的 != 比较对数值型
+				// 字段恒为 true。统一转成字符串再比较,和 DropColumnDefaultSql 生成
+				// SQL 时的 utils.ToString 口径保持一致。
+				if utils.ToString(field.Default()) != utils.ToString(cur_field.Default()) {
+					if sql := orm.dialect.DropColumnDefaultSql(self.Schema, tableName, field); sql != "" {
+						alter(field, sql, orm.dialect.DropColumnDefaultSql(self.Schema, tableName, cur_field), false)
+					} else {
+						modify(field, cur_field, false)
+					}
+				}
+
+				if field.Required() != cur_field.Required() {
+					var change *TSchemaChange
+					if sql := orm.dialect.DropColumnNotNullSql(self.Schema, tableName, field); sql != "" {
+						change = alter(field, sql, orm.dialect.DropColumnNotNullSql(self.Schema, tableName, cur_field), false)
+					} else {
+						// Fallback for dialects that use full column modification.
+						change = modify(field, cur_field, false)
+					}
+
+					// 加 NOT NULL：已有 NULL 行时语句会失败(或被 mysql 静默改成零值)
+					if change != nil && field.Required() && !change.Unsafe {
+						cnt, err := self._countNull(tableName, fieldName)
+						if err != nil {
+							return nil, err
+						}
+						if cnt > 0 {
+							change.Unsafe = true
+							change.Reason = fmt.Sprintf("set NOT NULL on a column with %d NULL rows", cnt)
+						}
+					}
+				}
+
+				// 字段以 oldname 声明了改名且库中仍是旧列名：改列名而不是补一个空列
+			} else if oldName := field.Base().oldName; oldName != "" && field.Store() && oldModel.GetFieldByName(oldName) != nil {
+				changes = append(changes, &TSchemaChange{
+					Kind:   ChangeRenameColumn,
+					Model:  modelName,
+					Table:  tableName,
+					Column: fieldName,
+					Up:     orm.dialect.RenameColumnSql(self.Schema, tableName, oldName, fieldName),
+					Down:   orm.dialect.RenameColumnSql(self.Schema, tableName, fieldName, oldName),
+					Unsafe: true,
+					Reason: fmt.Sprintf("rename column %s to %s", oldName, fieldName),
+				})
+
+				// 如果现在表无该字段则添加
+			} else if field.Store() && !field.IsInherited() {
+				/* 这里必须过滤掉 NOTE [SyncModel] 里提及的特殊字段 */
+				changes = append(changes, &TSchemaChange{
+					Kind:     ChangeAddColumn,
+					Model:    modelName,
+					Table:    tableName,
+					Column:   fieldName,
+					Up:       orm.dialect.GenAddColumnSQL(self.Schema, tableName, field),
+					Down:     orm.dialect.DropColumnSql(self.Schema, tableName, field),
+					tolerant: true,
+				})
+			}
+		}
+	}
+
+	{ // 表修改
+		// TODO 主键是否可以修改
+		{
+			newKeys := newModel.GetPrimaryKeys()
+			oldKeys := oldModel.GetPrimaryKeys()
+
+			keysChanged := false
+			if len(newKeys) != len(oldKeys) {
+				keysChanged = true
+			} else {
+				for i, k := range newKeys {
+					if k != oldKeys[i] {
+						keysChanged = true
+						break
+					}
+				}
+			}
+
+			if keysChanged {
+				quoter := orm.dialect.Quoter()
+				var dropSql string
+				var addSql string
+				dbType := strings.ToLower(orm.dialect.DBType())
+
+				if len(oldKeys) > 0 {
+					if dbType == "postgres" || dbType == "postgresql" {
+						dropSql = fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s_pkey;",
+							quoter.Quote(tableName), tableName)
+					} else if dbType == "mysql" {
+						dropSql = fmt.Sprintf("ALTER TABLE %s DROP PRIMARY KEY;", quoter.Quote(tableName))
+					}
+				}
+
+				if len(newKeys) > 0 {
+					if dbType == "postgres" || dbType == "postgresql" || dbType == "mysql" {
+						addSql = fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);",
+							quoter.Quote(tableName), quoter.Join(newKeys, ","))
+					}
+				}
+
+				log.Warnf("Table <%s> primary key constraint mismatch between struct (%v) and database (%v)!", tableName, newKeys, oldKeys)
+				if dropSql != "" || addSql != "" {
+					log.Warnf("To synchronize the primary key manually, please run:\n  %s\n  %s", dropSql, addSql)
+				}
+			}
+		}
+
+		// 检查更新索引 先取消索引载添加需要的。两侧都是 map，按名字排序遍历，
+		// 保证同一份差异每次生成的语句顺序一致(迁移文件可审阅、可比对)。
+		var foundIndexNames = make(map[string]bool)
+		var addedNames = make([]string, 0)
+		var dropChanges = make([]*TSchemaChange, 0)
+
+		dropIndex := func(index *TIndex) *TSchemaChange {
+			return &TSchemaChange{
+				Kind:  ChangeDropIndex,
+				Model: modelName,
+				Table: tableName,
+				Index: index.Name,
+				Up:    orm.dialect.DropIndexUniqueSql(self.Schema, tableName, index),
+				Down:  orm.dialect.CreateIndexUniqueSql(self.Schema, tableName, index),
+			}
+		}
+
+		curIndexs := oldModel.GetIndexes() // key 是数据库里的实际索引名(加工名)
+		newIndexs := newModel.GetIndexes() // key 是 struct tag 原始名
+		curNames := sortedIndexNames(curIndexs)
+		var existIndex *TIndex
+		for _, name := range sortedIndexNames(newIndexs) {
+			index := newIndexs[name]
+			// 1. 按加工名(GetName)完全匹配数据库索引——curIndexs 的 key 本就是加工名,
+			// 直接拿原始 name 去查永远落空(见 issue-orm-index-idempotency-restart-fatal
+			// 的教训：两套名字体系不能直接比较),必须先转换成同一空间再比。
+			mangledName := index.GetName(tableName)
+			var matchedName string
+			existIndex = curIndexs[mangledName]
+			if existIndex != nil {
+				matchedName = mangledName
+			} else {
+				// 2. 如果加工名也没对上,尝试按内容(字段和类型)匹配,避免不必要的重建
+				for _, curName := range curNames {
+					// 只有尚未被标记为“找到”的索引才进行内容匹配
+					if curIdx := curIndexs[curName]; !foundIndexNames[curName] && curIdx.Equal(index) {
+						existIndex = curIdx
+						matchedName = curName
+						break
+					}
+				}
+			}
+
+			// 现有的idex
+			if existIndex != nil {
+				// 名字已经在上面按同一空间比对过；这里只需要看内容是否需要重建
+				if !existIndex.Equal(index) {
+					dropChanges = append(dropChanges, dropIndex(existIndex))
+					addedNames = append(addedNames, name) // 加入列表稍后再添加
+				}
+				// 标记数据库中这个索引名已经被处理过,后续不要删除它
+				foundIndexNames[matchedName] = true
+			} else {
+				addedNames = append(addedNames, name) // 加入列表稍后再添加
+			}
+		}
+		changes = append(changes, dropChanges...)
+
+		// 清除已经作删除的索引
+		for _, name := range curNames {
+			if !foundIndexNames[name] {
+				changes = append(changes, dropIndex(curIndexs[name]))
+			}
+		}
+
+		// 重新添加索引
+		for _, name := range addedNames {
+			index := newIndexs[name]
+			if index.Type != UniqueType && index.Type != IndexType {
+				continue
+			}
+
+			// 幂等：CREATE 前按实际索引名查存在性，理由见 _addIndex。
+			sqlStr, args := orm.dialect.IndexCheckSql(self.Schema, tableName, index.GetName(tableName))
+			results, err := self._query(sqlStr, args...)
+			if err != nil {
+				return nil, err
+			}
+			if results.Count() > 0 {
+				continue
+			}
+
+			changes = append(changes, &TSchemaChange{
+				Kind:  ChangeAddIndex,
+				Model: modelName,
+				Table: tableName,
+				Index: name,
+				Up:    orm.dialect.CreateIndexUniqueSql(self.Schema, tableName, index),
+				Down:  orm.dialect.DropIndexUniqueSql(self.Schema, tableName, index),
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// _guardSchemaChanges 破坏性变更守护：未 AllowUnsafe 时拒绝含 Unsafe 变更的同步
+func (self *TSession) _guardSchemaChanges(changes []*TSchemaChange) error {
+	if self.allowUnsafe {
+		return nil
+	}
+
+	var unsafe []*TSchemaChange
+	for _, change := range changes {
+		if change.Unsafe {
+			unsafe = append(unsafe, change)
+		}
+	}
+	if len(unsafe) > 0 {
+		return &UnsafeSchemaError{Changes: unsafe}
+	}
+	return nil
+}
+
+// _narrowingReason 返回把列从 cur 重定义为 field 会丢数据的原因；不收窄时返回空串
+func (self *TSession) _narrowingReason(field, cur IField) string {
+	expected := sqlTypeName(self.orm.dialect.GetSqlType(field))
+	current := sqlTypeName(self.orm.dialect.GetSqlType(cur))
+	if from, ok := intTypeRank[current]; ok {
+		if to, ok := intTypeRank[expected]; ok && to < from {
+			return fmt.Sprintf("narrow type %s to %s", current, expected)
+		}
+	}
+	if current == Text && (expected == Varchar || expected == Char) {
+		return fmt.Sprintf("narrow type %s to %s", current, expected)
+	}
+	if field.Size() > 0 && cur.Size() > 0 && field.Size() < cur.Size() {
+		return fmt.Sprintf("narrow size %d to %d", cur.Size(), field.Size())
+	}
+	return ""
+}
+
+// _countNull 统计列中 NULL 行数
+func (self *TSession) _countNull(tableName, column string) (int64, error) {
+	quoter := self.orm.dialect.Quoter()
+	ds, err := self._query(fmt.Sprintf("SELECT COUNT(*) AS cnt FROM %s WHERE %s IS NULL",
+		quoter.QuoteTable(self.Schema, tableName), quoter.Quote(column)))
+	if err != nil {
+		return 0, err
+	}
+	if ds.Count() == 0 {
+		return 0, nil
+	}
+	ds.First()
+	return ds.FieldByName("cnt").AsInteger(), nil
+}
+
+// _applySchemaChanges 依序执行变更的 Up 语句
+func (self *TSession) _applySchemaChanges(changes []*TSchemaChange) error {
+	for _, change := range changes {
+		if change.Up == "" {
+			continue
+		}
+
+		if _, err := self._exec(change.Up); err != nil {
+			if change.tolerant {
+				log.Err(err)
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedIndexNames(indexes map[string]*TIndex) []string {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sqlTypeName 取类型名主体，如 "VARCHAR(64)" -> "VARCHAR"
+func sqlTypeName(sqlType string) string {
+	if i := strings.IndexByte(sqlType, '('); i > -1 {
+		sqlType = sqlType[:i]
+	}
+	return strings.ToUpper(strings.TrimSpace(sqlType))
+}
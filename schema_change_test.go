@@ -0,0 +1,352 @@
+package orm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	ormerr "github.com/volts-dev/orm/errors"
+)
+
+func TestPlanSyncModel_DoesNotTouchDatabase(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+
+	plan, err := o.PlanSyncModel("", new(BenchModel))
+	if err != nil {
+		t.Fatalf("PlanSyncModel: %v", err)
+	}
+	if plan.Dialect != "sqlite" {
+		t.Fatalf("plan should record the dialect, got %q", plan.Dialect)
+	}
+	if models := plan.Models(); len(models) != 1 || models[0] != "bench.model" {
+		t.Fatalf("plan should cover bench.model only, got %v", models)
+	}
+
+	sqls := plan.SQL()
+	if len(sqls) != 2 || !strings.HasPrefix(sqls[0], "CREATE TABLE") || !strings.HasPrefix(sqls[1], "CREATE INDEX") {
+		t.Fatalf("expected CREATE TABLE followed by CREATE INDEX, got %v", sqls)
+	}
+	if !strings.Contains(plan.String(), "-- model: bench.model") {
+		t.Fatalf("plan script should be grouped by model:\n%s", plan)
+	}
+	if exist, _ := o.IsTableExist("bench_model"); exist {
+		t.Fatal("PlanSyncModel must not create tables")
+	}
+	if o.HasModel("bench.model") {
+		t.Fatal("PlanSyncModel must not register models")
+	}
+
+	// 真正同步后计划为空；结构再变化时只给出增量
+	if _, err = o.SyncModel("", new(BenchModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	if plan, err = o.PlanSyncModel("", new(BenchModel)); err != nil || !plan.IsEmpty() {
+		t.Fatalf("plan should be empty after sync, got %v, %v", plan.SQL(), err)
+	}
+
+	o._computeTriggers("bench.model")
+	plan, err = o.PlanSyncModel("", new(BenchModelV2))
+	if err != nil {
+		t.Fatalf("PlanSyncModel: %v", err)
+	}
+	changes := plan.ByModel("bench.model")
+	if len(changes) != 1 || changes[0].Kind != ChangeAddColumn || changes[0].Column != "email" {
+		t.Fatalf("expected a single add_column for email, got %+v", changes)
+	}
+	model, err := o.GetModel("bench.model")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if model.GetFieldByName("email") != nil {
+		t.Fatal("PlanSyncModel must leave the registered model unchanged")
+	}
+	if o.computeTriggers == nil {
+		t.Fatal("PlanSyncModel must not reset the compute triggers")
+	}
+}
+
+// BenchModelRenamed 把 age 列改名为 years
+type BenchModelRenamed struct {
+	TModel `table:"name('bench_model')"`
+	Id     int64  `field:"pk autoincr title('ID')"`
+	Name   string `field:"varchar() size(64) index"`
+	Years  int    `field:"int() oldname('age')"`
+}
+
+func benchHasColumn(o *TOrm, column string) bool {
+	_, err := o.NewSession()._query("SELECT " + column + " FROM bench_model")
+	return err == nil
+}
+
+func TestSyncModel_RefusesUnsafeChangesByDefault(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+
+	if _, err := o.SyncModel("", new(BenchModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	plan, err := o.PlanSyncModel("", new(BenchModelRenamed))
+	if err != nil {
+		t.Fatalf("PlanSyncModel: %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Kind != ChangeRenameColumn || !plan.Changes[0].Unsafe {
+		t.Fatalf("expected a single unsafe rename, got %s", plan)
+	}
+	if !strings.Contains(plan.String(), "-- UNSAFE: rename column age to years") {
+		t.Fatalf("plan script should flag the unsafe change:\n%s", plan)
+	}
+
+	_, err = o.SyncModel("", new(BenchModelRenamed))
+	var unsafeErr *UnsafeSchemaError
+	if !errors.As(err, &unsafeErr) || !errors.Is(err, ormerr.ErrUnsafe) {
+		t.Fatalf("SyncModel should refuse the rename with UnsafeSchemaError, got %v", err)
+	}
+	if len(unsafeErr.Changes) != 1 || unsafeErr.Changes[0].Column != "years" {
+		t.Fatalf("error should list the refused change, got %+v", unsafeErr.Changes)
+	}
+	if !benchHasColumn(o, "age") {
+		t.Fatal("refused sync must leave the column untouched")
+	}
+
+	if _, err = o.NewSession().AllowUnsafe().SyncModel("", new(BenchModelRenamed)); err != nil {
+		t.Fatalf("SyncModel with AllowUnsafe: %v", err)
+	}
+	if !benchHasColumn(o, "years") {
+		t.Fatal("AllowUnsafe should let the rename through")
+	}
+}
+
+func TestCountNull(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+
+	if _, err := o.SyncModel("", new(BenchModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	if _, err := o.Exec("INSERT INTO bench_model (name, age) VALUES ('a', NULL), ('b', 1)"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	cnt, err := o.NewSession()._countNull("bench_model", "age")
+	if err != nil || cnt != 1 {
+		t.Fatalf("expected 1 NULL row, got %d, %v", cnt, err)
+	}
+}
+
+// alterColumnDialect 为 SQLite 补上改列语句，仅用于生成计划，不在库上执行
+type alterColumnDialect struct {
+	IDialect
+}
+
+func (self *alterColumnDialect) ModifyColumnSql(_, tableName string, col IField) string {
+	return "ALTER TABLE " + tableName + " ALTER COLUMN " + col.Name() + " TYPE " + self.GetSqlType(col)
+}
+
+func (self *alterColumnDialect) DropColumnNotNullSql(_, tableName string, col IField) string {
+	if col.Required() {
+		return "ALTER TABLE " + tableName + " ALTER COLUMN " + col.Name() + " SET NOT NULL"
+	}
+	return "ALTER TABLE " + tableName + " ALTER COLUMN " + col.Name() + " DROP NOT NULL"
+}
+
+type (
+	// BenchModelBig 把 age 由 int 放宽为 bigint
+	BenchModelBig struct {
+		TModel `table:"name('bench_model')"`
+		Id     int64  `field:"pk autoincr title('ID')"`
+		Name   string `field:"varchar() size(64) index"`
+		Age    int64  `field:"bigint()"`
+	}
+
+	// BenchModelRequired 给 age 加 NOT NULL
+	BenchModelRequired struct {
+		TModel `table:"name('bench_model')"`
+		Id     int64  `field:"pk autoincr title('ID')"`
+		Name   string `field:"varchar() size(64) index"`
+		Age    int    `field:"int() required"`
+	}
+
+	// BenchNote 与 bench_model 一同同步的另一模型，V2 补了 body 列
+	BenchNote struct {
+		TModel `table:"name('bench_note')"`
+		Id     int64  `field:"pk autoincr"`
+		Title  string `field:"varchar() size(64)"`
+	}
+	BenchNoteV2 struct {
+		TModel `table:"name('bench_note')"`
+		Id     int64  `field:"pk autoincr"`
+		Title  string `field:"varchar() size(64)"`
+		Body   string `field:"text()"`
+	}
+	// BenchNoteLinked 新增 m2m 字段，注册时会建关联表 bench_note_model_rel
+	BenchNoteLinked struct {
+		TModel   `table:"name('bench_note')"`
+		Id       int64  `field:"pk autoincr"`
+		Title    string `field:"varchar() size(64)"`
+		ModelIds []any  `field:"many2many(bench_model,bench_note_model_rel,note_id,model_id)"`
+	}
+)
+
+// columnChange 取计划中某列的改列变更
+func columnChange(plan *TSyncPlan, column string) *TSchemaChange {
+	for _, change := range plan.Changes {
+		if change.Kind == ChangeAlterColumn && change.Column == column {
+			return change
+		}
+	}
+	return nil
+}
+
+// benchAgeChange 对比模型与库中的 bench_model，返回 age 列的改列变更。
+// SQLite 内省把整数列都读作 INT，curType 模拟保留整数宽度的内省结果
+func benchAgeChange(t *testing.T, o *TOrm, mod IModel, curType string) *TSchemaChange {
+	t.Helper()
+	session := o.NewSession()
+	defer session.Close()
+	metas, err := o.DBMetas(session)
+	if err != nil {
+		t.Fatalf("DBMetas: %v", err)
+	}
+	model, err := o._mapping(mod)
+	if err != nil {
+		t.Fatalf("mapping: %v", err)
+	}
+	for _, meta := range metas {
+		if meta.Table() != "bench_model" {
+			continue
+		}
+		cur := meta.(*TModel)
+		cur.GetFieldByName("age").Base().SqlType = SQLType{Name: curType}
+		changes, err := session._diffTable(model, cur)
+		if err != nil {
+			t.Fatalf("diffTable: %v", err)
+		}
+		for _, change := range changes {
+			if change.Kind == ChangeAlterColumn && change.Column == "age" {
+				return change
+			}
+		}
+	}
+	return nil
+}
+
+func TestSyncModel_NarrowingAndNotNull(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BenchModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	if _, err := o.Exec("INSERT INTO bench_model (name, age) VALUES ('a', NULL), ('b', 1)"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	o.dialect = &alterColumnDialect{IDialect: o.dialect}
+
+	// 整数宽度变化：收窄标记为 Unsafe，放宽照常执行
+	if change := benchAgeChange(t, o, new(BenchModel), BigInt); change == nil || !change.Unsafe || change.Reason != "narrow type BIGINT to INT" {
+		t.Fatalf("bigint to int should be an unsafe narrowing, got %+v", change)
+	}
+	if change := benchAgeChange(t, o, new(BenchModelBig), Int); change == nil || change.Unsafe {
+		t.Fatalf("int to bigint should be a safe widening, got %+v", change)
+	}
+
+	// 对含 NULL 行的列加 NOT NULL 被拒绝，补齐后可执行
+	plan, err := o.PlanSyncModel("", new(BenchModelRequired))
+	if err != nil {
+		t.Fatalf("PlanSyncModel: %v", err)
+	}
+	if change := columnChange(plan, "age"); change == nil || !change.Unsafe || change.Reason != "set NOT NULL on a column with 1 NULL rows" {
+		t.Fatalf("NOT NULL over NULL rows should be unsafe, got %s", plan)
+	}
+	if _, err = o.SyncModel("", new(BenchModelRequired)); !errors.Is(err, ormerr.ErrUnsafe) {
+		t.Fatalf("SyncModel should refuse NOT NULL over NULL rows, got %v", err)
+	}
+	if _, err = o.Exec("UPDATE bench_model SET age = 0 WHERE age IS NULL"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if plan, err = o.PlanSyncModel("", new(BenchModelRequired)); err != nil {
+		t.Fatalf("PlanSyncModel: %v", err)
+	}
+	if change := columnChange(plan, "age"); change == nil || change.Unsafe {
+		t.Fatalf("NOT NULL without NULL rows should be safe, got %s", plan)
+	}
+}
+
+func TestDiffTable_OneModifyPerColumn(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BenchModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	o.dialect = &alterColumnDialect{IDialect: o.dialect}
+
+	session := o.NewSession()
+	defer session.Close()
+	metas, err := o.DBMetas(session)
+	if err != nil {
+		t.Fatalf("DBMetas: %v", err)
+	}
+	model, err := o._mapping(new(BenchModel))
+	if err != nil {
+		t.Fatalf("mapping: %v", err)
+	}
+	for _, meta := range metas {
+		if meta.Table() != "bench_model" {
+			continue
+		}
+		// 库中 name 为 char(32)，模型为 varchar(64)：类型与长度同时变化
+		cur := meta.(*TModel)
+		name := cur.GetFieldByName("name").Base()
+		name.SqlType = SQLType{Name: Char}
+		name.size = 32
+		changes, err := session._diffTable(model, cur)
+		if err != nil {
+			t.Fatalf("diffTable: %v", err)
+		}
+		count := 0
+		for _, change := range changes {
+			if change.Kind == ChangeAlterColumn && change.Column == "name" {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Fatalf("type and size change should give one modify for name, got %d: %+v", count, changes)
+		}
+		return
+	}
+	t.Fatal("bench_model not found")
+}
+
+func TestSyncModel_GuardBeforeApplying(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BenchNote), new(BenchModel)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	// 后一个模型被拒绝时，前一个模型的变更也不执行；非事务会话上 DDL 无从回滚
+	_, err := o.NewSession().SyncModel("", new(BenchNoteV2), new(BenchModelRenamed))
+	if !errors.Is(err, ormerr.ErrUnsafe) {
+		t.Fatalf("SyncModel should refuse the rename, got %v", err)
+	}
+	if _, err = o.NewSession()._query("SELECT body FROM bench_note"); err == nil {
+		t.Fatal("refused sync must not apply the changes of earlier models")
+	}
+
+	// 被拒绝的同步不建关联表，已注册模型保持原样
+	_, err = o.NewSession().SyncModel("", new(BenchNoteLinked), new(BenchModelRenamed))
+	if !errors.Is(err, ormerr.ErrUnsafe) {
+		t.Fatalf("SyncModel should refuse the rename, got %v", err)
+	}
+	if exist, _ := o.IsTableExist("bench_note_model_rel"); exist {
+		t.Fatal("refused sync must not create the relation table")
+	}
+	model, err := o.GetModel("bench.note")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if model.GetFieldByName("model_ids") != nil || model.GetFieldByName("body") != nil {
+		t.Fatal("refused sync must leave the registered model unchanged")
+	}
+}
@@ -2,10 +2,12 @@ package orm
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/volts-dev/orm/core"
 	"github.com/volts-dev/utils"
@@ -22,6 +24,13 @@ type (
 		orm                    *TOrm
 		db                     *core.DB
 		tx                     *core.Tx // 由Begin 传递而来
+		savepoints             []tSavepoint // 嵌套 Begin 建立的保存点，最内层在末尾
+		txOptions              *sql.TxOptions // 开启事务的隔离级别与只读模式，见 Isolation/ReadOnly
+		replicaRead            bool           // 本次读取可由只读副本执行，见 _onReplica
+		usePrimary             bool           // 读取一律走主库，见 Primary
+		writing                int            // 进行中的写操作层数，其间的读取走主库
+		lastWrite              time.Time      // 最近一次写入时间，用于读己之写
+		shard                  int            // 绑定的分片下标，-1 表示未绑定，见 Shard
 		Op                     SessionOp // 当前操作类型，见 OpCreate 等
 		Statement              TStatement
 		context                context.Context
@@ -49,6 +58,7 @@ type (
 		lastSQLArgs  []any                  // 储存有序值
 		allowUnsafe        bool           // Phase 2: bypasses no-WHERE Delete/Write guard; set via AllowUnsafe()
 		softDeleteMode     softDeleteMode // Phase 2: controls Read-path soft-delete filtering (default: filterActive)
+		sudo               bool           // 跳过记录规则(见 TRecordRule)；经 Sudo() 设置
 		exposeScopedFields bool           // 当 true 时，model.BeforeSession 钩子跳过字段级脱敏（如多租户 tenant_id 的 Omit）；默认 false=脱敏（fail closed）。经 IncludeScopedFields() 设置
 	}
 )
@@ -63,6 +73,24 @@ const (
 	OpSum                    // 求和
 )
 
+func (self SessionOp) String() string {
+	switch self {
+	case OpCreate:
+		return "create"
+	case OpRead:
+		return "read"
+	case OpWrite:
+		return "write"
+	case OpDelete:
+		return "unlink"
+	case OpCount:
+		return "count"
+	case OpSum:
+		return "sum"
+	}
+	return "none"
+}
+
 func NewSession(orm *TOrm) *TSession {
 	session := &TSession{
 		db:      orm.db,
@@ -80,6 +108,13 @@ func (self *TSession) init() error {
 	self.IsAutoClose = false
 	self.AutoResetStatement = true
 	self.IsCommitedOrRollbacked = false
+	self.savepoints = nil
+	self.txOptions = nil
+	self.replicaRead = false
+	self.usePrimary = false
+	self.writing = 0
+	self.lastWrite = time.Time{}
+	self.shard = -1
 	self.Prepared = false
 	self.CacheNameIds = nil
 	self.Statement.session = self
@@ -105,6 +140,18 @@ func (self *TSession) Clone() *TSession {
 	// schema 必须随克隆传播：克隆出的会话常用于同一请求内的派生读写(如 m2o 名称
 	// 查找建档)，丢失 schema 会让 schema 隔离租户的派生操作落回 public。
 	session.Schema = self.Schema
+	// 身份(context 上的用户)与 sudo 同理随克隆传播，派生读写按同一用户套用记录规则
+	session.context = self.context
+	session.sudo = self.sudo
+	// 派生会话沿用主库/副本路由状态，避免写入流程中的派生读取落到滞后的副本
+	session.usePrimary = self.usePrimary
+	session.writing = self.writing
+	session.lastWrite = self.lastWrite
+	// 绑定分片(或路由到分片)时派生读写须落在同一分片库上，关联、翻译等取值才能查到本分片的记录
+	if self.db != nil {
+		session.db = self.db
+	}
+	session.shard = self.shard
 	// TODO 优化掉无用的字段
 	//session.Statement = self.Statement
 	//session.Statement.session = self
@@ -124,6 +171,7 @@ func (self *TSession) Close() {
 		// When Close be called, if session is a transaction and do not call
 		// Commit or Rollback, then call Rollback.
 		if self.tx != nil && !self.IsCommitedOrRollbacked {
+			self.savepoints = nil // 整个事务回滚，而非仅回滚到保存点
 			self.Rollback(nil)
 		}
 
@@ -196,7 +244,40 @@ func (self *TSession) SyncModel(region string, models ...IModel) (modelNames []s
 	}
 	handledTables := make(map[string]bool, len(models))
 
-	modelNames = make([]string, 0)
+	// 先映射全部模型并对比已存在的表，整批通过破坏性变更守护后再执行任何 DDL，
+	// 避免后面的模型被拒绝时前面的模型已改了表(mysql 等 DDL 不随事务回滚)。
+	// 守护阶段在注册表副本上映射注册：被拒绝的同步既不建 m2m 关联表也不改动已注册模型
+	scratch := self.orm._scratch()
+	alters := make(map[string][]*TSchemaChange)
+	var planned []*TSchemaChange
+	for _, mod := range models {
+		model, err := scratch._mapping(mod)
+		if err != nil {
+			return nil, err
+		}
+
+		if model == nil {
+			continue
+		}
+
+		if err = scratch.osv._registerModel(region, model, false); err != nil {
+			return nil, err
+		}
+
+		if exitsModel := existsByTable[model.Table()]; exitsModel != nil {
+			changes, err := self._diffTable(model, exitsModel.(*TModel))
+			if err != nil {
+				return nil, err
+			}
+			alters[model.Table()] = changes
+			planned = append(planned, changes...)
+		}
+	}
+	if err = self._guardSchemaChanges(planned); err != nil {
+		return nil, err
+	}
+
+	mapped := make([]*TModel, 0, len(models))
 	for _, mod := range models {
 		model, err := self.orm._mapping(mod)
 		if err != nil {
@@ -211,7 +292,11 @@ func (self *TSession) SyncModel(region string, models ...IModel) (modelNames []s
 		if err = self.orm.osv.RegisterModel(region, model); err != nil {
 			return nil, err
 		}
+		mapped = append(mapped, model)
+	}
 
+	modelNames = make([]string, 0)
+	for _, model := range mapped {
 		modelName := model.String()
 		self.Model(modelName, WithModuleName(region)) // #设置该Session的Model/Table
 		exitsModel := existsByTable[model.Table()]    // 数据库存在的（按表名匹配，见上）
@@ -251,9 +336,25 @@ func (self *TSession) SyncModel(region string, models ...IModel) (modelNames []s
 
 			model.AfterSetup()
 		} else {
-			if err = self._alterTable(model, exitsModel.(*TModel)); err != nil {
+			if err = self._alterTable(model, exitsModel.(*TModel), alters[model.Table()]); err != nil {
+				return modelNames, err
+			}
+		}
+
+		// 审计表由 ORM 管理：首个开启审计的模型同步时建立
+		if self.Statement.Model.Obj().Audit && existsByTable[AuditTable] == nil {
+			if _, err = self._exec(auditTableSql(self.orm.dialect.Quoter(), self.Schema)); err != nil {
 				return modelNames, err
 			}
+			existsByTable[AuditTable] = self.Statement.Model
+		}
+
+		// 翻译附表同理：首个含翻译字段的模型同步时建立(Postgres 用 JSONB 列，无需附表)
+		if !self.orm._translateJsonb() && existsByTable[TranslationTable] == nil && hasTranslatable(self.Statement.Model) {
+			if _, err = self._exec(translationTableSql(self.orm.dialect.Quoter(), self.Schema)); err != nil {
+				return modelNames, err
+			}
+			existsByTable[TranslationTable] = self.Statement.Model
 		}
 
 		modelNames = append(modelNames, modelName)
@@ -274,6 +375,21 @@ func (self *TSession) SyncModel(region string, models ...IModel) (modelNames []s
 	return modelNames, nil
 }
 
+// PlanSyncModel 计划模式的 SyncModel：返回同步这些模型所需的全部 DDL(建表/补列/改列/
+// 索引/m2m 关联表)，只反查库结构，不执行任何语句。
+func (self *TSession) PlanSyncModel(region string, models ...IModel) (*TSyncPlan, error) {
+	changes, err := self._planModels(region, models...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TSyncPlan{
+		Dialect: self.orm.dialect.DBType(),
+		Schema:  self.Schema,
+		Changes: changes,
+	}, nil
+}
+
 // return the orm instance
 func (self *TSession) Orm() *TOrm {
 	return self.orm
@@ -488,6 +604,7 @@ func (self *TSession) WithContext(ctx context.Context) *TSession {
 		ctx = context.Background()
 	}
 	self.context = ctx
+	self._applyTenant()
 	return self
 }
 
@@ -567,241 +684,26 @@ func (self *TSession) _resetStatement() {
 * @model:提供新Session
 * @newModel:Model映射后的新表结构
 * @oldModel:当前数据库的表结构
+* @changes:_diffTable 生成且已通过守护的变更
  */
-func (self *TSession) _alterTable(newModel, oldModel *TModel) (err error) {
-	orm := self.orm
-	tableName := newModel.table
-
-	{ // 字段修改
-		var cur_field IField
-		var fieldName string
-		for _, field := range newModel.GetFields() {
-			fieldName = field.Name()
-			cur_field = oldModel.GetFieldByName(fieldName)
-
-			if cur_field != nil {
-				/* 忽略关系 */
-				if field.IsRelated() {
-					continue
-				}
-
-				expectedType := orm.dialect.GetSqlType(field)
-				curType := orm.dialect.GetSqlType(cur_field)
-				if expectedType != curType {
-					//TODO 修改数据类型
-					// 如果是修改字符串到
-					if expectedType == Text && strings.HasPrefix(curType, Varchar) ||
-						expectedType == Varchar && strings.HasPrefix(curType, Char) {
-						log.Warnf("Table <%s> column <%s> change type from %s to %s", tableName, fieldName, curType, expectedType)
-						_, err = self.Exec(orm.dialect.ModifyColumnSql(self.Schema, tableName, field))
-
-					} else if strings.HasPrefix(curType, Char) && strings.HasPrefix(expectedType, Varchar) {
-						// 如果是同是字符串 则检查长度变化 for mysql
-						if cur_field.Size() != field.Size() {
-							log.Warnf("Table <%s> column <%s> change type from varchar(%d) to varchar(%d)", tableName, fieldName, cur_field.Size(), field.Size())
-							_, err = self.Exec(orm.dialect.ModifyColumnSql(self.Schema, tableName, field))
-						}
-						//}
-						//其他
-					} else {
-						if !strings.HasPrefix(curType, expectedType) || curType[len(expectedType)] != '(' {
-							log.Warnf("Table <%s> column <%s> db type is <%s>, struct type is %s", tableName, fieldName, curType, expectedType)
-						}
-					}
-
-					if err != nil {
-						log.Err(err)
-						err = nil
-					}
-				}
-				// 如果是同是字符串 则检查长度变化 for mysql
-				//if orm.dialect.DBType() == MYSQL {
-				if cur_field.Size() != field.Size() {
-					log.Warnf("Table <%s> column <%s> change size from %s(%d) to %s(%d)",
-						tableName, fieldName, cur_field.SQLType().Name, cur_field.Size(), field.SQLType().Name, field.Size())
-					_, err = self.Exec(orm.dialect.ModifyColumnSql(self.Schema, tableName, field))
-					if err != nil {
-						log.Err(err)
-						err = nil
-					}
-				}
-				//}
-
-				// 两侧 Default() 的动态类型不一致(struct 侧数值型是 int64,DB 内省侧
-				// 因转换代码被注释掉而一直是 string),直接用 any 的 != 比较对数值型
-				// 字段恒为 true。统一转成字符串再比较,和 DropColumnDefaultSql 生成
-				// SQL 时的 utils.ToString 口径保持一致。
-				if utils.ToString(field.Default()) != utils.ToString(cur_field.Default()) {
-					if sql := orm.dialect.DropColumnDefaultSql(self.Schema, tableName, field); sql != "" {
-						if _, err := self.Exec(sql); err != nil {
-							return err
-						}
-					} else if sql := orm.dialect.ModifyColumnSql(self.Schema, tableName, field); sql != "" {
-						if _, err := self.Exec(sql); err != nil {
-							return err
-						}
-					}
-				}
-
-				if field.Required() != cur_field.Required() {
-					if sql := orm.dialect.DropColumnNotNullSql(self.Schema, tableName, field); sql != "" {
-						if _, err := self.Exec(sql); err != nil {
-							return err
-						}
-					} else if sql := orm.dialect.ModifyColumnSql(self.Schema, tableName, field); sql != "" {
-						// Fallback for dialects that use full column modification.
-						if _, err := self.Exec(sql); err != nil {
-							return err
-						}
-					}
-				}
-
-				// 如果现在表无该字段则添加
-			} else {
-				/* 这里必须过滤掉 NOTE [SyncModel] 里提及的特殊字段 */
-				if field.Store() && !field.IsInherited() {
-					//TODO # 修正上面指向错误Model
-					if err = self._addColumn(field); err != nil {
-						log.Err(err)
-						err = nil
-					}
-				}
-
-				/* 为M2M 添加中间表（带上本会话以继承目标 schema）*/
-				if field.IsRelated() {
-					field.UpdateDb(&TTagContext{
-						Orm:     self.orm,
-						Field:   field,
-						Model:   newModel,
-						Session: self,
-					})
-				}
-			}
-		}
+func (self *TSession) _alterTable(newModel, oldModel *TModel, changes []*TSchemaChange) (err error) {
+	if err = self._applySchemaChanges(changes); err != nil {
+		return err
 	}
 
-	{ // 表修改
-		var foundIndexNames = make(map[string]bool)
-		var addedNames = make(map[string]*TIndex)
-
-		// TODO 主键是否可以修改
-		{
-			newKeys := newModel.GetPrimaryKeys()
-			oldKeys := oldModel.GetPrimaryKeys()
-
-			keysChanged := false
-			if len(newKeys) != len(oldKeys) {
-				keysChanged = true
-			} else {
-				for i, k := range newKeys {
-					if k != oldKeys[i] {
-						keysChanged = true
-						break
-					}
-				}
-			}
-
-			if keysChanged {
-				quoter := orm.dialect.Quoter()
-				var dropSql string
-				var addSql string
-				dbType := strings.ToLower(orm.dialect.DBType())
-
-				if len(oldKeys) > 0 {
-					if dbType == "postgres" || dbType == "postgresql" {
-						dropSql = fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s_pkey;",
-							quoter.Quote(tableName), tableName)
-					} else if dbType == "mysql" {
-						dropSql = fmt.Sprintf("ALTER TABLE %s DROP PRIMARY KEY;", quoter.Quote(tableName))
-					}
-				}
-
-				if len(newKeys) > 0 {
-					if dbType == "postgres" || dbType == "postgresql" || dbType == "mysql" {
-						addSql = fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);",
-							quoter.Quote(tableName), quoter.Join(newKeys, ","))
-					}
-				}
-
-				log.Warnf("Table <%s> primary key constraint mismatch between struct (%v) and database (%v)!", tableName, newKeys, oldKeys)
-				if dropSql != "" || addSql != "" {
-					log.Warnf("To synchronize the primary key manually, please run:\n  %s\n  %s", dropSql, addSql)
-				}
-			}
-		}
-
-		// 检查更新索引 先取消索引载添加需要的
-		// 取消Idex
-		curIndexs := oldModel.GetIndexes() // key 是数据库里的实际索引名(加工名)
-		var existIndex *TIndex
-		for name, index := range newModel.GetIndexes() { // key 是 struct tag 原始名
-			// 1. 按加工名(GetName)完全匹配数据库索引——curIndexs 的 key 本就是加工名,
-			// 直接拿原始 name 去查永远落空(见 issue-orm-index-idempotency-restart-fatal
-			// 的教训：两套名字体系不能直接比较),必须先转换成同一空间再比。
-			mangledName := index.GetName(tableName)
-			var matchedName string
-			existIndex = curIndexs[mangledName]
-			if existIndex != nil {
-				matchedName = mangledName
-			} else {
-				// 2. 如果加工名也没对上,尝试按内容(字段和类型)匹配,避免不必要的重建
-				for curName, curIdx := range curIndexs {
-					// 只有尚未被标记为“找到”的索引才进行内容匹配
-					if !foundIndexNames[curName] && curIdx.Equal(index) {
-						existIndex = curIdx
-						matchedName = curName
-						break
-					}
-				}
-			}
-
-			// 现有的idex
-			if existIndex != nil {
-				// 名字已经在上面按同一空间比对过；这里只需要看内容是否需要重建
-				if !existIndex.Equal(index) {
-					sql := orm.dialect.DropIndexUniqueSql(self.Schema, tableName, existIndex)
-					if _, err = self.Exec(sql); err != nil {
-						return err
-					}
-					addedNames[name] = index // 加入列表稍后再添加
-				}
-				// 标记数据库中这个索引名已经被处理过,后续不要删除它
-				foundIndexNames[matchedName] = true
-			} else {
-				addedNames[name] = index // 加入列表稍后再添加
-			}
-		}
-
-		// 清除已经作删除的索引
-		for name, index := range curIndexs {
-			if !foundIndexNames[name] {
-				sql := orm.dialect.DropIndexUniqueSql(self.Schema, tableName, index)
-				if _, err = self.Exec(sql); err != nil {
-					return err
-				}
-			}
-		}
-
-		// 重新添加索引
-		for name, index := range addedNames {
-			//session := orm.NewSession()
-			//self.Model(newModel.String())
-			//TODO # 修正上面指向错误Model
-			//self.Statement.Model = newModel
-			if index.Type == UniqueType {
-				err = self._addUnique(tableName, name)
-
-			} else if index.Type == IndexType {
-				err = self._addIndex(tableName, name)
-			}
-
-			if err != nil {
-				return err
-			}
+	/* 为新增的M2M字段添加中间表（带上本会话以继承目标 schema）*/
+	for _, field := range newModel.GetFields() {
+		if field.IsRelated() && oldModel.GetFieldByName(field.Name()) == nil {
+			field.UpdateDb(&TTagContext{
+				Orm:     self.orm,
+				Field:   field,
+				Model:   newModel,
+				Session: self,
+			})
 		}
 	}
 
-	return
+	return nil
 }
 
 // 内部调用
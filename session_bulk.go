@@ -0,0 +1,683 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/orm/cacher"
+	"github.com/volts-dev/utils"
+)
+
+// tBulkGroup 插入列相同、可合并为多行 INSERT 的一组记录
+type tBulkGroup struct {
+	fields       []string
+	uniqueFields []string
+	rows         []int   // 记录在 src 中的下标
+	params       [][]any // 每条记录按 fields 排列的值
+}
+
+// _bulkCreate 批量新建：先逐条解析待插入值(默认值、时间戳、关联记录照常处理)，
+// 再把插入列相同的记录合并为多行 INSERT，按方言参数上限与 DefaultBatchSize 分批；
+// Postgres 上一组达到 BulkCopyThreshold 条、无冲突处理且在事务内时改用 COPY。
+// 返回的 id 与 src 顺序一致。以下记录仍逐行插入：一条记录需插入多行的；
+// 冲突时不更新(DO NOTHING)的，因被跳过的行无法与 RETURNING 结果对齐；
+// 不支持 RETURNING 的方言上带冲突处理的
+func (self *TSession) _bulkCreate(src []any, idCreator *TIdField) ([]any, error) {
+	rows := make([]*tCreateRow, len(src))
+	for i, one := range src {
+		row, err := self._createValues(one, idCreator)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = row
+	}
+	return self._insertRows(rows)
+}
+
+// _insertRows 插入已解析的记录，插入列相同的合并为多行 INSERT；返回的 id 与 rows 顺序一致，
+// 出错时返回已插入记录的 id
+func (self *TSession) _insertRows(rows []*tCreateRow) ([]any, error) {
+	onConflict := self.Statement.OnConflict
+	bulk := onConflict == nil ||
+		(self.orm.dialect.SupportReturning() && !onConflict.DoNothing && (onConflict.UpdateAll || len(onConflict.DoUpdates) > 0))
+
+	columns := make([][]string, len(rows))
+	params := make([][]any, len(rows))
+	groups := make([]*tBulkGroup, 0)
+	if bulk {
+		index := make(map[string]*tBulkGroup)
+		for i, row := range rows {
+			if row.multiSql != 1 {
+				continue
+			}
+
+			fields, values, uniqueFields, err := self._createColumns(row, 0)
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			columns[i], params[i] = fields, values
+
+			key := strings.Join(fields, ",")
+			group := index[key]
+			if group == nil {
+				group = &tBulkGroup{fields: fields, uniqueFields: uniqueFields}
+				index[key] = group
+				groups = append(groups, group)
+			}
+			group.rows = append(group.rows, i)
+			group.params = append(group.params, values)
+		}
+	}
+
+	inserted := make([]bool, len(rows))
+	for _, group := range groups {
+		ids, err := self._bulkInsert(group, onConflict)
+		for j, id := range ids {
+			i := group.rows[j]
+			rows[i].id = id
+			rows[i].ids = []any{id}
+			inserted[i] = true
+		}
+		if err != nil {
+			done := make([]any, 0, len(rows))
+			for i, row := range rows {
+				if inserted[i] {
+					done = append(done, row.id)
+				}
+			}
+			return done, err
+		}
+	}
+
+	ids := make([]any, 0, len(rows))
+	for i, row := range rows {
+		if columns[i] != nil {
+			if err := self._insertedRow(row.id, columns[i], params[i], row.trans); err != nil {
+				return ids, err
+			}
+		} else if err := self._insertRow(row); err != nil {
+			return ids, err
+		}
+
+		if err := self._createdRow(row); err != nil {
+			return ids, err
+		}
+		ids = append(ids, row.id)
+	}
+	return ids, nil
+}
+
+// _bulkInsert 以多行 INSERT 分批插入一组记录，返回与 group.rows 对应的 id；
+// 出错时返回此前各批已插入记录的 id
+func (self *TSession) _bulkInsert(group *tBulkGroup, onConflict *OnConflict) ([]any, error) {
+	model := self.Statement.Model
+	idField := model.IdField()
+	dialect := self.orm.dialect
+	sqlite := dialect.DBType() == SQLITE
+
+	if onConflict != nil {
+		// 冲突时以本行待插入的值(EXCLUDED)更新，只能更新本次插入的列
+		conflict := *onConflict
+		conflict.DoUpdates = make([]string, 0, len(group.fields))
+		for _, name := range group.fields {
+			if name == idField {
+				continue
+			}
+			if onConflict.UpdateAll {
+				if utils.IndexOf(name, onConflict.Fields...) == -1 {
+					conflict.DoUpdates = append(conflict.DoUpdates, name)
+				}
+			} else if utils.IndexOf(name, onConflict.DoUpdates...) != -1 {
+				conflict.DoUpdates = append(conflict.DoUpdates, name)
+			}
+		}
+		onConflict = &conflict
+	}
+
+	// COPY 只能在事务内进行，事务由 Create 为整批新建开启，见 _copyBulk
+	if onConflict == nil && self.tx != nil && self._copyBulk(len(group.params)) {
+		ids, ok, err := self._copyInsert(group)
+		if err != nil || ok {
+			return ids, err
+		}
+	}
+
+	size := min(DefaultBatchSize, max(dialect.MaxParams()/len(group.fields), 1))
+	var keys []int
+	if onConflict != nil {
+		ok := false
+		if keys, ok = conflictColumns(group.fields, group.uniqueFields, idField, onConflict); !ok || sqlite {
+			// 无法判断批内冲突目标是否重复时逐行插入；SQLite 的 RETURNING 不保证行序，
+			// 更新已有记录时 id 无法与行对应，同样逐行插入
+			size = 1
+		}
+	}
+
+	table := self.Statement.qualifiedTable(model.Table())
+	idIndex := utils.IndexOf(idField, group.fields...)
+	ids := make([]any, 0, len(group.params))
+	var step int64
+	for _, chunk := range bulkBatches(group.params, size, keys) {
+		args := make([]any, 0, len(chunk)*len(group.fields))
+		for _, values := range chunk {
+			args = append(args, values...)
+		}
+		sqlExpr := dialect.GenBulkInsertSql(table, group.fields, len(chunk), group.uniqueFields, idField, onConflict)
+
+		if dialect.SupportReturning() && idField != "" {
+			ds, err := self._query(sqlExpr, args...)
+			if err != nil {
+				return ids, err
+			}
+			self._invalidateExec(sqlExpr, false)
+			if ds.Count() != len(chunk) {
+				return ids, fmt.Errorf("bulk insert into %s returned %d ids for %d rows", model.Table(), ds.Count(), len(chunk))
+			}
+
+			returned := make([]any, len(chunk))
+			for j, rec := range ds.Data {
+				returned[j] = rec.GetByIndex(0)
+			}
+			switch {
+			case !sqlite || len(chunk) == 1:
+				// 按 VALUES 的顺序返回
+			case idIndex != -1:
+				// SQLite 的 RETURNING 不保证行序：主键值已给出时直接取给出的值
+				for j, values := range chunk {
+					returned[j] = values[idIndex]
+				}
+			default:
+				// 同一条 INSERT 的新行按 VALUES 顺序分配递增的 rowid，排序后即与行对应
+				sort.SliceStable(returned, func(a, b int) bool {
+					return utils.ToInt64(returned[a]) < utils.ToInt64(returned[b])
+				})
+			}
+			ids = append(ids, returned...)
+			continue
+		}
+
+		res, err := self._exec(sqlExpr, args...)
+		if err != nil {
+			return ids, err
+		}
+
+		switch {
+		case idField == "":
+			ids = append(ids, make([]any, len(chunk))...)
+		case idIndex != -1:
+			for _, values := range chunk {
+				ids = append(ids, values[idIndex])
+			}
+		default:
+			// 自增主键在同一条多行 INSERT 中按步长连续分配，LastInsertId 为首行 id
+			first, err := res.LastInsertId()
+			if err != nil {
+				return ids, err
+			}
+			if step == 0 {
+				if step, err = self._autoIncrementStep(); err != nil {
+					return ids, err
+				}
+			}
+			for j := range chunk {
+				ids = append(ids, first+int64(j)*step)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// _autoIncrementStep 自增主键的步长：MySQL 为会话的 auto_increment_increment，其他数据库为 1
+func (self *TSession) _autoIncrementStep() (int64, error) {
+	if self.orm.dialect.DBType() != MYSQL {
+		return 1, nil
+	}
+	ds, err := self._query("SELECT @@auto_increment_increment")
+	if err != nil {
+		return 0, err
+	}
+	if ds.Count() == 0 {
+		return 1, nil
+	}
+	step, err := utils.ToInt64E(utils.ToString(ds.Data[0].GetByIndex(0)))
+	if err != nil || step < 1 {
+		return 0, fmt.Errorf("invalid auto_increment_increment %v: %v", ds.Data[0].GetByIndex(0), err)
+	}
+	return step, nil
+}
+
+// conflictColumns 返回冲突目标列在 fields 中的下标，目标与 Postgres 的 ON CONFLICT 一致。
+// 目标为约束名或含未插入的列时无法判断批内重复，返回 ok=false；
+// 目标为未插入的主键时各行主键均为新值不会重复，返回空下标
+func conflictColumns(fields, uniqueFields []string, idField string, onConflict *OnConflict) (keys []int, ok bool) {
+	if onConflict.OnConstraint != "" {
+		return nil, false
+	}
+	target := conflictTarget(onConflict, uniqueFields[:min(len(uniqueFields), 1)], idField)
+	if len(target) == 1 && target[0] == idField && utils.IndexOf(idField, fields...) == -1 {
+		return nil, true
+	}
+	for _, name := range target {
+		idx := utils.IndexOf(name, fields...)
+		if idx == -1 {
+			return nil, false
+		}
+		keys = append(keys, idx)
+	}
+	return keys, true
+}
+
+// bulkBatches 把 rows 按 size 分批。给出 keys 时同一批内冲突目标值不重复，重复的行另起一批：
+// 一条 INSERT ... ON CONFLICT DO UPDATE 不能两次更新同一行，分批后按顺序后者覆盖前者
+func bulkBatches(rows [][]any, size int, keys []int) [][][]any {
+	batches := make([][][]any, 0, len(rows)/size+1)
+	seen := make(map[string]bool)
+	start := 0
+	for i, values := range rows {
+		key := ""
+		if len(keys) > 0 {
+			parts := make([]any, 0, len(keys))
+			for _, k := range keys {
+				if values[k] == nil {
+					// NULL 互不冲突
+					parts = nil
+					break
+				}
+				parts = append(parts, values[k])
+			}
+			if parts != nil {
+				key = fmt.Sprintf("%#v", parts)
+			}
+		}
+
+		if i-start == size || (key != "" && seen[key]) {
+			batches = append(batches, rows[start:i])
+			start = i
+			clear(seen)
+		}
+		if key != "" {
+			seen[key] = true
+		}
+	}
+	if start < len(rows) {
+		batches = append(batches, rows[start:])
+	}
+	return batches
+}
+
+// _copyBulk 新建 n 条记录时是否可能改用 COPY：Postgres 上达到 BulkCopyThreshold 条且无冲突处理
+func (self *TSession) _copyBulk(n int) bool {
+	return self.Statement.OnConflict == nil && self.orm.dialect.DBType() == POSTGRES && n >= BulkCopyThreshold
+}
+
+// _copyInsert 以 COPY FROM STDIN 插入一组记录，须在事务内调用。COPY 无法 RETURNING，
+// 未指定主键值时先从主键序列预取 id；主键无序列时返回 ok=false 由调用方改用 INSERT
+func (self *TSession) _copyInsert(group *tBulkGroup) (ids []any, ok bool, err error) {
+	model := self.Statement.Model
+	idField := model.IdField()
+	fields, rows := group.fields, group.params
+
+	if idIndex := utils.IndexOf(idField, fields...); idIndex != -1 {
+		for _, values := range rows {
+			ids = append(ids, values[idIndex])
+		}
+	} else {
+		ds, err := self._query(`SELECT nextval(pg_get_serial_sequence(?, ?)) FROM generate_series(1, ?)`,
+			self.Statement.qualifiedTable(model.Table()), idField, len(rows))
+		if err != nil {
+			return nil, false, err
+		}
+		if ds.Count() != len(rows) || ds.Data[0].GetByIndex(0) == nil {
+			return nil, false, nil
+		}
+
+		fields = append(slices.Clone(fields), idField)
+		withIds := make([][]any, len(rows))
+		for i, values := range rows {
+			id := ds.Data[i].GetByIndex(0)
+			ids = append(ids, id)
+			withIds[i] = append(slices.Clone(values), id)
+		}
+		rows = withIds
+	}
+
+	schema := self.Schema
+	if schema == "" {
+		schema = self.orm.dialect.DataSource().Schema
+	}
+	_, err = self.orm._logExecSql(fmt.Sprintf("COPY %s (%s) FROM STDIN [%d rows]", model.Table(), strings.Join(fields, ","), len(rows)), nil, func() (sql.Result, error) {
+		return nil, pqCopyIn(self.context, self.tx, schema, model.Table(), fields, rows)
+	})
+	if err != nil {
+		return nil, false, self.orm.dialect.MapError(err)
+	}
+	self._invalidate(func(chr *cacher.TCacher) {
+		chr.ClearSqlByTable(model.Table())
+	})
+	return ids, true, nil
+}
+
+// tWriteRow 一条待批量更新的记录
+type tWriteRow struct {
+	id       any
+	data     *dataset.TDataSet
+	explicit bool                      // 数据中的键即调用方明确给出的字段，见 hasExplicitKeys
+	version  any                       // 调用方读取时的版本，nil 表示不作版本校验
+	values   map[string]any            // 本表列的新值
+	refs     map[string]map[string]any // _inherits 父表字段的新值
+	trans    map[string]any            // 翻译字段的新值
+}
+
+// WriteMany 批量更新多条记录：每条数据须带主键，各记录可更新为不同的值。
+// 更新列相同的记录合并为一条语句，Postgres 上为 UPDATE ... FROM (VALUES ...)，
+// 其他数据库为 CASE id WHEN ... THEN ...；更新时间戳等按记录照常补全。
+// 模型有版本字段时各记录按自带的版本校验，冲突时返回 StaleRecordError。
+// 非事务会话自开事务，任一记录失败时整体回滚
+func (self *TSession) WriteMany(src ...any) (effect int64, err error) {
+	model := self.Statement.Model
+	self.Op = OpWrite
+	if _, err := model.BeforeSession(self); err != nil {
+		return 0, err
+	}
+	defer func() {
+		model.AfterSession(self)
+		self._resetStatement()
+	}()
+
+	if self.IsAutoClose {
+		defer self.Close()
+	}
+
+	if self.IsDeprecated {
+		return -1, ErrInvalidSession
+	}
+	// 批量更新不跨分片，须先以 Shard 指定分片
+	if self._shardRouted() {
+		return 0, ErrShardRequired
+	}
+	defer self._onPrimary()()
+
+	if self.IsAutoCommit {
+		if err = self.Begin(); err != nil {
+			return 0, err
+		}
+		effect, err = self._writeMany(src)
+		if err != nil {
+			self.Rollback(err)
+			return 0, err
+		}
+		return effect, self.Commit()
+	}
+
+	return self._writeMany(src)
+}
+
+// Low-level implementation of WriteMany()
+func (self *TSession) _writeMany(src []any) (int64, error) {
+	model := self.Statement.Model
+	if len(model.String()) < 1 {
+		return 0, ErrTableNotFound
+	}
+	if len(src) == 0 {
+		return 0, fmt.Errorf("must submit the values for update")
+	}
+
+	// 解析各记录时关系字段可能执行语句，Statement 须保留到全部记录处理完
+	if self.AutoResetStatement {
+		self.AutoResetStatement = false
+		defer func() {
+			self.AutoResetStatement = true
+		}()
+	}
+
+	idField := model.IdField()
+	rows := make([]*tWriteRow, 0, len(src))
+	ids := make([]any, 0, len(src))
+	for _, one := range src {
+		data, err := self._validateValues(one)
+		if err != nil {
+			return 0, err
+		}
+
+		/* 应用 Sets（覆盖已有值） */
+		if len(self.Statement.Sets) > 0 {
+			rec := data.Record()
+			for k, v := range self.Statement.Sets {
+				rec.SetByField(k, v)
+			}
+		}
+		self._stampTenant(data.Record(), false)
+
+		id := data.Record().GetByField(idField)
+		if id == nil || utils.IsBlank(id) {
+			return 0, fmt.Errorf("each record of batch update must carry its %s", idField)
+		}
+		for _, id := range flattenIds([]any{id}) {
+			rows = append(rows, &tWriteRow{id: id, data: data, explicit: hasExplicitKeys(one)})
+			ids = append(ids, id)
+		}
+	}
+
+	if err := self._checkRecordRules(OpWrite, ids); err != nil {
+		return 0, err
+	}
+
+	hooked := self._hooked(OpWrite)
+	if hooked {
+		for _, row := range rows {
+			if err := self._callHook(OpWrite, true, []any{row.id}, []map[string]any{row.data.Record().AsMap()}); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	versionField := model.Obj().VersionField
+	computeOn := make([]string, 0)
+	for _, row := range rows {
+		newVals, refVals, newTodo, err := self._separateValues(row.data, self.Statement.Fields, self.Statement.NullableFields, false, []any{row.id}, row.explicit)
+		if err != nil {
+			return 0, err
+		}
+		if row.trans, err = self._splitTranslations(newVals, false); err != nil {
+			return 0, err
+		}
+
+		// 根据字段计算数据值，计算值优先
+		datas, _, err := self._todoCompute(row.data, []any{row.id}, newTodo)
+		if err != nil {
+			return 0, err
+		}
+		for k, vs := range datas {
+			if len(vs) > 0 {
+				newVals[k] = vs[0]
+			}
+		}
+		if versionField != "" {
+			if v := row.data.Record().GetByField(versionField); v != nil && !utils.IsBlank(v) {
+				row.version = v
+			}
+			delete(newVals, versionField)
+		}
+		row.values, row.refs = newVals, refVals
+
+		for name := range row.data.Record().AsMap() {
+			if utils.IndexOf(name, computeOn...) == -1 {
+				computeOn = append(computeOn, name)
+			}
+		}
+	}
+
+	// 写入前收集经旧关联受影响的计算字段记录
+	var computeTodo map[string]*tComputeTodo
+	computeTriggered := self._computeTriggered()
+	if computeTriggered {
+		var err error
+		if computeTodo, err = self._computeAffected(nil, model.String(), computeOn, ids); err != nil {
+			return 0, err
+		}
+	}
+
+	// 更新前留存旧值供审计
+	var auditColumns []string
+	var auditOld map[string]*dataset.TRecordSet
+	if self._auditEnabled() {
+		names := make([]string, 0)
+		for _, row := range rows {
+			for k := range row.values {
+				if utils.IndexOf(k, names...) == -1 {
+					names = append(names, k)
+				}
+			}
+		}
+		auditColumns = self._auditColumns(names...)
+		var err error
+		if auditOld, err = self._auditSnapshot(ids, auditColumns); err != nil {
+			return 0, err
+		}
+	}
+
+	// 更新列相同的记录合并为一组
+	type tGroup struct {
+		fields   []IField
+		ids      []any
+		rows     [][]any
+		versions []any
+	}
+	groups := make([]*tGroup, 0)
+	index := make(map[string]*tGroup)
+	for _, row := range rows {
+		if len(row.values) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(row.values))
+		for k := range row.values {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		key := strings.Join(names, ",")
+		if row.version != nil {
+			key += ";version"
+		}
+		group := index[key]
+		if group == nil {
+			group = &tGroup{fields: make([]IField, len(names))}
+			for i, name := range names {
+				if group.fields[i] = model.GetFieldByName(name); group.fields[i] == nil {
+					return 0, fmt.Errorf("field %s not found on model %s", name, model.String())
+				}
+			}
+			index[key] = group
+			groups = append(groups, group)
+		}
+
+		values := make([]any, len(names))
+		for i, name := range names {
+			values[i] = row.values[name]
+		}
+		group.ids = append(group.ids, row.id)
+		group.rows = append(group.rows, values)
+		if row.version != nil {
+			group.versions = append(group.versions, row.version)
+		}
+	}
+
+	var effectedRows int64
+	var version IField
+	if versionField != "" {
+		version = model.GetFieldByName(versionField)
+	}
+	stale := make([]any, 0)
+	dialect := self.orm.dialect
+	table := self.Statement.qualifiedTable(model.Table())
+	for _, group := range groups {
+		// CASE 形式每个值占两个参数，版本条件另占两个
+		batch := min(DefaultBatchSize, max(dialect.MaxParams()/(2*len(group.fields)+3), 1))
+		for start := 0; start < len(group.ids); start += batch {
+			end := min(start+batch, len(group.ids))
+			var versions []any
+			if group.versions != nil {
+				versions = group.versions[start:end]
+			}
+			sqlExpr, params := dialect.GenBulkUpdateSql(table, model.GetFieldByName(idField), group.fields, group.ids[start:end], group.rows[start:end], version, versions)
+			res, err := self._exec(sqlExpr, params...)
+			if err != nil {
+				return 0, err
+			}
+			cnt, err := res.RowsAffected()
+			if err != nil {
+				return 0, err
+			}
+			self._invalidateIds(model.Table(), group.ids[start:end]...)
+			effectedRows += cnt
+
+			if versions != nil && cnt < int64(end-start) {
+				ids, err := self._staleIds(group.ids[start:end], versions)
+				if err != nil {
+					return 0, err
+				}
+				stale = append(stale, ids...)
+			}
+		}
+	}
+	if len(stale) > 0 {
+		return 0, &StaleRecordError{Model: model.String(), Ids: stale}
+	}
+
+	var entries []auditEntry
+	for _, row := range rows {
+		if len(row.trans) > 0 {
+			if err := self._writeTranslations([]any{row.id}, row.trans); err != nil {
+				return 0, err
+			}
+			if len(row.values) == 0 {
+				effectedRows++
+			}
+		}
+
+		if rec := auditOld[utils.ToString(row.id)]; rec != nil {
+			for _, col := range auditColumns {
+				if val, has := row.values[col]; has {
+					entries = append(entries, auditEntry{resId: row.id, field: col, old: rec.GetByField(col), new: val})
+				}
+			}
+		}
+
+		if err := self._writeInherits([]any{row.id}, row.refs); err != nil {
+			return 0, err
+		}
+	}
+	if len(entries) > 0 {
+		if err := self._auditRecord(OpWrite, entries); err != nil {
+			return 0, err
+		}
+	}
+
+	if computeTriggered {
+		var err error
+		if computeTodo, err = self._computeAffected(computeTodo, model.String(), computeOn, ids); err != nil {
+			return 0, err
+		}
+		if err = self._runRecompute(computeTodo, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	if hooked {
+		for _, row := range rows {
+			if err := self._callHook(OpWrite, false, []any{row.id}, []map[string]any{row.data.Record().AsMap()}); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return effectedRows, nil
+}
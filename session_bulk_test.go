@@ -0,0 +1,256 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/volts-dev/orm/core"
+	"github.com/volts-dev/utils"
+)
+
+type (
+	BulkItem struct {
+		TModel   `table:"name('bulk_item')"`
+		Id       int64  `field:"pk autoincr"`
+		Code     string `field:"varchar() size(32) unique"`
+		Qty      int    `field:"int()"`
+		State    string `field:"varchar() size(16) default('draft')"`
+		CreateAt int64  `field:"int() created"`
+		WriteAt  int64  `field:"int() updated"`
+	}
+
+	// sqlRecorder 记录执行过的 SQL
+	sqlRecorder struct {
+		sync.Mutex
+		sqls []string
+	}
+)
+
+func (self *sqlRecorder) BeforeProcess(c *core.ContextHook) (context.Context, error) {
+	self.Lock()
+	self.sqls = append(self.sqls, c.SQL)
+	self.Unlock()
+	return c.Ctx, nil
+}
+
+func (self *sqlRecorder) AfterProcess(c *core.ContextHook) error { return nil }
+
+func (self *sqlRecorder) count(prefix string) int {
+	self.Lock()
+	defer self.Unlock()
+	n := 0
+	for _, s := range self.sqls {
+		if strings.HasPrefix(s, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSession_CreateBulkInsert(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	recorder := &sqlRecorder{}
+	o.db.AddHook(recorder)
+
+	// 多条记录合并为多行 INSERT，按 DefaultBatchSize 分批，id 与传入顺序一致
+	values := make([]any, 0, 1200)
+	for i := 0; i < 1200; i++ {
+		values = append(values, map[string]any{"code": fmt.Sprintf("C%04d", i), "qty": i})
+	}
+	ids, err := o.NewSession().Model("bulk_item").Create(values...)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(ids) != len(values) {
+		t.Fatalf("Create should return %d ids, got %d", len(values), len(ids))
+	}
+	if n := recorder.count("INSERT INTO `bulk_item`"); n != 3 {
+		t.Fatalf("1200 records should be inserted by 3 statements, got %d", n)
+	}
+
+	ds, err := o.NewSession().Model("bulk_item").Ids(ids[0], ids[599], ids[1199]).OrderBy("id").Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	for i, want := range []int{0, 599, 1199} {
+		rec := ds.Data[i]
+		if rec.GetByField("code") != fmt.Sprintf("C%04d", want) || fmt.Sprint(rec.GetByField("qty")) != fmt.Sprint(want) {
+			t.Fatalf("id %v should map to record %d, got %v", ids[want], want, rec.AsMap())
+		}
+		if rec.GetByField("state") != "draft" || utils.IsBlank(rec.GetByField("create_at")) {
+			t.Fatalf("defaults and created timestamp should be applied, got %v", rec.AsMap())
+		}
+	}
+
+	// 冲突时更新：已存在的记录被更新并返回原 id，新记录照常插入
+	ids2, err := o.NewSession().Model("bulk_item").OnConflict(&OnConflict{Fields: []string{"code"}, DoUpdates: []string{"qty"}}).Create(
+		map[string]any{"code": "C0001", "qty": 100},
+		map[string]any{"code": "N0001", "qty": 5},
+	)
+	if err != nil {
+		t.Fatalf("Create on conflict: %v", err)
+	}
+	if len(ids2) != 2 || ids2[0] != ids[1] || ids2[1] == nil {
+		t.Fatalf("conflicting record should return its original id %v, got %v", ids[1], ids2)
+	}
+	rec, err := o.NewSession().Model("bulk_item").Ids(ids[1]).Read()
+	if err != nil || fmt.Sprint(rec.Record().GetByField("qty")) != "100" {
+		t.Fatalf("conflicting record should be updated: %v %v", rec, err)
+	}
+
+	// 冲突时忽略：逐行插入，被跳过的记录不影响其他记录
+	if _, err = o.NewSession().Model("bulk_item").OnConflict(&OnConflict{DoNothing: true}).Create(
+		map[string]any{"code": "C0002", "qty": 1},
+		map[string]any{"code": "N0002", "qty": 2},
+	); err != nil {
+		t.Fatalf("Create do nothing: %v", err)
+	}
+	if count, _ := o.NewSession().Model("bulk_item").Count(); count != 1202 {
+		t.Fatalf("expect 1202 records, got %d", count)
+	}
+}
+
+func TestSession_CreateBulkIds(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	if _, err := o.NewSession().Model("bulk_item").Create(map[string]any{"code": "K10"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 后一批失败时返回前一批已插入记录的 id
+	session := o.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	values := make([]any, 0, DefaultBatchSize+1)
+	for i := 0; i < DefaultBatchSize; i++ {
+		values = append(values, map[string]any{"code": fmt.Sprintf("P%04d", i)})
+	}
+	values = append(values, map[string]any{"code": "K10"})
+	ids, err := session.Model("bulk_item").Create(values...)
+	if err == nil || len(ids) != DefaultBatchSize {
+		t.Fatalf("failed batch should return the ids inserted before it, got %d ids %v", len(ids), err)
+	}
+	ds, err := session.Model("bulk_item").Ids(ids[0], ids[DefaultBatchSize-1]).OrderBy("id").Read()
+	if err != nil || fmt.Sprint(ds.Keys("code")) != fmt.Sprintf("[P0000 P%04d]", DefaultBatchSize-1) {
+		t.Fatalf("returned ids should be the inserted records: %v %v", ds, err)
+	}
+	session.Rollback(nil)
+}
+
+func TestBulkBatches(t *testing.T) {
+	rows := [][]any{{"a", 1}, {"b", 2}, {"a", 3}, {nil, 4}, {nil, 5}, {"c", 6}, {"d", 7}}
+	sizes := func(batches [][][]any) string {
+		n := make([]int, len(batches))
+		for i, batch := range batches {
+			n[i] = len(batch)
+		}
+		return fmt.Sprint(n)
+	}
+
+	// 冲突目标值重复的行另起一批，NULL 互不冲突
+	if got := sizes(bulkBatches(rows, 500, []int{0})); got != "[2 5]" {
+		t.Fatalf("batches split at duplicates = %s", got)
+	}
+	if got := sizes(bulkBatches(rows, 3, []int{0})); got != "[2 3 2]" {
+		t.Fatalf("batches split at duplicates and size = %s", got)
+	}
+	if got := sizes(bulkBatches(rows, 3, nil)); got != "[3 3 1]" {
+		t.Fatalf("batches by size = %s", got)
+	}
+
+	fields := []string{"code", "qty"}
+	if keys, ok := conflictColumns(fields, []string{"code"}, "id", &OnConflict{DoUpdates: []string{"qty"}}); !ok || fmt.Sprint(keys) != "[0]" {
+		t.Fatalf("conflict on the unique field = %v %v", keys, ok)
+	}
+	if keys, ok := conflictColumns(fields, nil, "id", &OnConflict{DoUpdates: []string{"qty"}}); !ok || keys != nil {
+		t.Fatalf("conflict on a new primary key never repeats, got %v %v", keys, ok)
+	}
+	if _, ok := conflictColumns(fields, nil, "id", &OnConflict{Fields: []string{"state"}}); ok {
+		t.Fatalf("conflict on a column not inserted cannot be checked")
+	}
+	if _, ok := conflictColumns(fields, nil, "id", &OnConflict{OnConstraint: "bulk_item_code_key"}); ok {
+		t.Fatalf("conflict on a named constraint cannot be checked")
+	}
+}
+
+func TestSession_WriteManyBatchUpdate(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	ids, err := o.NewSession().Model("bulk_item").Create(
+		map[string]any{"code": "A", "qty": 1},
+		map[string]any{"code": "B", "qty": 2},
+		map[string]any{"code": "C", "qty": 3},
+	)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	recorder := &sqlRecorder{}
+	o.db.AddHook(recorder)
+
+	// 各记录取不同的值，同列记录合并为一条语句
+	effect, err := o.NewSession().Model("bulk_item").WriteMany(
+		map[string]any{"id": ids[0], "qty": 10},
+		map[string]any{"id": ids[1], "qty": 20},
+		map[string]any{"id": ids[2], "qty": 30, "state": "done"},
+	)
+	if err != nil || effect != 3 {
+		t.Fatalf("WriteMany: %d %v", effect, err)
+	}
+	if n := recorder.count("UPDATE bulk_item"); n != 2 {
+		t.Fatalf("records sharing columns should be updated by one statement, got %d statements", n)
+	}
+	ds, err := o.NewSession().Model("bulk_item").OrderBy("id").Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	for i, want := range []string{"10 draft", "20 draft", "30 done"} {
+		rec := ds.Data[i]
+		if got := fmt.Sprint(rec.GetByField("qty"), " ", rec.GetByField("state")); got != want {
+			t.Fatalf("record %d should be %q, got %q", i, want, got)
+		}
+		if utils.IsBlank(rec.GetByField("write_at")) {
+			t.Fatalf("updated timestamp should be set, got %v", rec.AsMap())
+		}
+	}
+
+	// UpdateRequest 多条数据走批量更新；违反唯一约束时整体回滚
+	model, err := o.GetModel("bulk_item")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	if _, err = model.Update(&UpdateRequest{Data: []any{
+		map[string]any{"id": ids[0], "qty": 11},
+		map[string]any{"id": ids[1], "code": "A"},
+	}}); err == nil {
+		t.Fatalf("duplicate code should fail")
+	}
+	if rec, _ := o.NewSession().Model("bulk_item").Ids(ids[0]).Read(); fmt.Sprint(rec.Record().GetByField("qty")) != "10" {
+		t.Fatalf("failed batch should be rolled back, got %v", rec.Record().AsMap())
+	}
+	if effect, err = model.Update(&UpdateRequest{Data: []any{
+		map[string]any{"id": ids[0], "qty": 12},
+		map[string]any{"id": ids[1], "qty": 22},
+	}}); err != nil || effect != 2 {
+		t.Fatalf("UpdateRequest: %d %v", effect, err)
+	}
+
+	if _, err = o.NewSession().Model("bulk_item").WriteMany(map[string]any{"qty": 1}, map[string]any{"id": ids[0], "qty": 2}); err == nil {
+		t.Fatalf("record without id should be rejected")
+	}
+}
@@ -1,12 +1,13 @@
 package orm
 
 import (
-	"database/sql"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/orm/cacher"
 	"github.com/volts-dev/orm/errors"
 	"github.com/volts-dev/utils"
 )
@@ -33,6 +34,28 @@ func (self *TSession) Create(src ...any) (uid []any, err error) {
 	if self.IsDeprecated {
 		return nil, ErrInvalidSession
 	}
+	defer self._onPrimary()()
+
+	// 建档规则只能在插入后按新记录校验：非事务会话自开事务，被拒绝时整体回滚；
+	// 审计记录同样须与插入同进同退。COPY 须在事务内进行，其后的关联、翻译与钩子随之同进同退。
+	// 分片模型在各分片上分别开启事务
+	if self.IsAutoCommit && !self._shardRouted() {
+		node, err := self._ruleDomain(OpCreate)
+		if err != nil {
+			return nil, err
+		}
+		if node != nil || self._atomic(OpCreate) || self._copyBulk(len(src)) {
+			if err = self.Begin(); err != nil {
+				return nil, err
+			}
+			ids, err := self._create(src...)
+			if err != nil {
+				self.Rollback(err)
+				return nil, err
+			}
+			return ids, self.Commit()
+		}
+	}
 
 	return self._create(src...)
 }
@@ -59,6 +82,10 @@ func (self *TSession) Read() (*TDataset, error) {
 		return nil, ErrInvalidSession
 	}
 
+	defer self._onReplica()()
+	if self._shardRouted() {
+		return self._readSharded()
+	}
 	return self._read()
 }
 
@@ -85,6 +112,23 @@ func (self *TSession) Write(data any) (effect int64, err error) {
 	if self.IsDeprecated {
 		return -1, ErrInvalidSession
 	}
+	defer self._onPrimary()()
+	if self._shardRouted() {
+		return self._writeSharded(data)
+	}
+
+	// 审计记录与记录级钩子须与更新同一事务
+	if self.IsAutoCommit && self._atomic(OpWrite) {
+		if err = self.Begin(); err != nil {
+			return 0, err
+		}
+		effect, err = self._write(data)
+		if err != nil {
+			self.Rollback(err)
+			return 0, err
+		}
+		return effect, self.Commit()
+	}
 
 	return self._write(data)
 }
@@ -110,7 +154,29 @@ func (self *TSession) Delete(ids ...any) (res_effect int64, err error) {
 	if self.IsDeprecated {
 		return -1, ErrInvalidSession
 	}
+	defer self._onPrimary()()
+	if self._shardRouted() {
+		return self._deleteSharded(ids...)
+	}
+
+	// 审计记录与记录级钩子须与删除同一事务
+	if self.IsAutoCommit && self._atomic(OpDelete) {
+		if err = self.Begin(); err != nil {
+			return 0, err
+		}
+		res_effect, err = self._delete(ids...)
+		if err != nil {
+			self.Rollback(err)
+			return 0, err
+		}
+		return res_effect, self.Commit()
+	}
 
+	return self._delete(ids...)
+}
+
+// Low-level implementation of Delete()
+func (self *TSession) _delete(ids ...any) (int64, error) {
 	// TODO 为什么用len
 	if len(self.Statement.Model.String()) < 1 {
 		return 0, ErrTableNotFound
@@ -135,6 +201,14 @@ func (self *TSession) Delete(ids ...any) (res_effect int64, err error) {
 			return 0, err
 		}
 	}
+	if err := self._checkRecordRules(OpDelete, ids); err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		if err := self._callHook(OpDelete, true, ids, nil); err != nil {
+			return 0, err
+		}
+	}
 	expectRowCount := int64(len(ids))
 
 	if len(ids) == 0 {
@@ -142,6 +216,17 @@ func (self *TSession) Delete(ids ...any) (res_effect int64, err error) {
 		return 0, nil
 	}
 
+	// 删除前留存旧值供审计
+	var auditColumns []string
+	var auditOld map[string]*dataset.TRecordSet
+	if self._auditEnabled() {
+		auditColumns = self._auditColumns()
+		var err error
+		if auditOld, err = self._auditSnapshot(ids, auditColumns); err != nil {
+			return 0, err
+		}
+	}
+
 	// get the model id field name
 	id_field := self.Statement.Model.IdField()
 	quoter := self.orm.dialect.Quoter()
@@ -155,6 +240,7 @@ func (self *TSession) Delete(ids ...any) (res_effect int64, err error) {
 	if err != nil {
 		return 0, err
 	}
+	self._invalidateIds(self.Statement.Model.Table(), ids...)
 
 	cnt, err := res.RowsAffected()
 	if err != nil {
@@ -166,31 +252,41 @@ func (self *TSession) Delete(ids ...any) (res_effect int64, err error) {
 		return 0, err
 	}
 
+	if err = self._deleteTranslations(ids); err != nil {
+		return 0, err
+	}
+
+	if auditOld != nil {
+		entries := make([]auditEntry, 0, len(auditOld)*len(auditColumns))
+		for _, id := range ids {
+			rec := auditOld[utils.ToString(id)]
+			if rec == nil {
+				continue
+			}
+			for _, col := range auditColumns {
+				entries = append(entries, auditEntry{resId: id, field: col, old: rec.GetByField(col)})
+			}
+		}
+		if err = self._auditRecord(OpDelete, entries); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = self._callHook(OpDelete, false, ids, nil); err != nil {
+		return 0, err
+	}
+
 	/* check the row count */
 	if cnt != expectRowCount {
 		log.Warnf("expect delete %d rows, but %d rows affected", expectRowCount, cnt)
 		return expectRowCount, nil
 	}
-	/*
-		table_name := self.Statement.Model.Table()
-		//lCacher := self.orm.Cacher.RecCacher(self.Statement.Model.GetName()) // for del
-		//if lCacher != nil {
-		for _, id := range ids {
-			//lCacher.Remove(id)
-			self.orm.Cacher.RemoveById(table_name, id)
-		}
-		//}
-		// #由于表数据有所变动 所以清除所有有关于该表的SQL缓存结果
-		//lCacher = self.orm.Cacher.SqlCacher(self.Statement.Model.GetName()) // for del
-		//lCacher.Clear()
-		self.orm.Cacher.ClearByTable(self.Statement.Model.Table())
-	*/
 	return res.RowsAffected()
 }
 
 // _create 支持传入多个 src（变参/数组），一次插入多条记录。
 // 优化点：整批共享的不变量（表名校验、主键字段解析）在循环外只处理一次，
-// 循环内仅保留与每条记录数据相关的处理；其余逻辑与 _create 完全一致。
+// 循环内仅保留与每条记录数据相关的处理；多条记录时合并为多行 INSERT（见 _bulkCreate）。
 // 返回每条记录的 id（顺序与传入一致）；中途出错则返回已成功的 id 列表 + error。
 func (self *TSession) _create(src ...any) ([]any, error) {
 	// —— 整批不变量：循环外只处理一次 ——
@@ -203,6 +299,17 @@ func (self *TSession) _create(src ...any) ([]any, error) {
 		src = []any{nil}
 	}
 
+	var hookValues []map[string]any
+	if self._hooked(OpCreate) {
+		var err error
+		if hookValues, err = self._hookValues(src...); err != nil {
+			return nil, err
+		}
+		if err = self._callHook(OpCreate, true, nil, hookValues); err != nil {
+			return nil, err
+		}
+	}
+
 	idField := self.Statement.Model.IdField()
 	// 主键字段对象与 TIdField 断言仅解析一次；OnCreate 仍按记录调用
 	var idCreator *TIdField
@@ -210,241 +317,351 @@ func (self *TSession) _create(src ...any) ([]any, error) {
 		idCreator, _ = field.(*TIdField)
 	}
 
-	ids := make([]any, 0, len(src))
+	// 分片模型按分片键把记录分组插入各分片
+	if self._shardRouted() {
+		return self._createSharded(src, idCreator, hookValues)
+	}
 
-	// —— 每条记录处理 ——
-	for _, one := range src {
-		// If src is nil but Sets are present, use Sets as the data source.
-		// If src is provided, _validateValues converts it; Sets are applied afterward.
-		if one == nil && len(self.Statement.Sets) == 0 {
-			return ids, fmt.Errorf("must submit the values for create")
+	var ids []any
+	if len(src) > 1 {
+		var err error
+		if ids, err = self._bulkCreate(src, idCreator); err != nil {
+			return ids, err
 		}
-		srcWasSets := false
-		if one == nil {
-			one = self.Statement.Sets
-			srcWasSets = true
+	} else {
+		// —— 每条记录处理 ——
+		ids = make([]any, 0, len(src))
+		for _, one := range src {
+			row, err := self._createValues(one, idCreator)
+			if err != nil {
+				return ids, err
+			}
+			if err = self._insertRow(row); err != nil {
+				return ids, err
+			}
+			if err = self._createdRow(row); err != nil {
+				return ids, err
+			}
+			ids = append(ids, row.id)
 		}
+	}
+
+	return ids, self._created(ids, hookValues)
+}
 
-		/* 解析数据 */
-		data, res_err := self._validateValues(one)
-		if res_err != nil {
-			return ids, res_err
+// _created 新记录插入后的收尾：重算相关计算字段、校验建档规则并调用插入后钩子
+func (self *TSession) _created(ids []any, hookValues []map[string]any) error {
+	// 新记录的计算字段，以及经关系路径依赖新记录的计算字段
+	if self._computeTriggered() {
+		todo, err := self._computeAffected(nil, self.Statement.Model.String(), nil, ids)
+		if err != nil {
+			return err
+		}
+		if err = self._runRecompute(todo, 0); err != nil {
+			return err
 		}
+	}
 
-		/* 应用 Sets（覆盖已有值） */
-		if !srcWasSets && len(self.Statement.Sets) > 0 {
-			rec := data.Record()
-			for k, v := range self.Statement.Sets {
-				rec.SetByField(k, v)
-			}
+	if err := self._checkRecordRules(OpCreate, ids); err != nil {
+		return err
+	}
+
+	return self._callHook(OpCreate, false, ids, hookValues)
+}
+
+// tCreateRow 一条待新建记录：_createValues 解析出的列值，插入后回填 id
+type tCreateRow struct {
+	data     *dataset.TDataSet
+	values   map[string]any   // 存储字段值（含默认值、关联外键）
+	computed map[string][]any // 由计算得出的字段值，多值时每个值插入一行
+	multiSql int              // 该记录需插入的行数
+	todo     []IField         // 插入后处理的字段（如 M2M）
+	trans    map[string]any   // 翻译字段值，插入后按 id 写入
+	id       any
+	ids      []any // 插入的所有行 id
+}
+
+// _mergeSets 解析提交的值并以 Statement.Sets 覆盖，即实际写入的记录；one 为 nil 时以 Sets 为数据源
+func (self *TSession) _mergeSets(one any) (data *dataset.TDataSet, srcWasSets bool, err error) {
+	if one == nil {
+		one = self.Statement.Sets
+		srcWasSets = true
+	}
+
+	if data, err = self._validateValues(one); err != nil {
+		return nil, false, err
+	}
+
+	/* 应用 Sets（覆盖已有值） */
+	if !srcWasSets && len(self.Statement.Sets) > 0 {
+		rec := data.Record()
+		for k, v := range self.Statement.Sets {
+			rec.SetByField(k, v)
 		}
+	}
+	return data, srcWasSets, nil
+}
 
-		/* 拆分数据 */
-		newValues, refValues, newTodo, err := self._separateValues(data, self.Statement.Fields, self.Statement.NullableFields, true, nil, hasExplicitKeys(one) || srcWasSets)
-		if err != nil {
-			return ids, err
+// _createValues 解析一条记录的待插入值：应用 Sets、拆分字段、创建关联记录并补齐默认值
+func (self *TSession) _createValues(one any, idCreator *TIdField) (*tCreateRow, error) {
+	// If src is nil but Sets are present, use Sets as the data source.
+	// If src is provided, _validateValues converts it; Sets are applied afterward.
+	if one == nil && len(self.Statement.Sets) == 0 {
+		return nil, fmt.Errorf("must submit the values for create")
+	}
+
+	/* 解析数据并应用 Sets */
+	data, srcWasSets, err := self._mergeSets(one)
+	if err != nil {
+		return nil, err
+	}
+	self._stampTenant(data.Record(), true)
+
+	/* 拆分数据 */
+	newValues, refValues, newTodo, err := self._separateValues(data, self.Statement.Fields, self.Statement.NullableFields, true, nil, hasExplicitKeys(one) || srcWasSets)
+	if err != nil {
+		return nil, err
+	}
+	trans, err := self._splitTranslations(newValues, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if idCreator != nil {
+		newValues[idCreator.Name()] = idCreator.OnCreate(&TFieldContext{
+			Session: self,
+			Model:   self.Statement.Model,
+			Dataset: data,
+			Field:   idCreator,
+		})
+	}
+
+	// 根据字段计算数据值
+	datas, multiSql, err := self._todoCompute(data, nil, newTodo)
+	if err != nil {
+		return nil, err
+	}
+
+	/* 创建关联数据 */
+	var relModel IModel
+	for tbl, rel_vals := range refValues {
+		fieldName := self.Statement.Model.Obj().GetRelationByName(tbl)
+		hasExplicitValue := false
+		if v := newValues[fieldName]; v != nil && !utils.IsBlank(v) {
+			hasExplicitValue = true
+		}
+		if v := datas[fieldName]; len(v) > 0 && !utils.IsBlank(v[0]) {
+			hasExplicitValue = true
 		}
 
-		if idCreator != nil {
-			newValues[idField] = idCreator.OnCreate(&TFieldContext{
-				Session: self,
-				Model:   self.Statement.Model,
-				Dataset: data,
-				Field:   idCreator,
-			})
+		// 跳过条件：(a) 关系表没有任何待写字段；或 (b) 用户已经显式提供了关联外键值。
+		// 任一成立都说明无需自动创建/更新关联记录。
+		if len(rel_vals) == 0 || hasExplicitValue {
+			continue
 		}
 
-		// 根据字段计算数据值
-		datas, multiSql, err := self._todoCompute(data, nil, newTodo)
+		/* 使用原事物会话进行创建或者更新关联表记录 */
+		relModel, err = self._getModel(tbl) // NOTE 这里沿用了self的Tx
 		if err != nil {
-			return ids, err
+			return nil, err
 		}
 
-		/* 创建关联数据 */
-		var relModel IModel
-		for tbl, rel_vals := range refValues {
-			fieldName := self.Statement.Model.Obj().GetRelationByName(tbl)
-			hasExplicitValue := false
-			if v := newValues[fieldName]; v != nil && !utils.IsBlank(v) {
-				hasExplicitValue = true
-			}
-			if v := datas[fieldName]; len(v) > 0 && !utils.IsBlank(v[0]) {
-				hasExplicitValue = true
+		// 获取管理表UID
+		record_id := rel_vals[relModel.IdField()]
+		if record_id == nil || utils.IsBlank(record_id) {
+			/* 复制 OnConflict */
+			tx := relModel.Tx()
+			if oc := self.Statement.OnConflict; oc != nil {
+				tx.OnConflict(oc)
 			}
 
-			// 跳过条件：(a) 关系表没有任何待写字段；或 (b) 用户已经显式提供了关联外键值。
-			// 任一成立都说明无需自动创建/更新关联记录。
-			if len(rel_vals) == 0 || hasExplicitValue {
-				continue
-			}
-
-			/* 使用原事物会话进行创建或者更新关联表记录 */
-			relModel, err = self._getModel(tbl) // NOTE 这里沿用了self的Tx
+			rids, err := tx.Create(rel_vals)
 			if err != nil {
-				return ids, err
+				return nil, err
 			}
+			record_id = rids[0]
+		} else {
+			relModel.Tx().Ids(record_id).Write(rel_vals)
+		}
 
-			// 获取管理表UID
-			record_id := rel_vals[relModel.IdField()]
-			if record_id == nil || utils.IsBlank(record_id) {
-				/* 复制 OnConflict */
-				tx := relModel.Tx()
-				if oc := self.Statement.OnConflict; oc != nil {
-					tx.OnConflict(oc)
-				}
+		newValues[self.Statement.Model.Obj().GetRelationByName(tbl)] = record_id
+	}
 
-				rids, err := tx.Create(rel_vals)
-				if err != nil {
-					return ids, err
-				}
-				record_id = rids[0]
-			} else {
-				relModel.Tx().Ids(record_id).Write(rel_vals)
-			}
+	// 被设置默认值的字段赋值给Val
+	self.Statement.Model.Obj().GetDefault().Range(func(key, value any) bool {
+		k := key.(string)
+		if newValues[k] == nil {
+			newValues[k] = value //fmt. lFld._symbol_c
+		}
+		return true
+	})
+
+	// #验证数据类型
+	//TODO 需要更准确安全
+	self.Statement.Model.GetBase()._validate(newValues)
+
+	return &tCreateRow{
+		data:     data,
+		values:   newValues,
+		computed: datas,
+		multiSql: multiSql,
+		todo:     newTodo,
+		trans:    trans,
+	}, nil
+}
 
-			newValues[self.Statement.Model.Obj().GetRelationByName(tbl)] = record_id
+// _createColumns 生成记录第 idx 行的插入列与参数，列按名称排序，便于同列记录合并插入
+func (self *TSession) _createColumns(row *tCreateRow, idx int) (fields []string, params []any, uniqueFields []string, err error) {
+	for k, v := range row.values {
+		if v == nil {
+			continue
 		}
 
-		// 被设置默认值的字段赋值给Val
-		self.Statement.Model.Obj().GetDefault().Range(func(key, value any) bool {
-			k := key.(string)
-			if newValues[k] == nil {
-				newValues[k] = value //fmt. lFld._symbol_c
+		// 避免计算字段重复
+		if _, has := row.computed[k]; has {
+			continue
+		}
+		fields = append(fields, k)
+	}
+	for k, vs := range row.computed {
+		if vs == nil {
+			continue
+		}
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	params = make([]any, len(fields))
+	for i, k := range fields {
+		if field := self.Statement.Model.GetFieldByName(k); field != nil && field.IsUnique() && !field.IsPrimaryKey() {
+			uniqueFields = append(uniqueFields, field.Name())
+			if row.multiSql > 1 {
+				return nil, nil, nil, fmt.Errorf("Create record over than exspect %d rows!", row.multiSql)
 			}
-			return true
-		})
+		}
 
-		// #验证数据类型
-		//TODO 需要更准确安全
-		self.Statement.Model.GetBase()._validate(newValues)
+		if vs, has := row.computed[k]; has {
+			if len(vs) > 1 {
+				params[i] = vs[idx]
+			} else {
+				params[i] = vs[0]
+			}
+			continue
+		}
+		params[i] = row.values[k]
+	}
+	return fields, params, uniqueFields, nil
+}
 
-		var field IField
-		var id any
-		recIds := make([]any, 0, multiSql)
-		for idx := 0; idx < multiSql; idx++ {
-			fields := make([]string, 0)
-			params := make([]any, 0)
-			uniqueFields := make([]string, 0)
-
-			// 字段,值
-			for k, v := range newValues {
-				if v == nil {
-					continue
-				}
+// _insertRow 逐行插入一条记录
+func (self *TSession) _insertRow(row *tCreateRow) error {
+	newValues := row.values
+	row.ids = make([]any, 0, row.multiSql)
+	for idx := 0; idx < row.multiSql; idx++ {
+		fields, params, uniqueFields, err := self._createColumns(row, idx)
+		if err != nil {
+			return err
+		}
 
-				// 避免计算字段重复
-				if _, has := datas[k]; has {
-					continue
-				}
+		var id any
+		if v, has := newValues[self.Statement.Model.IdField()]; has {
+			id = v
+		}
 
-				if field = self.Statement.Model.GetFieldByName(k); field != nil && field.IsUnique() && !field.IsPrimaryKey() {
-					uniqueFields = append(uniqueFields, field.Name())
-					if multiSql > 1 {
-						return ids, fmt.Errorf("Create record over than exspect %d rows!", multiSql)
+		OnConflictValues := make([]any, 0)
+		if self.Statement.OnConflict != nil {
+			if self.Statement.OnConflict.UpdateAll {
+				self.Statement.OnConflict.DoUpdates = make([]string, 0)
+				for field_name, v := range newValues {
+					/* id 字段不参与更新 */
+					if field_name == self.Statement.Model.IdField() {
+						continue
 					}
-				}
 
-				if k == idField {
-					id = v
-				}
-
-				fields = append(fields, k)
-				params = append(params, v)
-			}
-
-			for k, vs := range datas {
-				if vs == nil {
-					continue
-				}
-
-				if field = self.Statement.Model.GetFieldByName(k); field != nil && field.IsUnique() && !field.IsPrimaryKey() {
-					uniqueFields = append(uniqueFields, field.Name())
-					if multiSql > 1 {
-						return ids, fmt.Errorf("Create record over than exspect %d rows!", multiSql)
+					if utils.IndexOf(field_name, self.Statement.OnConflict.Fields...) == -1 {
+						self.Statement.OnConflict.DoUpdates = append(self.Statement.OnConflict.DoUpdates, field_name)
+						OnConflictValues = append(OnConflictValues, v)
 					}
 				}
-
-				fields = append(fields, k)
-				if len(vs) > 1 {
-					params = append(params, vs[idx])
-				} else {
-					params = append(params, vs[0])
+			} else if len(self.Statement.OnConflict.DoUpdates) > 0 {
+				for _, field_name := range self.Statement.OnConflict.DoUpdates {
+					if v, ok := newValues[field_name]; ok {
+						OnConflictValues = append(OnConflictValues, v)
+					}
 				}
+			} else {
+				self.Statement.OnConflict.DoNothing = true
 			}
+		}
 
-			OnConflictValues := make([]any, 0)
-			if self.Statement.OnConflict != nil {
-				if self.Statement.OnConflict.UpdateAll {
-					self.Statement.OnConflict.DoUpdates = make([]string, 0)
-					for field_name, v := range newValues {
-						/* id 字段不参与更新 */
-						if field_name == self.Statement.Model.IdField() {
-							continue
-						}
-
-						if utils.IndexOf(field_name, self.Statement.OnConflict.Fields...) == -1 {
-							self.Statement.OnConflict.DoUpdates = append(self.Statement.OnConflict.DoUpdates, field_name)
-							OnConflictValues = append(OnConflictValues, v)
-						}
-					}
-				} else if len(self.Statement.OnConflict.DoUpdates) > 0 {
-					for _, field_name := range self.Statement.OnConflict.DoUpdates {
-						if v, ok := newValues[field_name]; ok {
-							OnConflictValues = append(OnConflictValues, v)
-						}
-					}
-				} else {
-					self.Statement.OnConflict.DoNothing = true
-				}
+		sqlExpr, isQuery := self.Statement.generate_insert(fields, uniqueFields)
+		if isQuery {
+			ds, err := self._query(sqlExpr, append(params, OnConflictValues...)...)
+			if err != nil {
+				return err
 			}
+			self._invalidateExec(sqlExpr, false) // RETURNING 经查询执行，不经 _exec
 
-			params = append(params, OnConflictValues...)
-			sqlExpr, isQuery := self.Statement.generate_insert(fields, uniqueFields)
-			if isQuery {
-				ds, err := self._query(sqlExpr, params...)
-				if err != nil {
-					return ids, err
-				}
+			id = ds.Record().GetByIndex(0)
+		} else {
+			res, err := self._exec(sqlExpr, append(params, OnConflictValues...)...)
+			if err != nil {
+				return err
+			}
 
-				id = ds.Record().GetByIndex(0)
-			} else {
-				var res sql.Result
-				res, err = self.Exec(sqlExpr, params...)
+			// 支持递增字段返回ID
+			if len(self.Statement.Model.IdField()) > 0 {
+				id, err = res.LastInsertId()
 				if err != nil {
-					return ids, err
-				}
-
-				// 支持递增字段返回ID
-				if len(self.Statement.Model.IdField()) > 0 {
-					id, err = res.LastInsertId()
-					if err != nil {
-						return ids, err
-					}
+					return err
 				}
 			}
-
-			recIds = append(recIds, id)
 		}
 
-		/*  根据 Ids 创建 M2M 关联记录 */
-		if _, _, err = self._todoCompute(data, recIds, newTodo); err != nil {
-			return ids, err
+		row.id = id
+		row.ids = append(row.ids, id)
+		if err = self._insertedRow(id, fields, params, row.trans); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if id != nil {
-			//更新缓存
-			table_name := self.Statement.Model.Table()
-			lRec := dataset.NewRecordSet(nil, newValues)
-			self.orm.Cacher.PutById(table_name, utils.ToString(id), lRec) //for create
+// _insertedRow 写入新行的翻译值与审计记录
+func (self *TSession) _insertedRow(id any, fields []string, params []any, trans map[string]any) error {
+	if err := self._writeTranslations([]any{id}, trans); err != nil {
+		return err
+	}
 
-			// #由于表数据有所变动 所以清除所有有关于该表的SQL缓存结果
-			self.orm.Cacher.ClearByTable(table_name) //for create
+	if self._auditEnabled() {
+		entries := make([]auditEntry, 0, len(fields))
+		for _, col := range self._auditColumns(fields...) {
+			entries = append(entries, auditEntry{resId: id, field: col, new: params[utils.IndexOf(col, fields...)]})
+		}
+		if err := self._auditRecord(OpCreate, entries); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		ids = append(ids, id)
+// _createdRow 记录插入后：根据 Ids 创建 M2M 等关联记录并更新缓存
+func (self *TSession) _createdRow(row *tCreateRow) error {
+	/*  根据 Ids 创建 M2M 关联记录 */
+	if _, _, err := self._todoCompute(row.data, row.ids, row.todo); err != nil {
+		return err
 	}
 
-	return ids, nil
+	if row.id != nil {
+		//更新缓存，涉及该表的 Sql 查询结果已在执行插入时清除(见 _exec)
+		table_name := self.Statement.Model.Table()
+		lRec := dataset.NewRecordSet(nil, row.values)
+		id := utils.ToString(row.id)
+		self._invalidate(func(chr *cacher.TCacher) {
+			chr.PutById(table_name, id, lRec) //for create
+		})
+	}
+	return nil
 }
 
 // Low-level implementation of write()
@@ -460,24 +677,11 @@ func (self *TSession) _write(src any) (int64, error) {
 	if src == nil && len(self.Statement.Sets) == 0 {
 		return 0, fmt.Errorf("must submit the values for update")
 	}
-	srcWasSets := false
-	if src == nil {
-		src = self.Statement.Sets
-		srcWasSets = true
-	}
-
-	data, err := self._validateValues(src)
+	data, srcWasSets, err := self._mergeSets(src)
 	if err != nil {
 		return 0, err
 	}
-
-	/* 应用 Sets（覆盖已有值） */
-	if !srcWasSets && len(self.Statement.Sets) > 0 {
-		rec := data.Record()
-		for k, v := range self.Statement.Sets {
-			rec.SetByField(k, v)
-		}
-	}
+	self._stampTenant(data.Record(), false)
 
 	// #获取Ids
 	var ids []any
@@ -511,6 +715,10 @@ func (self *TSession) _write(src any) (int64, error) {
 		from_clause = self.Statement.qualifiedTable(model.Table())
 
 	} else if self.Statement.domain.Count() > 0 {
+		// 按条件定位待更新记录与 search 一致：只能命中可读的记录
+		if err := self._applyRecordRules(OpRead); err != nil {
+			return 0, err
+		}
 		query, err := self.Statement.where_calc(self.Statement.domain, false, nil)
 		if err != nil {
 			return 0, err
@@ -551,10 +759,26 @@ func (self *TSession) _write(src any) (int64, error) {
 		return 0, fmt.Errorf("At least have one of Where()|Domain()|Ids() condition to locate for writing update")
 	}
 
+	if err := self._checkRecordRules(OpWrite, ids); err != nil {
+		return 0, err
+	}
+
+	var hookValues []map[string]any
+	if self._hooked(OpWrite) {
+		hookValues = []map[string]any{data.Record().AsMap()}
+		if err := self._callHook(OpWrite, true, ids, hookValues); err != nil {
+			return 0, err
+		}
+	}
+
 	newVals, refVals, newTodo, err := self._separateValues(data, self.Statement.Fields, self.Statement.NullableFields, false, ids, hasExplicitKeys(src) || srcWasSets)
 	if err != nil {
 		return 0, err
 	}
+	trans, err := self._splitTranslations(newVals, false)
+	if err != nil {
+		return 0, err
+	}
 
 	// 根据字段计算数据值
 	datas, multiSql, err := self._todoCompute(data, ids, newTodo)
@@ -562,6 +786,47 @@ func (self *TSession) _write(src any) (int64, error) {
 		return 0, err
 	}
 
+	// 乐观锁：版本字段不按提交值写入，每次更新递增；提交了读取时的版本则以其为更新条件
+	versionField := model.Obj().VersionField
+	var version any
+	if versionField != "" {
+		if v := data.Record().GetByField(versionField); v != nil && !utils.IsBlank(v) {
+			version = v
+		}
+		delete(newVals, versionField)
+	}
+
+	// 写入前收集经旧关联受影响的计算字段记录
+	var computeTodo map[string]*tComputeTodo
+	var computeOn []string
+	if self._computeTriggered() {
+		for name := range data.Record().AsMap() {
+			computeOn = append(computeOn, name)
+		}
+		if computeTodo, err = self._computeAffected(nil, model.String(), computeOn, ids); err != nil {
+			return 0, err
+		}
+	}
+
+	// 更新前留存旧值供审计
+	var auditColumns []string
+	var auditOld map[string]*dataset.TRecordSet
+	if self._auditEnabled() {
+		names := make([]string, 0, len(newVals)+len(datas))
+		for k := range newVals {
+			names = append(names, k)
+		}
+		for k := range datas {
+			if _, has := newVals[k]; !has {
+				names = append(names, k)
+			}
+		}
+		auditColumns = self._auditColumns(names...)
+		if auditOld, err = self._auditSnapshot(ids, auditColumns); err != nil {
+			return 0, err
+		}
+	}
+
 	var field IField
 	var effectedRows int64 = 0
 	// newVals holds plain scalar fields; datas holds relational (m2o/o2m/m2m) fields
@@ -642,9 +907,15 @@ func (self *TSession) _write(src any) (int64, error) {
 				return 0, fmt.Errorf("must have values")
 			}
 
+			if versionField != "" {
+				sql.WriteString(fmt.Sprintf(`,%s=%s+1`, quoter(versionField), quoter(versionField)))
+			}
+
 			sql.WriteString(" WHERE ")
+			stmtIds := ids
 			if multiSql > 1 {
 				sql.WriteString(fmt.Sprintf(`%s = ?`, quoter(self.Statement.IdKey)))
+				stmtIds = []any{id}
 				params = append(params, id) // add in ids data
 
 			} else {
@@ -655,6 +926,11 @@ func (self *TSession) _write(src any) (int64, error) {
 				params = append(params, ids...) // add in ids data
 			}
 
+			if version != nil {
+				sql.WriteString(fmt.Sprintf(` AND %s = ?`, quoter(versionField)))
+				params = append(params, version)
+			}
+
 			res, err := self._exec(sql.String(), params...)
 			if err != nil {
 				return 0, err
@@ -664,6 +940,20 @@ func (self *TSession) _write(src any) (int64, error) {
 			if err != nil {
 				return 0, err
 			}
+			self._invalidateIds(model.Table(), stmtIds...)
+
+			// 版本条件未命中的记录已被他人修改
+			if version != nil && res_effect < int64(len(stmtIds)) {
+				versions := make([]any, len(stmtIds))
+				for i := range versions {
+					versions[i] = version
+				}
+				stale, err := self._staleIds(stmtIds, versions)
+				if err != nil {
+					return 0, err
+				}
+				return 0, &StaleRecordError{Model: model.String(), Ids: stale}
+			}
 
 			/*table_name := self.Statement.Model.GetName()
 			//lCacher := self.orm.Cacher.RecCacher(self.Statement.Model.GetName()) // for write
@@ -687,7 +977,95 @@ func (self *TSession) _write(src any) (int64, error) {
 		}
 	}
 
+	if len(trans) > 0 {
+		if err = self._writeTranslations(ids, trans); err != nil {
+			return 0, err
+		}
+		if effectedRows == 0 {
+			effectedRows = int64(len(ids))
+		}
+	}
+
+	if auditOld != nil {
+		entries := make([]auditEntry, 0, len(ids)*len(auditColumns))
+		for idx, id := range ids {
+			rec := auditOld[utils.ToString(id)]
+			if rec == nil {
+				continue
+			}
+			for _, col := range auditColumns {
+				var val any
+				if vs, has := datas[col]; has {
+					if len(vs) > 1 {
+						val = vs[idx]
+					} else if len(vs) == 1 {
+						val = vs[0]
+					}
+				} else {
+					val = newVals[col]
+				}
+				entries = append(entries, auditEntry{resId: id, field: col, old: rec.GetByField(col), new: val})
+			}
+		}
+		if err = self._auditRecord(OpWrite, entries); err != nil {
+			return 0, err
+		}
+	}
+
 	// 更新关联表
+	if err = self._writeInherits(ids, refVals); err != nil {
+		return 0, err
+	}
+
+	if computeOn != nil {
+		if computeTodo, err = self._computeAffected(computeTodo, model.String(), computeOn, ids); err != nil {
+			return 0, err
+		}
+		if err = self._runRecompute(computeTodo, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = self._callHook(OpWrite, false, ids, hookValues); err != nil {
+		return 0, err
+	}
+
+	return effectedRows, nil
+}
+
+// _staleIds 找出版本条件未命中的记录：versions[i] 为调用方读取 ids[i] 时的版本，
+// 本次已更新的记录版本为其加一，其余(含已删除的)即为冲突记录
+func (self *TSession) _staleIds(ids []any, versions []any) ([]any, error) {
+	model := self.Statement.Model
+	idField, versionField := model.IdField(), model.Obj().VersionField
+	quoter := self.orm.dialect.Quoter()
+	ds, err := self._query(fmt.Sprintf(`SELECT %s,%s FROM %s WHERE %s IN (%s)`,
+		quoter.Quote(idField), quoter.Quote(versionField), quoter.QuoteTable(self.Schema, model.Table()),
+		quoter.Quote(idField), idsToSqlHolder(ids...)), ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]int64, ds.Count())
+	for _, rec := range ds.Data {
+		current[utils.ToString(rec.GetByField(idField))] = utils.ToInt64(rec.GetByField(versionField))
+	}
+	stale := make([]any, 0)
+	for i, id := range ids {
+		if v, has := current[utils.ToString(id)]; !has || v != utils.ToInt64(versions[i])+1 {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) == 0 {
+		stale = ids // 版本已被并发更新推进，无法区分时全部视为冲突
+	}
+	return stale, nil
+}
+
+// _writeInherits 把 _inherits 父表字段的新值写入 ids 所关联的父表记录
+func (self *TSession) _writeInherits(ids []any, refVals map[string]map[string]any) error {
+	model := self.Statement.Model
+	var err error
 	var refIds []any
 	var refModel IModel
 	var ds *TDataset
@@ -706,7 +1084,7 @@ func (self *TSession) _write(src any) (int64, error) {
 			quoter.Quote(fieldName), quoter.QuoteTable(self.Schema, model.Table()), quoter.Quote(self.Statement.IdKey), in_vals)
 		ds, err = self._query(sql, ids...)
 		if err != nil {
-			return 0, err
+			return err
 		}
 
 		if ds.Count() != 0 {
@@ -721,13 +1099,13 @@ func (self *TSession) _write(src any) (int64, error) {
 			//# 重新写入关联数据
 			refModel, err = self._getModel(tbl) // NOTE 这里沿用了self的Tx
 			if err != nil {
-				return 0, err
+				return err
 			}
 			refModel.Tx().Ids(refIds...).Write(ref_vals) //TODO 检查是否真确使用
 		}
 	}
 
-	return effectedRows, nil
+	return nil
 }
 
 func (self *TSession) _read() (*dataset.TDataSet, error) {
@@ -741,11 +1119,30 @@ func (self *TSession) _read() (*dataset.TDataSet, error) {
 	//	self.check_access_rights("read")
 	//	fields = self._check_field_access_rights("read", fields, nil)
 
+	storeFields, relateFields, computedFields, hasScalarCompute := self._readFields()
+
+	// 获取数据库数据
+	//# fetch stored fields from the database to the cache
+	dataset, _, err := self._readFromDatabase(storeFields, relateFields)
+	if err != nil {
+		return nil, err
+	}
+
+	self._readComputed(dataset, computedFields, hasScalarCompute)
+
+	dataset.First()
+	dataset.Classic(self.IsClassic)
+	return dataset, nil
+}
+
+// _readFields 按 Statement 的字段选择把模型字段分为存储字段、关系字段与计算字段；
+// hasScalarCompute 表示存在走 getter 计算、不读数据库的非存储标量字段
+func (self *TSession) _readFields() (storeFields, relateFields, computedFields []string, hasScalarCompute bool) {
+	model := self.Statement.Model
 	//# split fields into stored and computed fields
-	storeFields := make([]string, 0, 16) // 可存于数据的字段
-	relateFields := make([]string, 0, 8)
-	computedFields := make([]string, 0, 8) // 数据库没有的字段
-	hasScalarCompute := false              // 存在「非存储标量计算字段」(走 getter，不读 DB)
+	storeFields = make([]string, 0, 16) // 可存于数据的字段
+	relateFields = make([]string, 0, 8)
+	computedFields = make([]string, 0, 8) // 数据库没有的字段
 
 	// 字段分类
 	// 验证Select * From
@@ -792,13 +1189,12 @@ func (self *TSession) _read() (*dataset.TDataSet, error) {
 		}
 	}
 
-	// 获取数据库数据
-	//# fetch stored fields from the database to the cache
-	dataset, _, err := self._readFromDatabase(storeFields, relateFields)
-	if err != nil {
-		return nil, err
-	}
+	return storeFields, relateFields, computedFields, hasScalarCompute
+}
 
+// _readComputed 为已读出的记录加载计算字段与关系字段(经典/NameGet/嵌套读取时)
+func (self *TSession) _readComputed(dataset *dataset.TDataSet, computedFields []string, hasScalarCompute bool) {
+	model := self.Statement.Model
 	// TODO 优化循环代码
 	// 处理经典字段数据
 	if (self.UseNameGet || self.IsClassic || len(self.subReads) > 0 || hasScalarCompute) && dataset.Count() > 0 {
@@ -857,10 +1253,6 @@ func (self *TSession) _read() (*dataset.TDataSet, error) {
 			}
 		}
 	}
-
-	dataset.First()
-	dataset.Classic(self.IsClassic)
-	return dataset, nil
 }
 
 /*
@@ -877,22 +1269,61 @@ func (self *TSession) _read() (*dataset.TDataSet, error) {
 // :param field_names: Model的所有字段
 // :param inherited_field_names:关联父表的所有字段
 func (self *TSession) _readFromDatabase(storeFields, relateFields []string) (res_ds *dataset.TDataSet, res_sql string, err error) {
+	res_sql, where_clause_params, _, err := self._readSql(storeFields, relateFields)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 加锁读取须经数据库取得行锁，不读缓存
+	if self.Statement.Lock == nil && self._cacheable() {
+		// 从缓存里获得数据
+		res_ds = self._getBySql(self.Statement.Model.Table(), res_sql, where_clause_params)
+		if res_ds != nil {
+			res_ds.First()
+			return res_ds, res_sql, nil
+		}
+	}
+
+	// 获得Id占位符索引
+	res_ds, err = self.Query(res_sql, where_clause_params...) //cr.execute(res_sql, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	//# 添加进入缓存
+	if self._cacheable() {
+		self._putBySql(self.Statement.Model.Table(), res_sql, where_clause_params, res_ds)
+	}
+
+	//# 必须是合法位置上
+	res_ds.First()
+	return res_ds, res_sql, nil
+}
+
+// _readSql 按 Statement 生成读取记录的 SELECT 语句(含记录规则、软删除、排序与分页)，
+// selected 为实际进入 SELECT 的字段，列名即字段名
+func (self *TSession) _readSql(storeFields, relateFields []string) (res_sql string, where_clause_params []any, selected []string, err error) {
 	var (
 		query *TQuery
 		select_clause, from_clause, where_clause,
 		order_clause, limit_clause, offset_clause, groupby_clause string
-		where_clause_params []any
 	)
 	// 生成查询条件
 	// 当指定了主键其他查询条件将失效
 	if len(self.Statement.IdParam) != 0 {
 		self.Statement.domain.Clear() // 清楚其他查询条件
 		self.Statement.domain.IN(self.Statement.Model.IdField(), self.Statement.IdParam...)
+		self.Statement.ruled = false
+	}
+
+	// 记录规则：不可读的记录直接被过滤
+	if err = self._applyRecordRules(OpRead); err != nil {
+		return "", nil, nil, err
 	}
 
 	query, err = self.Statement.where_calc(self.Statement.domain, false, nil)
 	if err != nil {
-		return nil, "", err
+		return "", nil, nil, err
 	}
 
 	/* Join fields and function clause */
@@ -941,6 +1372,11 @@ func (self *TSession) _readFromDatabase(storeFields, relateFields []string) (res
 		}
 	} else {
 		for _, f := range fields_pre {
+			if f.Translate() {
+				// 翻译字段按会话语言取值，需经 inherits_join_calc 生成表达式
+				qual_names = append(qual_names, query.qualify(f, self.Statement.Model))
+				continue
+			}
 			qual_names = append(qual_names, query.qualify(f, nil))
 		}
 	}
@@ -948,6 +1384,30 @@ func (self *TSession) _readFromDatabase(storeFields, relateFields []string) (res
 	//} else {
 	//	qual_names = self.Statement.generate_fields()
 	//}
+
+	// 键集分页：排序与定位条件取自排序项，末行的排序值须一并读出以生成下一页游标
+	var keyset_cond string
+	var keyset_params []any
+	keyset := self.Statement.keyset
+	if keyset != nil {
+		order_clause, keyset_cond, keyset_params, err = self._keysetClause(query, keyset)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		for _, term := range keyset.terms {
+			found := false
+			for _, f := range fields_pre {
+				if f.Name() == term.field {
+					found = true
+					break
+				}
+			}
+			if !found {
+				qual_names = append(qual_names, fmt.Sprintf(`%s AS %s`, term.expr, self.orm.dialect.Quoter().Quote(term.field)))
+				selected = append(selected, term.field)
+			}
+		}
+	}
 	select_clause = strings.Join(append(qual_names, self.Statement.FuncsClause...), ",")
 
 	// # determine the actual query to execute
@@ -973,12 +1433,23 @@ func (self *TSession) _readFromDatabase(storeFields, relateFields []string) (res
 		}
 	}
 
+	if keyset_cond != "" {
+		if where_clause == "" {
+			where_clause = keyset_cond
+		} else {
+			where_clause = where_clause + " AND " + keyset_cond
+		}
+		where_clause_params = append(where_clause_params, keyset_params...)
+	}
+
 	if where_clause != "" {
 		where_clause = "WHERE " + where_clause
 	}
 
 	// orderby clause
-	order_clause = self.Statement.generate_order_by(query, nil) // TODO 未完成
+	if keyset == nil {
+		order_clause = self.Statement.generate_order_by(query, nil) // TODO 未完成
+	}
 
 	// GroupBy clause — 每个字段必须命中模型字段并经标识符校验/引用，防止注入
 	if len(self.Statement.GroupByClause) > 0 {
@@ -1010,9 +1481,12 @@ func (self *TSession) _readFromDatabase(storeFields, relateFields []string) (res
 		}
 		limit_clause = "LIMIT " + utils.ToString(limit)
 	}
+	if keyset != nil {
+		keyset.limit = limit
+	}
 
-	// offset clause
-	if self.Statement.OffsetClause > 0 {
+	// offset clause 键集分页由定位条件翻页，不再叠加偏移
+	if self.Statement.OffsetClause > 0 && keyset == nil {
 		offset_clause = "OFFSET " + utils.ToString(self.Statement.OffsetClause)
 	}
 
@@ -1028,25 +1502,22 @@ func (self *TSession) _readFromDatabase(storeFields, relateFields []string) (res
 		offset_clause,
 	)
 
-	// 从缓存里获得数据
-	res_ds = self.orm.Cacher.GetBySql(self.Statement.Model.Table(), res_sql, where_clause_params)
-	if res_ds != nil {
-		res_ds.First()
-		return res_ds, res_sql, nil
+	// 行锁：联表查询未指定锁定的表时只锁模型本表，避免锁到外连接的可空侧
+	if lock := self.Statement.Lock; lock != nil {
+		l := *lock
+		if len(l.Of) == 0 && len(query.tables) > 1 {
+			l.Of = []string{self.Statement.Model.Table()}
+		}
+		if res_sql, err = self.orm.dialect.LockSql(res_sql, &l); err != nil {
+			return "", nil, nil, err
+		}
 	}
 
-	// 获得Id占位符索引
-	res_ds, err = self.Query(res_sql, where_clause_params...) //cr.execute(res_sql, params)
-	if err != nil {
-		return nil, "", err
+	names := make([]string, 0, len(fields_pre)+len(selected))
+	for _, f := range fields_pre {
+		names = append(names, f.Name())
 	}
-
-	//# 添加进入缓存
-	self.orm.Cacher.PutBySql(self.Statement.Model.Table(), res_sql, where_clause_params, res_ds)
-
-	//# 必须是合法位置上
-	res_ds.First()
-	return res_ds, res_sql, nil
+	return res_sql, where_clause_params, append(names, selected...), nil
 }
 
 // TODO
@@ -207,12 +207,44 @@ func (self *TSession) NoCascade() *TSession {
 	return self
 }
 
-// ForUpdate Set Read/Write locking for UPDATE
+// ForUpdate 以排他锁(FOR UPDATE)读取记录，直至事务结束
 func (self *TSession) ForUpdate() *TSession {
-	self.Statement.IsForUpdate = true
+	self._lockOpts().Strength = LockUpdate
 	return self
 }
 
+// ForShare 以共享锁(FOR SHARE)读取记录：阻止他人修改，但允许他人同样加共享锁
+func (self *TSession) ForShare() *TSession {
+	self._lockOpts().Strength = LockShare
+	return self
+}
+
+// SkipLocked 跳过已被其他事务锁定的记录；未指定锁模式时为 FOR UPDATE
+func (self *TSession) SkipLocked() *TSession {
+	self._lockOpts().SkipLocked = true
+	return self
+}
+
+// NoWait 记录已被其他事务锁定时立即报错；未指定锁模式时为 FOR UPDATE
+func (self *TSession) NoWait() *TSession {
+	self._lockOpts().NoWait = true
+	return self
+}
+
+// Lock 以指定锁模式读取记录，见 Lock
+func (self *TSession) Lock(lock *Lock) *TSession {
+	self.Statement.Lock = lock
+	return self
+}
+
+// _lockOpts 取当前锁设置，未设置时新建 FOR UPDATE
+func (self *TSession) _lockOpts() *Lock {
+	if self.Statement.Lock == nil {
+		self.Statement.Lock = &Lock{Strength: LockUpdate}
+	}
+	return self.Statement.Lock
+}
+
 func (self *TSession) OnConflict(conflict *OnConflict) *TSession {
 	self.Statement.OnConflict = conflict
 	return self
@@ -0,0 +1,230 @@
+package orm
+
+import (
+	"fmt"
+	"iter"
+	"sync/atomic"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/orm/core"
+)
+
+// cursorSeq Postgres 服务端游标名序号
+var cursorSeq atomic.Int64
+
+// ReadIter 流式读取 Statement 条件下的记录，内存占用取决于 batchSize 而非结果集大小。
+// 记录按批(默认 DefaultBatchSize 条)扫描，每批照常加载计算字段与关系字段后逐条交出；
+// 未调用 Limit 时不分页。Postgres 上使用服务端游标，非事务会话在遍历期间自开事务。
+// 条件在开始遍历时生效，遍历结束后重置：
+//
+//	for rec, err := range session.Model("res.partner").Domain(...).ReadIter(1000) {
+//		if err != nil { ... }
+//	}
+func (self *TSession) ReadIter(batchSize ...int) iter.Seq2[*dataset.TRecordSet, error] {
+	return func(yield func(*dataset.TRecordSet, error) bool) {
+		model := self.Statement.Model
+		if model == nil {
+			yield(nil, ErrTableNotFound)
+			return
+		}
+		self.Op = OpRead
+		if _, err := model.BeforeSession(self); err != nil {
+			yield(nil, err)
+			return
+		}
+		defer func() {
+			model.AfterSession(self)
+			self._resetStatement()
+		}()
+
+		if self.IsAutoClose {
+			defer self.Close()
+		}
+
+		if self.IsDeprecated {
+			yield(nil, ErrInvalidSession)
+			return
+		}
+		// 流式读取不跨分片，须先以 Shard 指定分片
+		if self._shardRouted() {
+			yield(nil, ErrShardRequired)
+			return
+		}
+
+		if self.Statement.LimitClause == 0 {
+			self.Statement.LimitClause = -1
+		}
+		storeFields, relateFields, computedFields, hasScalarCompute := self._readFields()
+		sql, params, _, err := self._readSql(storeFields, relateFields)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer self._onReplica()()
+		stopped := false
+		err = self._streamRows(sql, params, batchSize, func(ds *dataset.TDataSet) bool {
+			self._readComputed(ds, computedFields, hasScalarCompute)
+			ds.Classic(self.IsClassic)
+			for _, rec := range ds.Data {
+				if !yield(rec, nil) {
+					stopped = true
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// SearchIter 流式返回满足 Statement 条件的记录 id，不读取其他字段
+func (self *TSession) SearchIter(batchSize ...int) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		model := self.Statement.Model
+		if model == nil {
+			yield(nil, ErrTableNotFound)
+			return
+		}
+		self.Op = OpRead
+		if _, err := model.BeforeSession(self); err != nil {
+			yield(nil, err)
+			return
+		}
+		defer func() {
+			model.AfterSession(self)
+			self._resetStatement()
+		}()
+
+		if self.IsAutoClose {
+			defer self.Close()
+		}
+
+		if self.IsDeprecated {
+			yield(nil, ErrInvalidSession)
+			return
+		}
+		// 流式读取不跨分片，须先以 Shard 指定分片
+		if self._shardRouted() {
+			yield(nil, ErrShardRequired)
+			return
+		}
+
+		if err := self.CheckAccessRights(OpRead); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		if self.Statement.LimitClause == 0 {
+			self.Statement.LimitClause = -1
+		}
+		idField := model.IdField()
+		sql, params, _, err := self._readSql([]string{idField}, nil)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer self._onReplica()()
+		stopped := false
+		err = self._streamRows(sql, params, batchSize, func(ds *dataset.TDataSet) bool {
+			for _, rec := range ds.Data {
+				if !yield(rec.GetByField(idField), nil) {
+					stopped = true
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// _streamRows 执行查询并按批扫描为数据集交给 fn，fn 返回 false 时停止。
+// Postgres 以服务端游标(DECLARE/FETCH)逐批取数，其余数据库依赖驱动逐行读取结果集
+func (self *TSession) _streamRows(sql string, params []any, batchSize []int, fn func(*dataset.TDataSet) bool) (err error) {
+	batch := DefaultBatchSize
+	if len(batchSize) > 0 && batchSize[0] > 0 {
+		batch = batchSize[0]
+	}
+
+	if self.orm.dialect.DBType() != POSTGRES {
+		rows, err := self._queryRows(sql, params...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		_, _, err = self._scanBatches(rows, batch, fn)
+		return err
+	}
+
+	// 服务端游标只在事务内有效
+	if self.IsAutoCommit {
+		if err = self.Begin(); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				self.Rollback(err)
+				return
+			}
+			err = self.Commit()
+		}()
+	}
+
+	cursor := fmt.Sprintf("orm_cursor_%d", cursorSeq.Add(1))
+	if _, err = self._computeSession()._exec(fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", cursor, sql), params...); err != nil {
+		return err
+	}
+	defer self._computeSession()._exec("CLOSE " + cursor)
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", batch, cursor)
+	for {
+		rows, err := self._queryRows(fetch)
+		if err != nil {
+			return err
+		}
+		count, stopped, err := self._scanBatches(rows, batch, fn)
+		rows.Close()
+		if err != nil || stopped || count < batch {
+			return err
+		}
+	}
+}
+
+// _scanBatches 把 rows 每 batch 条扫描为一个数据集交给 fn；stopped 表示 fn 要求停止
+func (self *TSession) _scanBatches(rows *core.Rows, batch int, fn func(*dataset.TDataSet) bool) (count int, stopped bool, err error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, false, err
+	}
+
+	vals := make([]any, len(cols))
+	ds := self._newScanDataset()
+	for rows.Next() {
+		if err = self._scanRecord(ds, rows, cols, vals); err != nil {
+			return count, false, err
+		}
+		count++
+
+		if ds.Count() >= batch {
+			if !fn(ds) {
+				return count, true, nil
+			}
+			ds = self._newScanDataset()
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return count, false, err
+	}
+
+	if ds.Count() > 0 && !fn(ds) {
+		return count, true, nil
+	}
+	return count, false, nil
+}
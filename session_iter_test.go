@@ -0,0 +1,124 @@
+package orm
+
+import (
+	"fmt"
+	"testing"
+)
+
+type IterHookedItem struct {
+	TModel `table:"name('iter_hooked_item')"`
+	Id     int64  `field:"pk autoincr"`
+	Name   string `field:"varchar() size(64)"`
+}
+
+// iterSessionCalls 记录会话钩子调用；测试串行执行
+var iterSessionCalls []string
+
+func (self *IterHookedItem) BeforeSession(session *TSession) (*TSession, error) {
+	iterSessionCalls = append(iterSessionCalls, "before")
+	return session, nil
+}
+
+func (self *IterHookedItem) AfterSession(session *TSession) (*TSession, error) {
+	iterSessionCalls = append(iterSessionCalls, "after")
+	return session, nil
+}
+
+func TestSession_SearchIterRunsSessionHooks(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(IterHookedItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	ids, err := o.NewSession().Model("iter_hooked_item").Create(map[string]any{"name": "a"}, map[string]any{"name": "b"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	iterSessionCalls = nil
+	count := 0
+	for _, err := range o.NewSession().Model("iter_hooked_item").SearchIter(1) {
+		if err != nil {
+			t.Fatalf("SearchIter: %v", err)
+		}
+		count++
+	}
+	if count != len(ids) {
+		t.Fatalf("SearchIter should stream %d ids, got %d", len(ids), count)
+	}
+	if fmt.Sprint(iterSessionCalls) != "[before after]" {
+		t.Fatalf("SearchIter should wrap the stream in session hooks like ReadIter, got %v", iterSessionCalls)
+	}
+}
+
+func TestSession_ReadIterStreamsInBatches(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(RelatedCountry), new(RelatedPartner), new(RelatedOrder)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	countries, err := o.NewSession().Model("rl_country").Create(map[string]any{"name": "France"})
+	if err != nil {
+		t.Fatalf("Create country: %v", err)
+	}
+	partners, err := o.NewSession().Model("rl_partner").Create(map[string]any{"name": "ACME", "country_id": countries[0]})
+	if err != nil {
+		t.Fatalf("Create partner: %v", err)
+	}
+	values := make([]any, 0, 7)
+	for i := 0; i < 7; i++ {
+		values = append(values, map[string]any{"partner_id": partners[0]})
+	}
+	orders, err := o.NewSession().Model("rl_order").Create(values...)
+	if err != nil {
+		t.Fatalf("Create orders: %v", err)
+	}
+
+	// 按批扫描，每条记录照常带上计算字段
+	count := 0
+	for rec, err := range o.NewSession().Model("rl_order").OrderBy("id").ReadIter(3) {
+		if err != nil {
+			t.Fatalf("ReadIter: %v", err)
+		}
+		if rec.GetByField("id") != orders[count] {
+			t.Fatalf("record %d should be order %v, got %v", count, orders[count], rec.GetByField("id"))
+		}
+		if name := fmt.Sprint(rec.GetByField("country_name")); name != "France" {
+			t.Fatalf("computed field should be loaded per batch, got %q", name)
+		}
+		count++
+	}
+	if count != len(orders) {
+		t.Fatalf("ReadIter should stream all %d records, got %d", len(orders), count)
+	}
+
+	// 条件与 Limit 照常生效，提前结束遍历不报错
+	count = 0
+	for _, err := range o.NewSession().Model("rl_order").Domain(`[('id', '>', ?)]`, orders[1]).Limit(4).ReadIter(2) {
+		if err != nil {
+			t.Fatalf("ReadIter: %v", err)
+		}
+		count++
+	}
+	if count != 4 {
+		t.Fatalf("ReadIter should respect domain and limit, got %d", count)
+	}
+	count = 0
+	for range o.NewSession().Model("rl_order").ReadIter(2) {
+		if count++; count == 3 {
+			break
+		}
+	}
+
+	ids := make([]any, 0)
+	for id, err := range o.NewSession().Model("rl_order").OrderBy("id").SearchIter(4) {
+		if err != nil {
+			t.Fatalf("SearchIter: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if fmt.Sprint(ids) != fmt.Sprint(orders) {
+		t.Fatalf("SearchIter should stream all ids, got %v want %v", ids, orders)
+	}
+}
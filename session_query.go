@@ -2,12 +2,16 @@ package orm
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/volts-dev/dataset"
 	"github.com/volts-dev/orm/core"
+	ormerr "github.com/volts-dev/orm/errors"
 )
 
 // search and return the id list only
@@ -24,15 +28,25 @@ func (self *TSession) Search() ([]any, int64, error) {
 		}
 	}()
 
+	if err := self.CheckAccessRights(OpRead); err != nil {
+		return nil, 0, err
+	}
+
+	defer self._onReplica()()
+	if self._shardRouted() {
+		return self._searchSharded()
+	}
 	return self._search("", nil)
 }
 
 // Query a raw sql and return records as dataset
+// 非事务中的 SELECT 可由只读副本执行
 func (self *TSession) Query(sql string, paramStr ...any) (*dataset.TDataSet, error) {
 	if self.IsAutoClose {
 		defer self.Close()
 	}
 
+	defer self._onReplica()()
 	return self._query(sql, paramStr...)
 }
 
@@ -42,7 +56,11 @@ func (self *TSession) Exec(sql_str string, args ...any) (sql.Result, error) {
 		defer self.Close()
 	}
 
-	return self._exec(sql_str, args...)
+	res, err := self._exec(sql_str, args...)
+	if err == nil {
+		self._invalidateExec(sql_str, true)
+	}
+	return res, err
 }
 
 func (self *TSession) Count() (int, error) {
@@ -65,9 +83,20 @@ func (self *TSession) Count() (int, error) {
 		return -1, ErrInvalidSession
 	}
 
+	if err := self.CheckAccessRights(OpCount); err != nil {
+		return 0, err
+	}
+
 	self.Statement.IsCount = true
 
-	_, count, err := self._search("", nil)
+	defer self._onReplica()()
+	var count int64
+	var err error
+	if self._shardRouted() {
+		_, count, err = self._searchSharded()
+	} else {
+		_, count, err = self._search("", nil)
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -92,6 +121,10 @@ func (self *TSession) Sum(fieldName string) (float64, error) {
 		defer self.Close()
 	}
 
+	if err := self.CheckAccessRights(OpSum); err != nil {
+		return 0, err
+	}
+
 	// 校验字段并引用，防止注入
 	if self.Statement.Model.GetFieldByName(fieldName) == nil {
 		return 0, fmt.Errorf("Sum: field %s not found on model %s", fieldName, self.Statement.Model.String())
@@ -101,6 +134,19 @@ func (self *TSession) Sum(fieldName string) (float64, error) {
 		return 0, fmt.Errorf("Sum: invalid field %s: %w", fieldName, err)
 	}
 
+	defer self._onReplica()()
+	if self._shardRouted() {
+		return self._sumSharded(col)
+	}
+	return self._sum(col)
+}
+
+// _sum 按 Statement 的条件对已引用的列 col 求和
+func (self *TSession) _sum(col string) (float64, error) {
+	if err := self._applyRecordRules(OpRead); err != nil {
+		return 0, err
+	}
+
 	// 复用 where_calc 生成 from/where（与 Count 路径一致），构造真实的 SUM 查询
 	query, err := self.Statement.where_calc(self.Statement.domain, false, make(map[string]any))
 	if err != nil {
@@ -155,6 +201,10 @@ func (self *TSession) _search(access_rights_uid string, context map[string]any)
 	//	fields_str = `*`
 	//}
 
+	if err = self._applyRecordRules(OpRead); err != nil {
+		return nil, 0, err
+	}
+
 	query, err = self.Statement.where_calc(self.Statement.domain, false, context)
 	if err != nil {
 		return nil, 0, err
@@ -176,7 +226,10 @@ func (self *TSession) _search(access_rights_uid string, context map[string]any)
 		// Ignore order, limit and offset when just counting, they don't make sense and could
 		// hurt performance
 		query_str = `SELECT count(1) AS count FROM ` + from_clause + where_clause
-		res_ds := self.orm.Cacher.GetBySql(table_name, query_str, where_clause_params)
+		var res_ds *dataset.TDataSet
+		if self._cacheable() {
+			res_ds = self._getBySql(table_name, query_str, where_clause_params)
+		}
 		if res_ds == nil {
 			lRes, err := self._query(query_str, where_clause_params...)
 			if err != nil {
@@ -185,7 +238,9 @@ func (self *TSession) _search(access_rights_uid string, context map[string]any)
 			//res_ids = []interface{}{lRes.FieldByName("count").AsInterface()}
 			count = lRes.FieldByName("count").AsInteger()
 			// #存入缓存
-			self.orm.Cacher.PutBySql(table_name, query_str, where_clause_params, lRes)
+			if self._cacheable() {
+				self._putBySql(table_name, query_str, where_clause_params, lRes)
+			}
 		} else {
 			//res_ids = res_ds.Keys(self.Statement.IdKey)
 			count = res_ds.FieldByName("count").AsInteger()
@@ -208,15 +263,29 @@ func (self *TSession) _search(access_rights_uid string, context map[string]any)
 	quoter := self.orm.dialect.Quoter()
 	query_str = fmt.Sprintf(`SELECT %s.%s FROM `, quoter.Quote(self.Statement.Model.Table()), quoter.Quote(self.Statement.IdKey)) + from_clause + where_clause + order_by + limit_str + offset_str
 
-	// #调用缓存
-	res_ds := self.orm.Cacher.GetBySql(table_name, query_str, where_clause_params)
+	// 加锁读取须经数据库取得行锁，不读缓存
+	var res_ds *dataset.TDataSet
+	if lock := self.Statement.Lock; lock != nil {
+		l := *lock
+		if len(l.Of) == 0 && len(query.tables) > 1 {
+			l.Of = []string{table_name}
+		}
+		if query_str, err = self.orm.dialect.LockSql(query_str, &l); err != nil {
+			return nil, 0, err
+		}
+	} else if self._cacheable() {
+		// #调用缓存
+		res_ds = self._getBySql(table_name, query_str, where_clause_params)
+	}
 	if res_ds == nil {
 		res, err := self._query(query_str, where_clause_params...)
 		if err != nil {
 			return nil, 0, err
 		}
 		res_ids = res.Keys(self.Statement.IdKey)
-		self.orm.Cacher.PutBySql(table_name, query_str, where_clause_params, res)
+		if self._cacheable() {
+			self._putBySql(table_name, query_str, where_clause_params, res)
+		}
 	} else {
 		res_ids = res_ds.Keys(self.Statement.IdKey)
 	}
@@ -230,20 +299,41 @@ func (self *TSession) _query(sql string, paramStr ...any) (*dataset.TDataSet, er
 		sql = filter.Do(sql, self.orm.dialect, self.Statement.Model)
 	}
 
-	return self.orm._logQuerySql(sql, paramStr, func() (*dataset.TDataSet, error) {
+	ds, err := self.orm._logQuerySql(sql, paramStr, func() (*dataset.TDataSet, error) {
 		if self.IsAutoCommit {
 			return self._queryWithOrg(sql, paramStr...)
 		}
 		return self._queryWithTx(sql, paramStr...)
 	})
+	if err == nil && firstKeyword(sql) != "SELECT" {
+		self.lastWrite = time.Now() // INSERT ... RETURNING 等经查询执行的写入
+	}
+	return ds, err
 }
 
 func (self *TSession) _queryWithOrg(sql_str string, args ...any) (*dataset.TDataSet, error) {
+	// 可由副本执行的读取先走副本，副本连接失败时标记不可用并改走主库
+	if node := self._replica(sql_str); node != nil {
+		ds, err := self._queryWithDB(node.db, sql_str, args...)
+		if !isConnError(err) {
+			return ds, err
+		}
+		self.orm.replicas.markDown(node)
+	}
+	return self._queryWithDB(self.db, sql_str, args...)
+}
+
+// isConnError 是否为连接失败，副本上的此类错误改走主库
+func isConnError(err error) bool {
+	return errors.Is(err, ormerr.ErrConnection) || errors.Is(err, driver.ErrBadConn)
+}
+
+func (self *TSession) _queryWithDB(db *core.DB, sql_str string, args ...any) (*dataset.TDataSet, error) {
 	var rows *core.Rows
 	var err error
 
 	if self.Prepared {
-		stmt, err := self._doPrepare(sql_str)
+		stmt, err := db.PrepareContext(self.context, sql_str)
 		if err != nil {
 			return nil, err
 		}
@@ -254,7 +344,7 @@ func (self *TSession) _queryWithOrg(sql_str string, args ...any) (*dataset.TData
 			return nil, self.orm.dialect.MapError(err)
 		}
 	} else {
-		rows, err = self.db.QueryContext(self.context, sql_str, args...)
+		rows, err = db.QueryContext(self.context, sql_str, args...)
 		if err != nil {
 			return nil, self.orm.dialect.MapError(err)
 		}
@@ -263,6 +353,42 @@ func (self *TSession) _queryWithOrg(sql_str string, args ...any) (*dataset.TData
 	return self._scanRows(rows)
 }
 
+// _queryRows 执行查询并返回游标，由调用方逐行扫描并负责关闭。
+// 不重置 Statement：逐行扫描时仍需按 Statement.Model 转换字段值
+func (self *TSession) _queryRows(sql string, args ...any) (*core.Rows, error) {
+	for _, filter := range self.orm.dialect.Fmter() {
+		sql = filter.Do(sql, self.orm.dialect, self.Statement.Model)
+	}
+	if self.orm.config.ShowSql {
+		log.Infof("[SQL] %s [args] %v", sql, args)
+	}
+
+	if !self.IsAutoCommit {
+		rows, err := self.tx.QueryContext(self.context, sql, args...)
+		if err != nil {
+			return nil, self.orm.dialect.MapError(err)
+		}
+		return rows, nil
+	}
+
+	// 与 _queryWithOrg 相同：副本连接失败时标记不可用并改走主库
+	if node := self._replica(sql); node != nil {
+		rows, err := node.db.QueryContext(self.context, sql, args...)
+		if err == nil {
+			return rows, nil
+		}
+		if err = self.orm.dialect.MapError(err); !isConnError(err) {
+			return nil, err
+		}
+		self.orm.replicas.markDown(node)
+	}
+	rows, err := self.db.QueryContext(self.context, sql, args...)
+	if err != nil {
+		return nil, self.orm.dialect.MapError(err)
+	}
+	return rows, nil
+}
+
 func (self *TSession) _queryWithTx(query string, params ...any) (*dataset.TDataSet, error) {
 	rows, err := self.tx.QueryContext(self.context, query, params...)
 	if err != nil {
@@ -312,8 +438,12 @@ func (self *TSession) _exec(sql_str string, args ...any) (sql.Result, error) {
 		return self._execWithTx(sql_str, args...)
 	})
 
-	if err == nil && ddl {
-		self.orm.metaEpoch.Add(1)
+	if err == nil {
+		self.lastWrite = time.Now()
+		if ddl {
+			self.orm.metaEpoch.Add(1)
+		}
+		self._invalidateExec(sql_str, false)
 	}
 
 	return res, err
@@ -322,13 +452,21 @@ func (self *TSession) _exec(sql_str string, args ...any) (sql.Result, error) {
 // isDDL 粗判一条语句是否会改动库结构(据以让 DBMetas 缓存失效)。只看首个
 // 关键字,宁多勿漏——多一次内省是纯性能损耗,漏一次会返回过期结构。
 func isDDL(sql_str string) bool {
+	switch firstKeyword(sql_str) {
+	case "CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "COMMENT":
+		return true
+	}
+	return false
+}
+
+// firstKeyword 取语句的首个关键字(大写)，跳过行首的 -- 注释与空白
+func firstKeyword(sql_str string) string {
 	s := strings.TrimSpace(sql_str)
-	// 跳过行首的 -- 注释与空白,取第一个真正的关键字
 	for strings.HasPrefix(s, "--") {
 		if i := strings.IndexByte(s, '\n'); i >= 0 {
 			s = strings.TrimSpace(s[i+1:])
 		} else {
-			return false
+			return ""
 		}
 	}
 	end := len(s)
@@ -338,11 +476,7 @@ func isDDL(sql_str string) bool {
 			break
 		}
 	}
-	switch strings.ToUpper(s[:end]) {
-	case "CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "COMMENT":
-		return true
-	}
-	return false
+	return strings.ToUpper(s[:end])
 }
 
 // Execute sql
@@ -383,11 +517,7 @@ func (self *TSession) _doPrepare(sql string) (*core.Stmt, error) {
 // scan data to a slice's pointer, slice's length should equal to columns' number
 func (self *TSession) _scanRows(rows *core.Rows) (*TDataset, error) {
 	// #无论如何都会返回一个Dataset
-	res_dataset := dataset.NewDataSet()
-	// #提供必要的IdKey/
-	if self.Statement.IdKey != "" {
-		res_dataset.KeyField = self.Statement.IdKey //设置主键
-	}
+	res_dataset := self._newScanDataset()
 
 	if rows != nil {
 		defer rows.Close() // 确保在函数退出时关闭rows
@@ -397,95 +527,111 @@ func (self *TSession) _scanRows(rows *core.Rows) (*TDataset, error) {
 			return nil, err
 		}
 
-		length := len(cols)
-		vals := make([]any, length)
-
-		var value any
-		var field IField
-		hasModel := self.Statement.Model != nil
+		vals := make([]any, len(cols))
 		for rows.Next() {
-			// TODO 优化不使用MAP
-			rec := dataset.NewRecordSet()
-			//rec.Fields(cols...)
-
-			// 创建数据容器
-			for idx := range cols {
-				vals[idx] = reflect.New(ITF_TYPE).Interface()
-			}
-
-			// 采集数据
-			err = rows.Scan(vals...)
-			if err != nil {
+			if err = self._scanRecord(res_dataset, rows, cols, vals); err != nil {
 				return nil, err
 			}
+		}
+	}
 
-			// 存储到数据集
-			for idx, name := range cols {
-				// typeName/field 必须**每列重置**：早先它们声明在列循环之外，没走到
-				// 赋值分支的列(field==nil 的函数列如 Count、以及 !hasModel 分支)会
-				// 沿用上一列的值，把上一列的格式化器套到本列上——例如紧跟在 id 列
-				// 之后的 Count 列会被当成大数列转成字符串。
-				typeName := ""
-				field = nil
-				// bigNumAsString 记录「Varchar 这个输出类型是因 BigNumberToString 才
-				// 选上的」，与「本来就是字符列」区分开：前者的零值语义只对外键成立。
-				bigNumAsString := false
-				// !NOTE! 转换数据类型输出
-				if hasModel { // TODO exec,query 的SQL不包含Model
-					field = self.Statement.Model.GetFieldByName(name)
-					if field != nil {
-						value = field.onConvertToRead(self, cols, vals, idx)
-						typeName = field.OutputAs()
-
-						// as tag 指定输出格式
-						if typeName == "" && self.orm.config.BigNumberToString && isBigNumberField(field) {
-							typeName = Varchar
-							bigNumAsString = true
-						}
-					} else {
-						value = nil // 初始化
-						// 处理函数字段 Count 等
-						for _, funcName := range self.Statement.FuncsClause {
-							if strings.HasPrefix(funcName, name) { // TODO 这里需要更高效的判断
-								value = *vals[idx].(*any)
-							}
-						}
-
-						if value == nil {
-							value = *vals[idx].(*any)
-						}
-
-						// #兼容没有使用 as tag 的大数转换为字符串
-						if _, ok := value.(int64); ok && self.orm.config.BigNumberToString {
-							typeName = Varchar
-							bigNumAsString = true
-						}
-					}
+	res_dataset.First()
+	return res_dataset, nil
+}
+
+// _newScanDataset 新建承载查询结果的数据集
+func (self *TSession) _newScanDataset() *TDataset {
+	res_dataset := dataset.NewDataSet()
+	// #提供必要的IdKey/
+	if self.Statement.IdKey != "" {
+		res_dataset.KeyField = self.Statement.IdKey //设置主键
+	}
+	return res_dataset
+}
+
+// _scanRecord 扫描 rows 当前行，按模型字段转换后追加到 res_dataset；vals 为可复用的列容器
+func (self *TSession) _scanRecord(res_dataset *TDataset, rows *core.Rows, cols []string, vals []any) error {
+	var value any
+	var field IField
+	hasModel := self.Statement.Model != nil
+	// TODO 优化不使用MAP
+	rec := dataset.NewRecordSet()
+	//rec.Fields(cols...)
+
+	// 创建数据容器
+	for idx := range cols {
+		vals[idx] = reflect.New(ITF_TYPE).Interface()
+	}
 
-					if typeName != "" {
-						if bigNumAsString {
-							// 只有关系字段(外键)的 0 才归空串=「没有关联」；普通 int64
-							// 数据列的 0 是合法值，必须原样输出 "0"。field==nil 的函数
-							// 列(Count 等)同理不归空。详见 converterBigNumberToString。
-							res_dataset.SetFieldFormater(name, converterBigNumberToString(field != nil && field.IsRelated()))
-						} else {
-							res_dataset.SetFieldFormater(name, converter(typeName))
-						}
+	// 采集数据
+	err := rows.Scan(vals...)
+	if err != nil {
+		return err
+	}
+
+	// 存储到数据集
+	for idx, name := range cols {
+		// typeName/field 必须**每列重置**：早先它们声明在列循环之外，没走到
+		// 赋值分支的列(field==nil 的函数列如 Count、以及 !hasModel 分支)会
+		// 沿用上一列的值，把上一列的格式化器套到本列上——例如紧跟在 id 列
+		// 之后的 Count 列会被当成大数列转成字符串。
+		typeName := ""
+		field = nil
+		// bigNumAsString 记录「Varchar 这个输出类型是因 BigNumberToString 才
+		// 选上的」，与「本来就是字符列」区分开：前者的零值语义只对外键成立。
+		bigNumAsString := false
+		// !NOTE! 转换数据类型输出
+		if hasModel { // TODO exec,query 的SQL不包含Model
+			field = self.Statement.Model.GetFieldByName(name)
+			if field != nil {
+				value = field.onConvertToRead(self, cols, vals, idx)
+				typeName = field.OutputAs()
+
+				// as tag 指定输出格式
+				if typeName == "" && self.orm.config.BigNumberToString && isBigNumberField(field) {
+					typeName = Varchar
+					bigNumAsString = true
+				}
+			} else {
+				value = nil // 初始化
+				// 处理函数字段 Count 等
+				for _, funcName := range self.Statement.FuncsClause {
+					if strings.HasPrefix(funcName, name) { // TODO 这里需要更高效的判断
+						value = *vals[idx].(*any)
 					}
+				}
 
-				} else {
+				if value == nil {
 					value = *vals[idx].(*any)
 				}
 
-				if !rec.SetByField(name, value, false) {
-					return nil, fmt.Errorf("add %s value to recordset fail.", name)
+				// #兼容没有使用 as tag 的大数转换为字符串
+				if _, ok := value.(int64); ok && self.orm.config.BigNumberToString {
+					typeName = Varchar
+					bigNumAsString = true
 				}
 			}
 
-			res_dataset.AppendRecord(rec)
+			if typeName != "" {
+				if bigNumAsString {
+					// 只有关系字段(外键)的 0 才归空串=「没有关联」；普通 int64
+					// 数据列的 0 是合法值，必须原样输出 "0"。field==nil 的函数
+					// 列(Count 等)同理不归空。详见 converterBigNumberToString。
+					res_dataset.SetFieldFormater(name, converterBigNumberToString(field != nil && field.IsRelated()))
+				} else {
+					res_dataset.SetFieldFormater(name, converter(typeName))
+				}
+			}
+
+		} else {
+			value = *vals[idx].(*any)
+		}
+
+		if !rec.SetByField(name, value, false) {
+			return fmt.Errorf("add %s value to recordset fail.", name)
 		}
 	}
 
-	res_dataset.First()
-	return res_dataset, nil
+	res_dataset.AppendRecord(rec)
+	return nil
 }
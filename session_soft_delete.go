@@ -41,6 +41,7 @@ func (self *TSession) OnlyDeleted() *TSession {
 //
 // Requires a row-locating condition (Ids / Where / Domain) unless AllowUnsafe()
 // is set. Returns ErrNoSoftDelete if the model has no `deleted` tag field.
+// On audited models it is recorded as a write of the `deleted` field.
 func (self *TSession) SoftDelete(ids ...any) (int64, error) {
 	obj := self.Statement.Model.Obj()
 	if obj.DeletedField == "" {
@@ -1,5 +1,25 @@
 package orm
 
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	ormerr "github.com/volts-dev/orm/errors"
+)
+
+// savepointSeq 保存点名序号
+var savepointSeq uint64
+
+// tSavepoint 嵌套 Begin 建立的保存点
+type tSavepoint struct {
+	name   string
+	queued int // 建立时事务已登记的缓存操作数，回滚到本保存点时丢弃此后登记的
+}
+
 // Begin a transaction
 //
 //	Begin()
@@ -7,45 +27,96 @@ package orm
 //	if err = Commit(); err != nil {
 //			Rollback()
 //	}
+//
+// 已在事务中时建立保存点，与之配对的 Commit/Rollback 只释放/回滚到该保存点；
+// 隔离级别与只读模式取自 Isolation/ReadOnly，仅对最外层事务生效
 func (self *TSession) Begin() error {
 	// 当第一次调用时才修改Tx
 	if self.IsAutoCommit {
-		tx, err := self.db.BeginTx(self.context, nil)
+		opts := self.txOptions
+		if opts == nil && self.orm.config.TxIsolation != sql.LevelDefault {
+			opts = &sql.TxOptions{Isolation: self.orm.config.TxIsolation}
+		}
+		if opts != nil {
+			if err := self.orm.dialect.CheckTxOptions(opts); err != nil {
+				return err
+			}
+		}
+
+		tx, err := self.db.BeginTx(self.context, opts)
 		if err != nil {
-			return err
+			return self.orm.dialect.MapError(err)
 		}
 
 		self.IsAutoCommit = false
 		self.IsCommitedOrRollbacked = false
 		self.tx = tx
+		self.savepoints = nil
+		return nil
+	}
+
+	// 嵌套事务：保存点名全局唯一，共享同一事务的派生会话各自嵌套也不会重名
+	if self.tx != nil && !self.IsCommitedOrRollbacked {
+		name := fmt.Sprintf("sp_%d", atomic.AddUint64(&savepointSeq, 1))
+		if _, err := self.tx.ExecContext(self.context, "SAVEPOINT "+name); err != nil {
+			return self.orm.dialect.MapError(err)
+		}
+		self.savepoints = append(self.savepoints, tSavepoint{name: name, queued: self.orm._deferredInvalidations(self.tx)})
 	}
 
 	return nil
 }
 
 func (self *TSession) Commit() error {
+	if sp := self._popSavepoint(); sp != nil {
+		if _, err := self.tx.ExecContext(self.context, "RELEASE SAVEPOINT "+sp.name); err != nil {
+			return self.orm.dialect.MapError(err)
+		}
+		return nil
+	}
+
 	if !self.IsAutoCommit && !self.IsCommitedOrRollbacked {
 		self.IsCommitedOrRollbacked = true
 
 		if self.tx != nil {
 			if err := self.tx.Commit(); err != nil {
-				return err
+				// 提交失败事务已终止，会话同样回到非事务状态
+				self.orm._endTx(self.tx, false)
+				self.IsAutoCommit = true
+				self.tx = nil
+				return self.orm.dialect.MapError(err)
 			}
+			self.orm._endTx(self.tx, true)
 		}
 	}
 
 	/* 关闭事务 */
 	self.IsAutoCommit = true
 	self.tx = nil
+	self.lastWrite = time.Now() // 提交后的读取在粘滞窗口内仍走主库
 	return nil
 }
 
 // Rollback when using transaction, you can rollback if any error
 // e: the error witch trigger this Rollback
+// 处于保存点内时只回滚到该保存点，外层事务继续有效
 func (self *TSession) Rollback(e error) error {
+	if sp := self._popSavepoint(); sp != nil {
+		if _, err := self.tx.ExecContext(self.context, "ROLLBACK TO SAVEPOINT "+sp.name); err != nil {
+			return newSessionError("", e, err)
+		}
+		// 保存点之后的写入已撤销，其登记的缓存操作一并丢弃
+		self.orm._discardInvalidations(self.tx, sp.queued)
+		if _, err := self.tx.ExecContext(self.context, "RELEASE SAVEPOINT "+sp.name); err != nil {
+			return newSessionError("", e, err)
+		}
+		return newSessionError("", e)
+	}
+
 	if !self.IsAutoCommit && !self.IsCommitedOrRollbacked {
 		self.IsCommitedOrRollbacked = true
 		if self.tx != nil {
+			self.orm._endTx(self.tx, false)
 			err := self.tx.Rollback()
 			if err != nil {
 				return newSessionError("", e, err)
@@ -58,6 +129,78 @@ func (self *TSession) Rollback(e error) error {
 	return newSessionError("", e)
 }
 
+// Isolation 设置本会话此后开启事务的隔离级别，未设置时取 Config.TxIsolation
+func (self *TSession) Isolation(level sql.IsolationLevel) *TSession {
+	self._txOpts().Isolation = level
+	return self
+}
+
+// ReadOnly 本会话此后开启的事务为只读事务，数据库据此拒绝写入并可省去部分锁开销
+func (self *TSession) ReadOnly() *TSession {
+	self._txOpts().ReadOnly = true
+	return self
+}
+
+// _txOpts 取会话的事务选项，未设置时按 Config 默认值新建
+func (self *TSession) _txOpts() *sql.TxOptions {
+	if self.txOptions == nil {
+		self.txOptions = &sql.TxOptions{Isolation: self.orm.config.TxIsolation}
+	}
+	return self.txOptions
+}
+
+// _popSavepoint 弹出最内层保存点，不在保存点内时返回 nil
+func (self *TSession) _popSavepoint() *tSavepoint {
+	if len(self.savepoints) == 0 || self.tx == nil || self.IsCommitedOrRollbacked {
+		return nil
+	}
+	sp := self.savepoints[len(self.savepoints)-1]
+	self.savepoints = self.savepoints[:len(self.savepoints)-1]
+	return &sp
+}
+
+// Transaction 在事务中执行 fn：fn 返回 nil 时提交，返回错误或 panic 时回滚。
+// 已在事务中时以保存点嵌套，失败只回滚 fn 所做的修改；
+// 最外层事务因序列化失败或死锁(ErrSerialization)失败时整体重新执行 fn，至多 TxMaxRetries 次，
+// 故 fn 须可重复执行(不在事务外留下副作用)
+func (self *TSession) Transaction(fn func(*TSession) error) (err error) {
+	nested := self.IsTx()
+	for attempt := 0; ; attempt++ {
+		err = self._transaction(fn)
+		if err == nil || nested || attempt >= TxMaxRetries || !errors.Is(err, ormerr.ErrSerialization) {
+			return err
+		}
+
+		// 指数退避并加随机抖动，避免冲突双方同时重来再次冲突
+		backoff := TxRetryInterval << attempt
+		backoff += time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-self.context.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (self *TSession) _transaction(fn func(*TSession) error) (err error) {
+	if err = self.Begin(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			self.Rollback(fmt.Errorf("transaction panic: %v", r))
+			panic(r)
+		}
+	}()
+
+	if err = fn(self); err != nil {
+		self.Rollback(err)
+		return err
+	}
+	return self.Commit()
+}
+
 // IsInTx if current session is in a transaction
 func (self *TSession) IsTx() bool {
 	return !self.IsAutoCommit
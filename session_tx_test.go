@@ -0,0 +1,214 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	ormerr "github.com/volts-dev/orm/errors"
+)
+
+func bulkItemCodes(t *testing.T, o *TOrm) string {
+	t.Helper()
+	ds, err := o.NewSession().Model("bulk_item").Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	codes := make([]string, 0, ds.Count())
+	for _, rec := range ds.Data {
+		codes = append(codes, fmt.Sprint(rec.GetByField("code")))
+	}
+	sort.Strings(codes)
+	return fmt.Sprint(codes)
+}
+
+func TestSession_NestedBeginSavepoint(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	session := o.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := session.Model("bulk_item").Create(map[string]any{"code": "A"}); err != nil {
+		t.Fatalf("Create A: %v", err)
+	}
+
+	// 内层回滚只撤销保存点之后的修改
+	if err := session.Begin(); err != nil {
+		t.Fatalf("nested Begin: %v", err)
+	}
+	if _, err := session.Model("bulk_item").Create(map[string]any{"code": "B"}); err != nil {
+		t.Fatalf("Create B: %v", err)
+	}
+	session.Rollback(nil)
+	if !session.IsTx() {
+		t.Fatalf("outer transaction should survive the nested rollback")
+	}
+
+	if err := session.Begin(); err != nil {
+		t.Fatalf("nested Begin: %v", err)
+	}
+	if _, err := session.Model("bulk_item").Create(map[string]any{"code": "C"}); err != nil {
+		t.Fatalf("Create C: %v", err)
+	}
+	if err := session.Commit(); err != nil || !session.IsTx() {
+		t.Fatalf("nested Commit should only release the savepoint: %v", err)
+	}
+	if err := session.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := bulkItemCodes(t, o); got != "[A C]" {
+		t.Fatalf("expect [A C] after nested rollback, got %s", got)
+	}
+
+	// 保存点内关闭会话回滚整个事务
+	session = o.NewSession()
+	session.Begin()
+	session.Model("bulk_item").Create(map[string]any{"code": "D"})
+	session.Begin()
+	session.Close()
+	if got := bulkItemCodes(t, o); got != "[A C]" {
+		t.Fatalf("Close should roll back the whole transaction, got %s", got)
+	}
+}
+
+func TestSession_Transaction(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	// 内层失败只回滚内层，外层照常提交
+	failed := errors.New("inner failed")
+	err := o.NewSession().Transaction(func(tx *TSession) error {
+		if _, err := tx.Model("bulk_item").Create(map[string]any{"code": "A"}); err != nil {
+			return err
+		}
+		err := tx.Transaction(func(tx *TSession) error {
+			if _, err := tx.Model("bulk_item").Create(map[string]any{"code": "B"}); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("nested Transaction should return its error, got %v", err)
+		}
+		return nil
+	})
+	if err != nil || bulkItemCodes(t, o) != "[A]" {
+		t.Fatalf("Transaction: %v %s", err, bulkItemCodes(t, o))
+	}
+
+	// 序列化失败整体重试，其他错误不重试
+	calls := 0
+	err = o.NewSession().Transaction(func(tx *TSession) error {
+		calls++
+		if _, err := tx.Model("bulk_item").Create(map[string]any{"code": fmt.Sprintf("R%d", calls)}); err != nil {
+			return err
+		}
+		if calls < 3 {
+			return ormerr.New(ormerr.ErrSerialization, errors.New("could not serialize access"))
+		}
+		return nil
+	})
+	if err != nil || calls != 3 || bulkItemCodes(t, o) != "[A R3]" {
+		t.Fatalf("serialization failure should be retried: %v calls=%d %s", err, calls, bulkItemCodes(t, o))
+	}
+
+	calls = 0
+	err = o.NewSession().Transaction(func(tx *TSession) error {
+		calls++
+		return ormerr.New(ormerr.ErrStaleRecord, nil)
+	})
+	if !errors.Is(err, ormerr.ErrStaleRecord) || calls != 1 {
+		t.Fatalf("other conflicts should not be retried: %v calls=%d", err, calls)
+	}
+
+	// panic 时回滚并继续抛出
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("panic should be propagated")
+			}
+		}()
+		o.NewSession().Transaction(func(tx *TSession) error {
+			tx.Model("bulk_item").Create(map[string]any{"code": "P"})
+			panic("boom")
+		})
+	}()
+	if got := bulkItemCodes(t, o); got != "[A R3]" {
+		t.Fatalf("panicking transaction should be rolled back, got %s", got)
+	}
+}
+
+func TestDialect_CheckTxOptions(t *testing.T) {
+	pg := newPgDialectForTest(t)
+	for _, level := range []sql.IsolationLevel{sql.LevelDefault, sql.LevelReadCommitted, sql.LevelRepeatableRead, sql.LevelSerializable} {
+		if err := pg.CheckTxOptions(&sql.TxOptions{Isolation: level, ReadOnly: true}); err != nil {
+			t.Errorf("postgres should accept %s: %v", level, err)
+		}
+	}
+	if err := pg.CheckTxOptions(&sql.TxOptions{Isolation: sql.LevelSnapshot}); !errors.Is(err, ErrTxOptionsNotSupported) {
+		t.Errorf("postgres should reject snapshot isolation, got %v", err)
+	}
+
+	lite := &sqlite{}
+	if err := lite.CheckTxOptions(&sql.TxOptions{Isolation: sql.LevelSerializable}); err != nil {
+		t.Errorf("sqlite should accept serializable: %v", err)
+	}
+	if err := lite.CheckTxOptions(&sql.TxOptions{Isolation: sql.LevelReadCommitted}); !errors.Is(err, ErrTxOptionsNotSupported) {
+		t.Errorf("sqlite should reject read committed, got %v", err)
+	}
+}
+
+func TestSession_TxOptions(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	// 不支持的隔离级别在开启事务前即被拒绝
+	session := o.NewSession().Isolation(sql.LevelReadCommitted)
+	if err := session.Begin(); !errors.Is(err, ErrTxOptionsNotSupported) || session.IsTx() {
+		t.Fatalf("sqlite should reject read committed, got %v", err)
+	}
+	err := session.Transaction(func(tx *TSession) error { return nil })
+	if !errors.Is(err, ErrTxOptionsNotSupported) {
+		t.Fatalf("Transaction should use the session options, got %v", err)
+	}
+
+	session.Isolation(sql.LevelSerializable).ReadOnly()
+	err = session.Transaction(func(tx *TSession) error {
+		_, err := tx.Model("bulk_item").Read()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("serializable read only transaction: %v", err)
+	}
+	if session.txOptions == nil || !session.txOptions.ReadOnly {
+		t.Fatalf("options should persist for later transactions of the session")
+	}
+	session.Close()
+	if session.txOptions != nil {
+		t.Fatalf("Close should reset the transaction options")
+	}
+
+	// 未设置时取 Config.TxIsolation
+	o.config.TxIsolation = sql.LevelRepeatableRead
+	defer func() { o.config.TxIsolation = sql.LevelDefault }()
+	if err := o.NewSession().Begin(); !errors.Is(err, ErrTxOptionsNotSupported) {
+		t.Fatalf("default isolation should come from config, got %v", err)
+	}
+	if s := o.NewSession().ReadOnly(); s.txOptions.Isolation != sql.LevelRepeatableRead {
+		t.Fatalf("ReadOnly should keep the configured isolation, got %s", s.txOptions.Isolation)
+	}
+}
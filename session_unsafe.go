@@ -1,7 +1,8 @@
 package orm
 
 // AllowUnsafe disables this session's Phase 2 dangerous-operation guards,
-// permitting no-WHERE Delete/Write and the DDL counterparts DropTable/Truncate.
+// permitting no-WHERE Delete/Write, the DDL counterparts DropTable/Truncate and
+// destructive schema changes during SyncModel (see UnsafeSchemaError).
 // Effect is sticky for the session's lifetime.
 func (self *TSession) AllowUnsafe() *TSession {
 	self.allowUnsafe = true
@@ -0,0 +1,91 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	ormerr "github.com/volts-dev/orm/errors"
+)
+
+type VersionItem struct {
+	TModel  `table:"name('version_item')"`
+	Id      int64  `field:"pk autoincr"`
+	Name    string `field:"varchar() size(32)"`
+	Version int64  `field:"int() version"`
+}
+
+func TestSession_WriteOptimisticLock(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(VersionItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	ids, err := o.NewSession().Model("version_item").Create(
+		map[string]any{"name": "a"},
+		map[string]any{"name": "b"},
+		map[string]any{"name": "c"},
+	)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	version := func(id any) string {
+		ds, err := o.NewSession().Model("version_item").Ids(id).Read()
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		return fmt.Sprint(ds.Record().GetByField("version"))
+	}
+	if v := version(ids[0]); v != "1" {
+		t.Fatalf("new record should start at version 1, got %s", v)
+	}
+
+	// 带读取时的版本更新，版本递增；不带版本时不校验但仍递增
+	if _, err = o.NewSession().Model("version_item").Ids(ids[0]).Write(map[string]any{"name": "a1", "version": 1}); err != nil {
+		t.Fatalf("Write with current version: %v", err)
+	}
+	if _, err = o.NewSession().Model("version_item").Ids(ids[0]).Write(map[string]any{"name": "a2"}); err != nil {
+		t.Fatalf("Write without version: %v", err)
+	}
+	if v := version(ids[0]); v != "3" {
+		t.Fatalf("version should be incremented on every write, got %s", v)
+	}
+
+	// 以过期版本更新：不生效并返回冲突记录
+	_, err = o.NewSession().Model("version_item").Ids(ids[0]).Write(map[string]any{"name": "lost", "version": 1})
+	var staleErr *StaleRecordError
+	if !errors.As(err, &staleErr) || !errors.Is(err, ormerr.ErrStaleRecord) || !errors.Is(err, ormerr.ErrConflict) {
+		t.Fatalf("stale version should fail with StaleRecordError, got %v", err)
+	}
+	if fmt.Sprint(staleErr.Ids) != fmt.Sprint([]any{ids[0]}) {
+		t.Fatalf("stale error should carry the conflicting id, got %v", staleErr.Ids)
+	}
+	if ds, _ := o.NewSession().Model("version_item").Ids(ids[0]).Read(); ds.Record().GetByField("name") != "a2" || version(ids[0]) != "3" {
+		t.Fatalf("stale write should not be applied, got %v", ds.Record().AsMap())
+	}
+
+	// 批量更新：仅版本过期的记录冲突，整批回滚
+	model, err := o.GetModel("version_item")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	_, err = model.Update(&UpdateRequest{Data: []any{
+		map[string]any{"id": ids[1], "name": "b1", "version": 1},
+		map[string]any{"id": ids[2], "name": "c1", "version": 7},
+	}})
+	if !errors.As(err, &staleErr) || fmt.Sprint(staleErr.Ids) != fmt.Sprint([]any{ids[2]}) {
+		t.Fatalf("batch update should report the stale record %v, got %v", ids[2], err)
+	}
+	if v := version(ids[1]); v != "1" {
+		t.Fatalf("conflicting batch should be rolled back, got version %s", v)
+	}
+	if effect, err := model.Update(&UpdateRequest{Data: []any{
+		map[string]any{"id": ids[1], "name": "b1", "version": 1},
+		map[string]any{"id": ids[2], "name": "c1", "version": 1},
+	}}); err != nil || effect != 2 {
+		t.Fatalf("batch update with current versions: %d %v", effect, err)
+	}
+	if version(ids[1]) != "2" || version(ids[2]) != "2" {
+		t.Fatalf("batch update should increment versions")
+	}
+}
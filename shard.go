@@ -0,0 +1,824 @@
+package orm
+
+import (
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/volts-dev/dataset"
+	ormerr "github.com/volts-dev/orm/errors"
+	"github.com/volts-dev/utils"
+)
+
+type (
+	// IShardResolver 分片解析器：由记录的分片键值决定其所在分片
+	IShardResolver interface {
+		// Key 分片键字段名
+		Key() string
+		// Shard 返回键值 key 所在分片的下标，shards 为分片总数。
+		// key 可能来自记录值或查询条件，同一值的不同类型(如 1 与 "1")须落在同一分片
+		Shard(key any, shards int) (int, error)
+	}
+
+	tShardResolver struct {
+		key     string
+		resolve func(key any, shards int) (int, error)
+	}
+
+	// tShardTarget 一次操作涉及的分片；ids 非 nil 时为该分片上的主键条件
+	tShardTarget struct {
+		shard int
+		ids   []any
+	}
+)
+
+// NewShardResolver 以自定义函数解析分片
+func NewShardResolver(key string, fn func(key any, shards int) (int, error)) IShardResolver {
+	return &tShardResolver{key: key, resolve: fn}
+}
+
+// NewHashShardResolver 按键值字符串形式的 FNV 哈希对分片数取模
+func NewHashShardResolver(key string) IShardResolver {
+	return NewShardResolver(key, func(value any, shards int) (int, error) {
+		hash := fnv.New32a()
+		hash.Write([]byte(utils.ToString(value)))
+		return int(hash.Sum32() % uint32(shards)), nil
+	})
+}
+
+func (self *tShardResolver) Key() string {
+	return self.key
+}
+
+func (self *tShardResolver) Shard(key any, shards int) (int, error) {
+	return self.resolve(key, shards)
+}
+
+// WithShardResolver 为模型设置分片解析器；指定 models 时仅作用于这些模型，
+// 常经 WithModelOptions 在注册模型时套用。见 Config.Shards
+func WithShardResolver(resolver IShardResolver, models ...string) ModelOption {
+	models = slices.Clone(models) // 不改动调用方的切片
+	for i, name := range models {
+		models[i] = fmtModelName(name)
+	}
+	return func(opts *ModelOptions) {
+		if len(models) > 0 && (opts.Model == nil || utils.IndexOf(opts.Model.String(), models...) == -1) {
+			return
+		}
+		opts.ShardResolver = resolver
+	}
+}
+
+// newShards 连接各分片库；每个分片是独立的 TOrm，拥有各自的方言、连接与模型注册表
+func newShards(cfg *Config) ([]*TOrm, error) {
+	shards := make([]*TOrm, 0, len(cfg.Shards))
+	for _, ds := range cfg.Shards {
+		shardCfg := *cfg
+		shardCfg.DataSource = ds.inherit(cfg.DataSource)
+		shardCfg.Shards = nil
+		shardCfg.Replicas = nil
+		shardCfg.CacheBackend = nil // 分片上的读写经由主库会话，缓存后端归主库所有
+		shard, err := New(func(c *Config) { *c = shardCfg })
+		if err != nil {
+			for _, opened := range shards {
+				opened.Close()
+			}
+			return nil, err
+		}
+		shards = append(shards, shard)
+	}
+	return shards, nil
+}
+
+// Shard 把会话绑定到第 idx 个分片，之后的读写与事务都在该分片上执行，不再按分片键路由。
+// 跨分片的事务无法保证原子性，分片模型上的事务须先以 Shard 指定分片再 Begin
+func (self *TSession) Shard(idx int) *TSession {
+	if idx < 0 || idx >= len(self.orm.shards) {
+		log.Errf("%v: %d", ErrShardNotFound, idx)
+		self.IsDeprecated = true
+		return self
+	}
+	if self.tx != nil {
+		log.Errf("Shard must be set before the transaction begins")
+		self.IsDeprecated = true
+		return self
+	}
+
+	self.db = self.orm.shards[idx].db
+	self.shard = idx
+	return self
+}
+
+// _shardResolver 当前模型的分片解析器；未配置分片或模型未分片时为 nil
+func (self *TSession) _shardResolver() IShardResolver {
+	model := self.Statement.Model
+	if len(self.orm.shards) == 0 || model == nil || model.Obj() == nil {
+		return nil
+	}
+	return model.Obj().ShardResolver
+}
+
+// _shardRouted 本次操作须按分片键路由：模型已分片且会话未绑定分片
+func (self *TSession) _shardRouted() bool {
+	return self.shard < 0 && self._shardResolver() != nil
+}
+
+// _cacheable SQL 结果可经缓存读写；各分片上相同的语句结果不同，绑定分片时不走缓存；
+// 事务内可读到未提交的数据，同样不走缓存
+func (self *TSession) _cacheable() bool {
+	return self.shard < 0 && self.tx == nil
+}
+
+func (self *TSession) _shardOf(resolver IShardResolver, key any) (int, error) {
+	if key == nil {
+		return -1, fmt.Errorf("%w: %s.%s", ErrShardKeyMissing, self.Statement.Model.String(), resolver.Key())
+	}
+	idx, err := resolver.Shard(key, len(self.orm.shards))
+	if err != nil {
+		return -1, err
+	}
+	if idx < 0 || idx >= len(self.orm.shards) {
+		return -1, fmt.Errorf("%w: %d", ErrShardNotFound, idx)
+	}
+	return idx, nil
+}
+
+// _shardTargets 按主键或查询条件中的分片键裁剪本次操作涉及的分片，无法裁剪时为全部分片
+func (self *TSession) _shardTargets() ([]tShardTarget, error) {
+	resolver := self._shardResolver()
+	key := resolver.Key()
+
+	var targets []tShardTarget
+	index := make(map[int]int)
+	add := func(value any, id bool) error {
+		idx, err := self._shardOf(resolver, value)
+		if err != nil {
+			return err
+		}
+		pos, has := index[idx]
+		if !has {
+			pos = len(targets)
+			index[idx] = pos
+			targets = append(targets, tShardTarget{shard: idx})
+		}
+		if id {
+			targets[pos].ids = append(targets[pos].ids, value)
+		}
+		return nil
+	}
+
+	if ids := self.Statement.IdParam; len(ids) > 0 {
+		if key != self.Statement.Model.IdField() {
+			return self._allShards(), nil
+		}
+		for _, id := range ids {
+			if err := add(id, true); err != nil {
+				return nil, err
+			}
+		}
+	} else if values, ok := self._shardKeyValues(key); ok {
+		for _, value := range values {
+			if err := add(value, false); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		return self._allShards(), nil
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].shard < targets[j].shard
+	})
+	return targets, nil
+}
+
+func (self *TSession) _allShards() []tShardTarget {
+	targets := make([]tShardTarget, len(self.orm.shards))
+	for i := range targets {
+		targets[i].shard = i
+	}
+	return targets
+}
+
+// _shardKeyValues 从查询条件中取分片键的取值：仅识别与其余条件以 AND 连接的 = 与 in 条件，
+// 出现在 OR/NOT 之下或无法确定占位符取值时返回 false
+func (self *TSession) _shardKeyValues(key string) ([]any, bool) {
+	node := self.Statement.domain
+	if node == nil || node.Count() == 0 {
+		return nil, false
+	}
+
+	nodes := node.Nodes()
+	if node.IsLeafNode() {
+		nodes = nodes[:0:0]
+		nodes = append(nodes, node)
+	}
+
+	params := self.Statement.Params
+	holder := 0
+	consume := func(value any) (any, bool) {
+		if utils.IndexOf(utils.ToString(value), "?", "%s") == -1 {
+			return value, true
+		}
+		if holder >= len(params) {
+			return nil, false
+		}
+		holder++
+		return params[holder-1], true
+	}
+
+	var values []any
+	found := false
+	// 逐项记录各待填操作数是否处于 AND 语境；栈空时为顶层的隐式 AND
+	stack := make([]bool, 0)
+	for _, n := range nodes {
+		and := true
+		if last := len(stack) - 1; last >= 0 {
+			and = stack[last]
+			stack = stack[:last]
+		}
+
+		if n.IsValueNode() {
+			switch n.String() {
+			case "&":
+				stack = append(stack, and, and)
+			case "|":
+				stack = append(stack, false, false)
+			case "!":
+				stack = append(stack, false)
+			}
+			continue
+		}
+		if !n.IsLeafNode() {
+			return nil, false
+		}
+
+		right := n.Item(2)
+		items := []any{right.Value}
+		if right.IsListNode() {
+			items = items[:0]
+			for _, item := range right.Nodes() {
+				items = append(items, item.Value)
+			}
+		}
+		leafValues := make([]any, 0, len(items))
+		for _, item := range items {
+			value, ok := consume(item)
+			if !ok {
+				return nil, false
+			}
+			leafValues = append(leafValues, value)
+		}
+
+		op := strings.ToLower(n.String(1))
+		if found || !and || n.String(0) != key || (op != "=" && op != "in") {
+			continue
+		}
+		if op == "in" {
+			leafValues = flattenIds(leafValues)
+		}
+		values, found = leafValues, true
+	}
+
+	// 占位符与参数个数不符时无法确定各条件的取值
+	if !found || holder != len(params) || len(values) == 0 {
+		return nil, false
+	}
+	return values, true
+}
+
+// _onShard 在第 idx 个分片上执行 fn；atomic 时在该分片上自开事务
+func (self *TSession) _onShard(idx int, atomic bool, fn func() error) (err error) {
+	if self.tx != nil {
+		return ErrShardRequired
+	}
+
+	db, shard := self.db, self.shard
+	self.db, self.shard = self.orm.shards[idx].db, idx
+	defer func() {
+		self.db, self.shard = db, shard
+	}()
+
+	if !atomic {
+		return fn()
+	}
+	if err = self.Begin(); err != nil {
+		return err
+	}
+	if err = fn(); err != nil {
+		self.Rollback(err)
+		return err
+	}
+	return self.Commit()
+}
+
+// _eachShard 在各目标分片上以相同的 Statement 执行 fn，完成后恢复 Statement
+func (self *TSession) _eachShard(targets []tShardTarget, atomic bool, fn func(target tShardTarget) error) error {
+	stmt := self.Statement
+	autoReset := self.AutoResetStatement
+	self.AutoResetStatement = false
+	defer func() {
+		self.Statement = stmt
+		self.AutoResetStatement = autoReset
+	}()
+
+	for _, target := range targets {
+		self.Statement = stmt
+		self.Statement.domain = stmt.domain.Copy()
+		if target.ids != nil {
+			self.Statement.IdParam = target.ids
+		}
+		if err := self._onShard(target.shard, atomic, func() error { return fn(target) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// _readSharded 在涉及的各分片上读取并合并：每个分片取前 offset+limit 条，合并排序后再截取本页
+func (self *TSession) _readSharded() (*dataset.TDataSet, error) {
+	targets, err := self._shardTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &self.Statement
+	keyset := stmt.keyset
+	limit, offset := stmt.LimitClause, stmt.OffsetClause
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	if keyset != nil {
+		offset = 0
+	}
+	if len(targets) > 1 {
+		if limit > 0 {
+			stmt.LimitClause = limit + offset
+		}
+		stmt.OffsetClause = 0
+	}
+
+	var terms []tOrderTerm
+	if keyset == nil {
+		terms = self._shardOrderTerms()
+		// 合并排序所需的列须一并读出
+		if len(stmt.Fields) > 0 {
+			for _, term := range terms {
+				if utils.IndexOf(term.field, stmt.Fields...) == -1 {
+					stmt.Fields = append(stmt.Fields, term.field)
+				}
+			}
+		}
+	}
+
+	var res *dataset.TDataSet
+	records := make([]*dataset.TRecordSet, 0)
+	err = self._eachShard(targets, false, func(tShardTarget) error {
+		ds, err := self._read()
+		if err != nil {
+			return err
+		}
+		if res == nil || res.Count() == 0 {
+			res = ds
+		}
+		records = append(records, ds.Data...)
+		return nil
+	})
+	if err != nil || len(targets) == 1 {
+		return res, err
+	}
+
+	if keyset != nil {
+		terms = keyset.terms
+	}
+	self._sortShardRecords(records, terms)
+	records = shardPage(records, offset, limit)
+
+	res.Data = nil
+	res.RecordsIndex = nil
+	if err = res.AppendRecord(records...); err != nil {
+		return nil, err
+	}
+	res.First()
+	return res, nil
+}
+
+// _searchSharded 在涉及的各分片上查询 id 并合并；计数时累加各分片的计数
+func (self *TSession) _searchSharded() ([]any, int64, error) {
+	targets, err := self._shardTargets()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stmt := &self.Statement
+	if stmt.IsCount || len(targets) == 1 {
+		var ids []any
+		var count int64
+		err = self._eachShard(targets, false, func(tShardTarget) error {
+			part, n, err := self._search("", nil)
+			ids = append(ids, part...)
+			count += n
+			return err
+		})
+		return ids, count, err
+	}
+
+	// 与 _search 一致，未设置 limit 时不限条数
+	limit, offset := stmt.LimitClause, stmt.OffsetClause
+	if limit <= 0 {
+		limit = -1
+	} else {
+		stmt.LimitClause = limit + offset
+	}
+	stmt.OffsetClause = 0
+
+	model := stmt.Model
+	idField := model.IdField()
+	terms := self._shardOrderTerms()
+	records := make([]*dataset.TRecordSet, 0)
+	err = self._eachShard(targets, false, func(tShardTarget) error {
+		ids, _, err := self._search("", nil)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if len(terms) == 1 {
+			for _, id := range ids {
+				records = append(records, dataset.NewRecordSet(map[string]any{idField: id}))
+			}
+			return nil
+		}
+		ds, err := self._shardOrderValues(ids, terms)
+		if err != nil {
+			return err
+		}
+		records = append(records, ds.Data...)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	self._sortShardRecords(records, terms)
+	records = shardPage(records, offset, limit)
+	ids := make([]any, len(records))
+	for i, rec := range records {
+		ids[i] = rec.GetByField(idField)
+	}
+	return ids, int64(len(ids)), nil
+}
+
+// _shardOrderValues 读取本分片上记录 ids 的排序值
+func (self *TSession) _shardOrderValues(ids []any, terms []tOrderTerm) (*dataset.TDataSet, error) {
+	quoter := self.orm.dialect.Quoter()
+	cols := make([]string, len(terms))
+	for i, term := range terms {
+		cols[i] = quoter.Quote(term.field)
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s IN (%s)`, strings.Join(cols, ","), self.Statement.QuoteTable(),
+		quoter.Quote(self.Statement.Model.IdField()), idsToSqlHolder(ids...))
+	return self._query(query, ids...)
+}
+
+// _shardOrderTerms 合并各分片结果所用的排序项：取本表存储字段上的排序，并以主键收尾使顺序确定
+func (self *TSession) _shardOrderTerms() []tOrderTerm {
+	model := self.Statement.Model
+	table := model.Table()
+	idField := model.IdField()
+	query := NewQuery(self, []string{table}, nil, nil, nil, nil)
+
+	terms := make([]tOrderTerm, 0)
+	hasId := false
+	for _, term := range self.Statement.generate_order_terms(table, self.Statement.OrderByClause, query) {
+		if term.field == idField {
+			hasId = true
+		} else if field := model.GetFieldByName(term.field); field == nil || field.IsInherited() || field.Translate() {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	if !hasId {
+		terms = append(terms, tOrderTerm{field: idField, direction: "ASC"})
+	}
+	return terms
+}
+
+// _sumSharded 累加涉及的各分片上的求和结果
+func (self *TSession) _sumSharded(col string) (float64, error) {
+	targets, err := self._shardTargets()
+	if err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	err = self._eachShard(targets, false, func(tShardTarget) error {
+		part, err := self._sum(col)
+		sum += part
+		return err
+	})
+	return sum, err
+}
+
+// _writeSharded 在涉及的各分片上更新：先定位本分片上命中的记录，再按 id 更新。
+// 各分片分别提交，整体不具备跨分片的原子性；记录不会经更新分片键迁移到其他分片
+func (self *TSession) _writeSharded(data any) (int64, error) {
+	resolver := self._shardResolver()
+	model := self.Statement.Model
+	keyShard := -1
+	if data != nil {
+		ds, err := self._validateValues(data)
+		if err != nil {
+			return 0, err
+		}
+		rec := ds.Record()
+		if len(self.Statement.IdParam) == 0 {
+			if id := rec.GetByField(model.IdField()); id != nil && !utils.IsBlank(id) {
+				self.Statement.IdParam = []any{id}
+			}
+		}
+		if value := rec.GetByField(resolver.Key()); value != nil && resolver.Key() != model.IdField() {
+			if keyShard, err = self._shardOf(resolver, value); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if !self.allowUnsafe && !self.hasCondition() {
+		return 0, ormerr.ErrUnsafe
+	}
+
+	targets, err := self._shardTargets()
+	if err != nil {
+		return 0, err
+	}
+
+	var effect int64
+	matched := false
+	err = self._eachShard(targets, self._atomic(OpWrite), func(target tShardTarget) error {
+		ids, err := self._shardIds()
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if keyShard >= 0 && keyShard != target.shard {
+			return fmt.Errorf("%s.%s cannot move records to another shard", model.String(), resolver.Key())
+		}
+		matched = true
+		self.Statement.IdParam = ids
+		n, err := self._write(data)
+		effect += n
+		return err
+	})
+	if err == nil && !matched {
+		// 各分片均未命中时在首个分片上照常执行，使结果与单库一致
+		err = self._eachShard(targets[:1], false, func(tShardTarget) error {
+			effect, err = self._write(data)
+			return err
+		})
+	}
+	return effect, err
+}
+
+// _deleteSharded 在涉及的各分片上删除命中的记录，各分片分别提交
+func (self *TSession) _deleteSharded(ids ...any) (int64, error) {
+	if len(ids) > 0 {
+		self.Statement.IdParam = append(self.Statement.IdParam, flattenIds(ids)...)
+	}
+	if !self.allowUnsafe && !self.hasCondition() {
+		return 0, ormerr.ErrUnsafe
+	}
+
+	targets, err := self._shardTargets()
+	if err != nil {
+		return 0, err
+	}
+
+	var effect int64
+	err = self._eachShard(targets, self._atomic(OpDelete), func(tShardTarget) error {
+		ids, err := self._shardIds()
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		self.Statement.IdParam = ids
+		n, err := self._delete()
+		effect += n
+		return err
+	})
+	return effect, err
+}
+
+// _shardIds 本分片上命中的记录：指定了 id 时取其中存在于本分片的，否则按条件查询
+func (self *TSession) _shardIds() ([]any, error) {
+	model := self.Statement.Model
+	if ids := self.Statement.IdParam; len(ids) > 0 {
+		quoter := self.orm.dialect.Quoter()
+		idField := quoter.Quote(model.IdField())
+		query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s IN (%s)`, idField, self.Statement.QuoteTable(), idField, idsToSqlHolder(ids...))
+		ds, err := self._query(query, ids...)
+		if err != nil {
+			return nil, err
+		}
+		return ds.Keys(model.IdField()), nil
+	}
+
+	ids, _, err := self._search("", nil)
+	return ids, err
+}
+
+// _createSharded 按分片键把新记录分组插入各分片：先解析全部待插入值(主键已生成、
+// 关联记录已建立)，再在各分片上插入并完成插入后的计算、规则校验与钩子。返回的 id 与 src 顺序一致
+func (self *TSession) _createSharded(src []any, idCreator *TIdField, hookValues []map[string]any) ([]any, error) {
+	if self.tx != nil {
+		return nil, ErrShardRequired
+	}
+
+	resolver := self._shardResolver()
+	key := resolver.Key()
+	atomic := self._atomic(OpCreate) || self._copyBulk(len(src)) // COPY 须在分片事务内进行
+	if !atomic {
+		node, err := self._ruleDomain(OpCreate)
+		if err != nil {
+			return nil, err
+		}
+		atomic = node != nil
+	}
+
+	rows := make([]*tCreateRow, len(src))
+	parts := make(map[int][]int)
+	targets := make([]tShardTarget, 0)
+	for i, one := range src {
+		row, err := self._createValues(one, idCreator)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = row
+
+		value := row.values[key]
+		if value == nil && len(row.computed[key]) > 0 {
+			value = row.computed[key][0]
+		}
+		idx, err := self._shardOf(resolver, value)
+		if err != nil {
+			return nil, err
+		}
+		if _, has := parts[idx]; !has {
+			targets = append(targets, tShardTarget{shard: idx})
+		}
+		parts[idx] = append(parts[idx], i)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].shard < targets[j].shard
+	})
+
+	ids := make([]any, len(rows))
+	err := self._eachShard(targets, atomic, func(target tShardTarget) error {
+		part := make([]*tCreateRow, 0, len(parts[target.shard]))
+		var partValues []map[string]any
+		for _, i := range parts[target.shard] {
+			part = append(part, rows[i])
+			if len(hookValues) == len(src) {
+				partValues = append(partValues, hookValues[i])
+			}
+		}
+
+		partIds, err := self._insertRows(part)
+		if err != nil {
+			return err
+		}
+		for j, i := range parts[target.shard] {
+			ids[i] = partIds[j]
+		}
+		if hookValues != nil && partValues == nil {
+			partValues = hookValues
+		}
+		return self._created(partIds, partValues)
+	})
+	return ids, err
+}
+
+// _sortShardRecords 按排序项稳定排序合并后的记录，顺序与单库上执行同一排序一致：
+// NULL 的位置随方言，大数字段以字符串取出时(BigNumberToString)仍按数值比较
+func (self *TSession) _sortShardRecords(records []*dataset.TRecordSet, terms []tOrderTerm) {
+	model := self.Statement.Model
+	numeric := make([]bool, len(terms))
+	for i, term := range terms {
+		numeric[i] = isBigNumberField(model.GetFieldByName(term.field))
+	}
+
+	// Postgres 与 Oracle 视 NULL 大于任何值(升序 NULLS LAST)，MySQL、SQLite 等视为最小
+	dbType := self.orm.dialect.DBType()
+	sortShardRecords(records, terms, numeric, dbType == POSTGRES || dbType == ORACLE)
+}
+
+// sortShardRecords 按排序项稳定排序合并后的记录；numeric 标记按数值比较字符串值的排序项，
+// nullsLast 时 NULL 视为最大值
+func sortShardRecords(records []*dataset.TRecordSet, terms []tOrderTerm, numeric []bool, nullsLast bool) {
+	sort.SliceStable(records, func(i, j int) bool {
+		for k, term := range terms {
+			a, b := records[i].GetByField(term.field), records[j].GetByField(term.field)
+			var c int
+			switch {
+			case a == nil && b == nil:
+				continue
+			case a == nil:
+				c = -1
+			case b == nil:
+				c = 1
+			default:
+				c = compareShardValues(a, b, k < len(numeric) && numeric[k])
+			}
+			if (a == nil || b == nil) && nullsLast {
+				c = -c
+			}
+			if c == 0 {
+				continue
+			}
+			if term.desc() {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// compareShardValues 比较两个非 nil 的排序值；numeric 时字符串按数值比较
+func compareShardValues(a, b any, numeric bool) int {
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			if numeric {
+				if c, ok := compareNumberStrings(x, y); ok {
+					return c
+				}
+			}
+			return strings.Compare(x, y)
+		}
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			return strings.Compare(string(x), string(y))
+		}
+	case bool:
+		if y, ok := b.(bool); ok && x != y {
+			if x {
+				return 1
+			}
+			return -1
+		} else if ok {
+			return 0
+		}
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case va.CanInt() && vb.CanInt():
+		return cmp.Compare(va.Int(), vb.Int())
+	case va.CanUint() && vb.CanUint():
+		return cmp.Compare(va.Uint(), vb.Uint())
+	case isShardNumber(va) && isShardNumber(vb):
+		return cmp.Compare(utils.ToFloat64(a), utils.ToFloat64(b))
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// compareNumberStrings 按数值比较两个数字字符串，任一方不是数字时 ok 为 false
+func compareNumberStrings(a, b string) (int, bool) {
+	if x, err := strconv.ParseInt(a, 10, 64); err == nil {
+		if y, err := strconv.ParseInt(b, 10, 64); err == nil {
+			return cmp.Compare(x, y), true
+		}
+	}
+	x, okA := new(big.Float).SetString(a)
+	y, okB := new(big.Float).SetString(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+func isShardNumber(v reflect.Value) bool {
+	return v.CanInt() || v.CanUint() || v.CanFloat()
+}
+
+// shardPage 截取合并结果中的一页；limit 为负时不限条数
+func shardPage[T any](items []T, offset, limit int64) []T {
+	if offset > 0 {
+		if offset >= int64(len(items)) {
+			return nil
+		}
+		items = items[offset:]
+	}
+	if limit >= 0 && limit < int64(len(items)) {
+		items = items[:limit]
+	}
+	return items
+}
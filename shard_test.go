@@ -0,0 +1,388 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/volts-dev/dataset"
+)
+
+type (
+	ShardOrder struct {
+		TModel   `table:"name('shard_order')"`
+		Id       int64  `field:"id"`
+		TenantId int64  `field:"int()"`
+		Code     string `field:"varchar() size(32)"`
+		Qty      int    `field:"int()"`
+	}
+
+	ShardCustomer struct {
+		TModel   `table:"name('shard_customer')"`
+		Id       int64  `field:"id"`
+		TenantId int64  `field:"int()"`
+		Name     string `field:"varchar() size(64) translate"`
+	}
+
+	ShardInvoice struct {
+		TModel       `table:"name('shard_invoice')"`
+		Id           int64  `field:"id"`
+		TenantId     int64  `field:"int()"`
+		CustomerId   int64  `field:"many2one(shard_customer)"`
+		CustomerName string `field:"varchar() related('customer_id.name')"`
+	}
+)
+
+// tenantResolver 按租户号奇偶分到两个分片
+var tenantResolver = NewShardResolver("tenant_id", func(key any, shards int) (int, error) {
+	var id int64
+	if _, err := fmt.Sscan(fmt.Sprint(key), &id); err != nil {
+		return 0, err
+	}
+	return int(id % int64(shards)), nil
+})
+
+// setupShardOrm 主库与两个分片均为独立的库文件
+func setupShardOrm(t *testing.T, resolver IShardResolver) *TOrm {
+	t.Helper()
+	dir := t.TempDir()
+	o, err := New(
+		WithDataSource(&TDataSource{DbType: "sqlite", DbName: filepath.Join(dir, "primary.db")}),
+		WithShards(&TDataSource{DbName: filepath.Join(dir, "shard0.db")}, &TDataSource{DbName: filepath.Join(dir, "shard1.db")}),
+		WithModelOptions(WithShardResolver(resolver, "shard.order")),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err = o.SyncModel("", new(ShardOrder), new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	return o
+}
+
+func shardCount(t *testing.T, o *TOrm, idx int) int {
+	t.Helper()
+	n, err := o.NewSession().Shard(idx).Model("shard_order").Count()
+	if err != nil {
+		t.Fatalf("Count shard %d: %v", idx, err)
+	}
+	return n
+}
+
+func TestShard_RouteById(t *testing.T) {
+	o := setupShardOrm(t, NewHashShardResolver("id"))
+	defer o.Close()
+
+	src := make([]any, 0, 20)
+	for i := 0; i < 20; i++ {
+		src = append(src, map[string]any{"code": fmt.Sprintf("C%02d", i), "qty": i % 7, "tenant_id": 1})
+	}
+	ids, err := o.NewSession().Model("shard_order").Create(src...)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(ids) != 20 {
+		t.Fatalf("Create should return one id per record, got %d", len(ids))
+	}
+
+	first, second := shardCount(t, o, 0), shardCount(t, o, 1)
+	if first+second != 20 || first == 0 || second == 0 {
+		t.Fatalf("records should be spread over both shards, got %d and %d", first, second)
+	}
+	if n, _ := o.NewSession().Model("shard_order").Count(); n != 20 {
+		t.Fatalf("Count should sum every shard, got %d", n)
+	}
+
+	ds, err := o.NewSession().Model("shard_order").Ids(ids[5]).Read()
+	if err != nil || ds.Count() != 1 || ds.Record().GetByField("code") != "C05" {
+		t.Fatalf("Read by id should find the record on its shard: %v %v", ds, err)
+	}
+
+	// 跨分片的排序与分页在合并后生效
+	ds, err = o.NewSession().Model("shard_order").OrderBy("qty desc, code").Limit(4, 3).Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	type row struct {
+		code string
+		qty  int
+	}
+	rows := make([]row, 20)
+	for i := range rows {
+		rows[i] = row{fmt.Sprintf("C%02d", i), i % 7}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].qty != rows[j].qty {
+			return rows[i].qty > rows[j].qty
+		}
+		return rows[i].code < rows[j].code
+	})
+	want := make([]any, 0, 4)
+	for _, r := range rows[3:7] {
+		want = append(want, r.code)
+	}
+	if got := fmt.Sprint(ds.Keys("code")); got != fmt.Sprint(want) {
+		t.Fatalf("merged page = %s, want %v", got, want)
+	}
+
+	found, _, err := o.NewSession().Model("shard_order").OrderBy("qty desc, code").Limit(4, 3).Search()
+	if err != nil || len(found) != 4 {
+		t.Fatalf("Search: %v %v", found, err)
+	}
+	ds, _ = o.NewSession().Model("shard_order").Ids(found...).OrderBy("qty desc, code").Read()
+	if got := fmt.Sprint(ds.Keys("code")); got != fmt.Sprint(want) {
+		t.Fatalf("merged search page = %s, want %v", got, want)
+	}
+
+	if sum, err := o.NewSession().Model("shard_order").Sum("qty"); err != nil || sum != 57 {
+		t.Fatalf("Sum should add every shard, got %v %v", sum, err)
+	}
+
+	// 更新与删除作用于各分片上命中的记录
+	if n, err := o.NewSession().Model("shard_order").Where("qty = ?", 0).Write(map[string]any{"code": "ZERO"}); err != nil || n != 3 {
+		t.Fatalf("Write should update matches on every shard, got %d %v", n, err)
+	}
+	if n, _ := o.NewSession().Model("shard_order").Where("code = ?", "ZERO").Count(); n != 3 {
+		t.Fatalf("updated records = %d, want 3", n)
+	}
+	if n, err := o.NewSession().Model("shard_order").Delete(ids[:6]...); err != nil || n != 6 {
+		t.Fatalf("Delete by ids should remove them from their shards, got %d %v", n, err)
+	}
+	if n, _ := o.NewSession().Model("shard_order").Count(); n != 14 {
+		t.Fatalf("remaining records = %d, want 14", n)
+	}
+
+	// 键集分页在各分片上按同一游标定位
+	seen := make(map[any]bool)
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		ds, next, err := o.NewSession().Model("shard_order").OrderBy("qty, code").Limit(5).ReadPage(cursor)
+		if err != nil {
+			t.Fatalf("ReadPage: %v", err)
+		}
+		for _, id := range ds.Keys("id") {
+			seen[id] = true
+		}
+		if cursor = next; cursor == "" {
+			break
+		}
+	}
+	if len(seen) != 14 || cursor != "" {
+		t.Fatalf("ReadPage should walk every record once, got %d", len(seen))
+	}
+
+	// 未分片的模型仍在主库
+	if _, err = o.NewSession().Model("bulk_item").Create(map[string]any{"code": "P"}); err != nil {
+		t.Fatalf("Create unsharded: %v", err)
+	}
+	if n, _ := o.NewSession().Shard(0).Model("bulk_item").Count(); n != 0 {
+		t.Fatalf("unsharded model should stay on the primary, shard has %d", n)
+	}
+}
+
+func TestShard_UpdateMany(t *testing.T) {
+	o := setupShardOrm(t, NewHashShardResolver("id"))
+	defer o.Close()
+
+	src := make([]any, 0, 6)
+	for i := 0; i < 6; i++ {
+		src = append(src, map[string]any{"code": fmt.Sprintf("U%d", i), "qty": i, "tenant_id": 1})
+	}
+	ids, err := o.NewSession().Model("shard_order").Create(src...)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 多条数据无 Domain 时各按主键路由到所在分片更新
+	model, err := o.GetModel("shard_order")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	data := make([]any, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, map[string]any{"id": id, "qty": 100})
+	}
+	if effect, err := model.Update(&UpdateRequest{Data: data}); err != nil || effect != int64(len(ids)) {
+		t.Fatalf("Update should write every record on its shard, got %d %v", effect, err)
+	}
+	if n, _ := o.NewSession().Model("shard_order").Where("qty = ?", 100).Count(); n != len(ids) {
+		t.Fatalf("updated records = %d, want %d", n, len(ids))
+	}
+}
+
+func TestShard_PruneByKey(t *testing.T) {
+	o := setupShardOrm(t, tenantResolver)
+	defer o.Close()
+
+	for tenant := int64(1); tenant <= 4; tenant++ {
+		if _, err := o.NewSession().Model("shard_order").Create(map[string]any{"tenant_id": tenant, "code": fmt.Sprint("T", tenant)}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if first, second := shardCount(t, o, 0), shardCount(t, o, 1); first != 2 || second != 2 {
+		t.Fatalf("records should follow the tenant key, got %d and %d", first, second)
+	}
+
+	// 绑定分片后不再路由：在分片 0 上放一条本应属于分片 1 的记录
+	if _, err := o.NewSession().Shard(0).Model("shard_order").Create(map[string]any{"tenant_id": 1, "code": "stray"}); err != nil {
+		t.Fatalf("Create on bound shard: %v", err)
+	}
+
+	// 条件带分片键时只查询其所在分片
+	ds, err := o.NewSession().Model("shard_order").Where("tenant_id = ?", 1).Read()
+	if err != nil || fmt.Sprint(ds.Keys("code")) != "[T1]" {
+		t.Fatalf("query on tenant key should only hit its shard: %v %v", ds, err)
+	}
+	// 分片键在 OR 之下时查询全部分片
+	n, err := o.NewSession().Model("shard_order").Where("tenant_id = ?", 1).Or("code = ?", "T2").Count()
+	if err != nil || n != 3 {
+		t.Fatalf("OR query should scan every shard, got %d %v", n, err)
+	}
+	ids, _, err := o.NewSession().Model("shard_order").In("tenant_id", 2, 4).Search()
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("IN query on tenant key: %v %v", ids, err)
+	}
+}
+
+func TestShard_Transaction(t *testing.T) {
+	o := setupShardOrm(t, tenantResolver)
+	defer o.Close()
+
+	// 未绑定分片的事务无法承载分片模型的操作
+	session := o.NewSession()
+	if err := session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := session.Model("shard_order").Create(map[string]any{"tenant_id": 1, "code": "A"}); !errors.Is(err, ErrShardRequired) {
+		t.Fatalf("unbound transaction should fail with ErrShardRequired, got %v", err)
+	}
+	session.Rollback(nil)
+
+	session = o.NewSession().Shard(1)
+	if err := session.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := session.Model("shard_order").Create(map[string]any{"tenant_id": 1, "code": "A"}); err != nil {
+		t.Fatalf("Create in shard transaction: %v", err)
+	}
+	session.Rollback(nil)
+	if n := shardCount(t, o, 1); n != 0 {
+		t.Fatalf("rolled back shard transaction left %d records", n)
+	}
+
+	if s := o.NewSession().Shard(2); !s.IsDeprecated {
+		t.Fatal("Shard out of range should deprecate the session")
+	}
+	if _, err := o.NewSession().Model("shard_order").WriteMany(map[string]any{"id": 1, "code": "X"}); !errors.Is(err, ErrShardRequired) {
+		t.Fatalf("WriteMany on a sharded model should require a shard, got %v", err)
+	}
+}
+
+func TestShard_DerivedReads(t *testing.T) {
+	dir := t.TempDir()
+	o, err := New(
+		WithDataSource(&TDataSource{DbType: "sqlite", DbName: filepath.Join(dir, "primary.db")}),
+		WithShards(&TDataSource{DbName: filepath.Join(dir, "shard0.db")}, &TDataSource{DbName: filepath.Join(dir, "shard1.db")}),
+		WithModelOptions(WithShardResolver(tenantResolver, "shard.customer", "shard.invoice")),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer o.Close()
+	if _, err = o.SyncModel("", new(ShardCustomer), new(ShardInvoice)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	fr := WithLang(context.Background(), "fr_FR")
+	customers, err := o.NewSession().Model("shard_customer").Create(map[string]any{"tenant_id": 1, "name": "ACME"})
+	if err != nil {
+		t.Fatalf("Create customer: %v", err)
+	}
+	if _, err = o.NewSession().WithContext(fr).Model("shard_customer").Ids(customers...).Write(map[string]any{"name": "ACME SARL"}); err != nil {
+		t.Fatalf("Write fr: %v", err)
+	}
+	invoices, err := o.NewSession().Model("shard_invoice").Create(map[string]any{"tenant_id": 1, "customer_id": customers[0]})
+	if err != nil {
+		t.Fatalf("Create invoice: %v", err)
+	}
+
+	// 关联与翻译的取值与记录落在同一分片：路由读取与绑定分片读取结果一致
+	for name, session := range map[string]func() *TSession{
+		"routed":   func() *TSession { return o.NewSession() },
+		"Shard(1)": func() *TSession { return o.NewSession().Shard(1) },
+	} {
+		ds, err := session().Model("shard_invoice").Ids(invoices...).Read()
+		if err != nil || ds.Count() != 1 {
+			t.Fatalf("%s Read invoice: %v", name, err)
+		}
+		if got := ds.FieldByName("customer_name").AsString(); got != "ACME" {
+			t.Fatalf("%s read of related field = %q, want ACME", name, got)
+		}
+
+		ds, err = session().WithContext(fr).Model("shard_customer").Ids(customers...).Read()
+		if err != nil || ds.Count() != 1 {
+			t.Fatalf("%s Read customer: %v", name, err)
+		}
+		if got := ds.FieldByName("name").AsString(); got != "ACME SARL" {
+			t.Fatalf("%s read of translated field = %q, want ACME SARL", name, got)
+		}
+	}
+}
+
+func TestShard_MergeOrder(t *testing.T) {
+	records := func(values ...any) []*dataset.TRecordSet {
+		recs := make([]*dataset.TRecordSet, len(values))
+		for i, v := range values {
+			recs[i] = dataset.NewRecordSet(map[string]any{"v": v})
+		}
+		return recs
+	}
+	keys := func(recs []*dataset.TRecordSet) string {
+		out := make([]any, len(recs))
+		for i, rec := range recs {
+			out[i] = rec.GetByField("v")
+		}
+		return fmt.Sprint(out...)
+	}
+	asc := []tOrderTerm{{field: "v", direction: "ASC"}}
+	desc := []tOrderTerm{{field: "v", direction: "DESC"}}
+
+	// NULL 的位置与方言一致：Postgres 升序在后、降序在前，其他方言相反
+	for _, c := range []struct {
+		terms     []tOrderTerm
+		nullsLast bool
+		want      string
+	}{
+		{asc, true, fmt.Sprint(1, 2, nil)},
+		{desc, true, fmt.Sprint(nil, 2, 1)},
+		{asc, false, fmt.Sprint(nil, 1, 2)},
+		{desc, false, fmt.Sprint(2, 1, nil)},
+	} {
+		recs := records(2, nil, 1)
+		sortShardRecords(recs, c.terms, nil, c.nullsLast)
+		if got := keys(recs); got != c.want {
+			t.Errorf("%v nullsLast=%v: got %s, want %s", c.terms[0].direction, c.nullsLast, got, c.want)
+		}
+	}
+
+	// 以字符串取出的大数按数值排序，普通字符串仍按字典序
+	recs := records("10", "9", "100")
+	sortShardRecords(recs, asc, []bool{true}, false)
+	if got := keys(recs); got != fmt.Sprint("9", "10", "100") {
+		t.Errorf("numeric strings should sort by value, got %s", got)
+	}
+	recs = records("10", "9", "100")
+	sortShardRecords(recs, asc, []bool{false}, false)
+	if got := keys(recs); got != fmt.Sprint("10", "100", "9") {
+		t.Errorf("plain strings should sort lexically, got %s", got)
+	}
+
+	models := []string{"shard_order"}
+	WithShardResolver(tenantResolver, models...)
+	if models[0] != "shard_order" {
+		t.Errorf("WithShardResolver should not modify the caller's slice, got %v", models)
+	}
+}
@@ -39,12 +39,21 @@ type (
 		LimitClause   int64
 		OffsetClause  int64
 		IsCount       bool
-		IsForUpdate   bool
+		Lock          *Lock    // 悲观锁，见 TSession.Lock
+		ruled         bool     // 记录规则已合并进 domain
+		keyset        *tKeyset // 键集分页，见 ReadPage
 		UseCascade    bool
 		OnConflict    *OnConflict
 		Charset       string //???
 		StoreEngine   string //???
 	}
+
+	// tOrderTerm 排序项：field 为模型字段名，expr 为其在 SQL 中的取值表达式
+	tOrderTerm struct {
+		field     string
+		expr      string
+		direction string // ""/ASC/DESC
+	}
 )
 
 // Init reset all the statment's fields
@@ -61,6 +70,9 @@ func (self *TStatement) Init() {
 	self.LimitClause = 0
 	self.OffsetClause = 0
 	self.IsCount = false
+	self.ruled = false
+	self.keyset = nil
+	self.Lock = nil
 	self.Params = make([]any, 0, 16)
 	self.Sets = nil // 不预先创建添加GC负担
 
@@ -624,6 +636,12 @@ func (self *TStatement) where_calc(node *domain.TDomainNode, active_test bool, c
 	if context == nil {
 		context = make(map[string]any)
 	}
+	if _, has := context["lang"]; !has {
+		context["lang"] = self.session.Lang() // 翻译字段按会话语言搜索
+	}
+	if _, has := context["schema"]; !has {
+		context["schema"] = self.session.Schema // 翻译附表随会话 schema(租户)限定
+	}
 
 	// domain = domain[:]
 	// if the object has a field named 'active', filter out all inactive
@@ -686,6 +704,15 @@ func (self *TStatement) where_calc(node *domain.TDomainNode, active_test bool, c
 func (self *TStatement) generate_order_by_inner(alias, order_spec string, query *TQuery, reverse_direction bool, seen []string) []string {
 	// TODO: initialize seen when nil (e.g. seen = []string{})
 	order_by_elements := make([]string, 0)
+	for _, term := range self.generate_order_terms(alias, order_spec, query) {
+		order_by_elements = append(order_by_elements, fmt.Sprintf(`%s %s`, term.expr, term.direction))
+	}
+	return order_by_elements
+}
+
+// generate_order_terms 解析排序规格与 Asc/Desc 字段为排序项，跳过不存在或不可排序的字段
+func (self *TStatement) generate_order_terms(alias, order_spec string, query *TQuery) []tOrderTerm {
+	order_terms := make([]tOrderTerm, 0)
 
 	generate_order := func(fields []string, order_direction string) {
 		// 排序方向白名单：只允许 ""/ASC/DESC，其它（用户可控）一律丢弃该排序项，
@@ -697,8 +724,8 @@ func (self *TStatement) generate_order_by_inner(alias, order_spec string, query
 		}
 		for _, fieldName := range fields {
 			if fieldName == self.IdKey {
-				lStr := fmt.Sprintf(`"%s"."%s" %s`, alias, fieldName, order_direction)
-				order_by_elements = append(order_by_elements, lStr)
+				expr := fmt.Sprintf(`"%s"."%s"`, alias, fieldName)
+				order_terms = append(order_terms, tOrderTerm{field: fieldName, expr: expr, direction: order_direction})
 
 			} else {
 				field := self.Model.Obj().GetFieldByName(fieldName)
@@ -718,8 +745,7 @@ func (self *TStatement) generate_order_by_inner(alias, order_spec string, query
 						qualifield_name = fmt.Sprintf(`COALESCE(%s, false)`, qualifield_name)
 					}
 
-					lStr := fmt.Sprintf(`%s %s`, qualifield_name, order_direction)
-					order_by_elements = append(order_by_elements, lStr)
+					order_terms = append(order_terms, tOrderTerm{field: fieldName, expr: qualifield_name, direction: order_direction})
 				} else {
 					continue //# ignore non-readable or "non-joinable" fields
 				}
@@ -748,7 +774,7 @@ func (self *TStatement) generate_order_by_inner(alias, order_spec string, query
 
 	generate_order(self.AscFields, "ASC")
 	generate_order(self.DescFields, "DESC")
-	return order_by_elements
+	return order_terms
 }
 
 func (self *TStatement) ___generate_order_by_inner(alias, order_spec string, query *TQuery, reverse_direction bool, seen []string) []string {
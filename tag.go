@@ -28,9 +28,9 @@ const (
 	TAG_TABLE_NAME        = "table_name"
 	TAG_TABLE_DESCRIPTION = "table_description"
 	TAG_TABLE_ORDER       = "table_order"
+	TAG_TABLE_AUDIT       = "table_audit" // #记录字段级变更到审计表
 
 	// rel
-	//TAG_RELATED   = "related" //废弃
 	TAG_TABLE_EXTENDS = "table_extends" // TODO
 	TAG_TABLE_RELATE  = "table_relate"
 	TAG_INHERITS      = "inherits"  // #postgres 的继承功能
@@ -57,7 +57,7 @@ const (
 	TAG_STATES        = "states"
 	TAG_PRIORITY      = "priority"   // TODO
 	TAG_ON_DELETE     = "ondelete"   // TODO
-	TAG_TRANSLATE     = "translate"  // TODO
+	TAG_TRANSLATE     = "translate"  // #按语言存储的文本字段
 	TAG_SELECT        = "select"     // #select=True （在外键字段上创建了一个索引）
 	TAG_CLASSIC_READ  = "read"       // #经典模式
 	TAG_CLASSIC_WRITE = "write"      // #经典模式
@@ -69,6 +69,10 @@ const (
 	TAG_VER           = "version"    // TODO
 	TAG_SETTER        = "setter"     // # 函数赋值
 	TAG_GETTER        = "getter"     // # 函数赋值
+	TAG_COMPUTE       = "compute"    // #compute('MethodName') 存储型计算字段，依赖变更时重算写回
+	TAG_DEPENDS       = "depends"    // #depends('qty','partner_id.name','line_ids.amount') 计算依赖
+	TAG_RELATED       = "related"    // #related('partner_id.country_id.name') 沿 many2one 链取值的关联字段
+	TAG_GROUPS        = "groups"     // #groups('base.group_user','base.group_system') 可访问该字段的权限组
 
 	// type
 	TAG_ID        = "id"
@@ -102,6 +106,7 @@ func init() {
 		TAG_TABLE_NAME:        tag_table_name,
 		TAG_TABLE_DESCRIPTION: tag_table_description,
 		TAG_TABLE_ORDER:       tag_table_order,
+		TAG_TABLE_AUDIT:       tag_table_audit,
 		// # rel
 		TAG_TABLE_EXTENDS: tag_table_extends,
 		//TAG_TABLE_RELATE:  tag_table_relate,
@@ -133,7 +138,7 @@ func init() {
 		//TAG_STATES:tag_s
 		//TAG_PRIORITY] = "priority"     // TODO
 		TAG_ON_DELETE: tag_ondelete,
-		TAG_TRANSLATE: tag_translate, // TODO
+		TAG_TRANSLATE: tag_translate,
 		//TAG_SELECT] = "select"         // #select=True （在外键字段上创建了一个索引）
 		//TAG_CLASSIC_READ:  tag_read,
 		//TAG_CLASSIC_WRITE: tag_write,
@@ -141,7 +146,7 @@ func init() {
 		TAG_DOMAIN:     tag_domain,
 		TAG_ATTACHMENT: tag_attachment,
 		//TAG_SELECTABLE] =
-		//TAG_GROUPS] = "groups"         // #groups='base.group_user' CSV list of ext IDs of groups
+		TAG_GROUPS:  tag_groups,
 		TAG_DELETED: tag_deleted,
 		TAG_VER:     tag_ver,
 
@@ -162,8 +167,11 @@ func init() {
 		TAG_MANY2MANY: tag_many2many,
 		TAG_JSON:    tag_json,*/
 		//TAG_RELATION: tag_relation,
-		TAG_SETTER: tag_setter,
-		TAG_GETTER: tag_getter,
+		TAG_SETTER:  tag_setter,
+		TAG_GETTER:  tag_getter,
+		TAG_COMPUTE: tag_compute,
+		TAG_DEPENDS: tag_depends,
+		TAG_RELATED: tag_related,
 	}
 }
 
@@ -261,6 +269,91 @@ func tag_setter(ctx *TTagContext) error {
 	return nil
 }
 
+// compute 存储型计算字段：值落库，依赖字段(depends)写入时调用计算方法重算。
+// 计算方法为 FieldFunc，按 ctx.Ids/ctx.Dataset 计算后 ctx.SetValue 返回
+// 与 Ids 等长的 []any，或一个用于全部记录的值
+func tag_compute(ctx *TTagContext) error {
+	field := ctx.Field.Base()
+	field.computeMethod = ""
+
+	if field.computeFunc == nil {
+		params := ctx.Params
+		if len(params) == 0 {
+			log.Panic("Compute tag ", field.Name(), "'s Args can no be blank!")
+		}
+
+		methodName := strings.Trim(params[0], "'")
+		// modelValue 为结构体值，指针接收者的方法需经地址查找
+		value := ctx.Model.GetBase().modelValue
+		m := value.MethodByName(methodName)
+		if !m.IsValid() && value.CanAddr() {
+			m = value.Addr().MethodByName(methodName)
+		}
+		if m.IsValid() {
+			if fn, ok := m.Interface().(FieldFunc); ok {
+				field.boundModel = ctx.Model.GetBase()
+				field.computeMethod = methodName
+				field.computeFunc = fn
+			}
+		}
+
+		if field.computeFunc == nil {
+			return fmt.Errorf("compute method %s of field %s is not found or not a FieldFunc", methodName, field.Name())
+		}
+	}
+
+	if ctx.Orm != nil {
+		ctx.Orm._resetComputeTriggers()
+	}
+	return nil
+}
+
+// depends 计算字段的依赖，点路径经 many2one/one2many 指向关联模型的字段
+func tag_depends(ctx *TTagContext) error {
+	field := ctx.Field.Base()
+	for _, param := range ctx.Params {
+		for _, path := range strings.Split(param, ",") {
+			if path = strings.Trim(strings.TrimSpace(path), "'\""); path != "" && utils.IndexOf(path, field.depends...) == -1 {
+				field.depends = append(field.depends, path)
+			}
+		}
+	}
+
+	if ctx.Orm != nil {
+		ctx.Orm._resetComputeTriggers()
+	}
+	return nil
+}
+
+// related 关联字段：值取自 many2one 链末端模型的字段，可用于 domain 搜索。
+// 默认不存储，读取时按链 JOIN 取值；另加 store 标签（不论先后）则落库，链上任一字段变更时重算
+func tag_related(ctx *TTagContext) error {
+	field := ctx.Field.Base()
+	if len(ctx.Params) == 0 {
+		return fmt.Errorf("related tag of field %s needs a dotted path", field.Name())
+	}
+
+	path := strings.Trim(strings.TrimSpace(ctx.Params[0]), "'\"")
+	if len(strings.Split(path, ".")) < 2 {
+		return fmt.Errorf("related path %s of field %s must start with a many2one field", path, field.Name())
+	}
+
+	field.relatedPath = path
+	field.store = false
+	field.readonly = true
+	field.getterMethod = ""
+	field.getterFunc = relatedGetter
+	field.hasGetter = true
+	field.computeMethod = ""
+	field.computeFunc = relatedCompute
+	field.depends = []string{path}
+
+	if ctx.Orm != nil {
+		ctx.Orm._resetComputeTriggers()
+	}
+	return nil
+}
+
 // dataset 数据类型
 func tag_type(ctx *TTagContext) error {
 	params := ctx.Params
@@ -364,6 +457,9 @@ func tag_ver(ctx *TTagContext) error {
 	field := ctx.Field.Base()
 	field.isVersion = true
 	field.SetDefault("1")
+	if ctx.Model != nil {
+		ctx.Model.Obj().VersionField = field.Name()
+	}
 	return nil
 }
 
@@ -404,6 +500,28 @@ func tag_name(ctx *TTagContext) error {
 }
 
 func tag_old_name(ctx *TTagContext) error {
+	field := ctx.Field.Base()
+	params := ctx.Params
+
+	if len(params) > 0 {
+		field.oldName = strings.Trim(params[0], "'")
+	}
+	return nil
+}
+
+// groups('group_a','group_b') 或 groups('group_a,group_b')
+func tag_groups(ctx *TTagContext) error {
+	field := ctx.Field.Base()
+	groups := make([]string, 0, len(ctx.Params))
+	for _, param := range ctx.Params {
+		for _, group := range strings.Split(param, ",") {
+			if group = strings.Trim(strings.TrimSpace(group), "'\""); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	field.permissionGroups = strings.Join(groups, ",")
 	return nil
 }
 
@@ -540,6 +658,8 @@ func tag_ondelete(ctx *TTagContext) error {
 	return nil
 }
 
+// translate 翻译字段：按会话语言(WithLang)读写与搜索，缺值时回退默认语言。
+// Postgres 上列类型改为 JSONB 存放全部语言，其余数据库非默认语言存入 TranslationTable
 func tag_translate(ctx *TTagContext) error {
 	field := ctx.Field.Base()
 	params := ctx.Params
@@ -549,6 +669,15 @@ func tag_translate(ctx *TTagContext) error {
 	} else {
 		field.translatable = true
 	}
+
+	if field.translatable {
+		if !field.SqlType.IsText() {
+			return fmt.Errorf("translate field %s must be a char/text field", field.Name())
+		}
+		if ctx.Orm != nil && ctx.Orm._translateJsonb() {
+			field.SqlType = SQLType{Name: Jsonb}
+		}
+	}
 	return nil
 }
 
@@ -631,6 +760,13 @@ func tag_table_order(ctx *TTagContext) error {
 
 }
 
+// Only for table
+// 开启审计：Create/Write/Delete 的字段变更写入 AuditTable
+func tag_table_audit(ctx *TTagContext) error {
+	ctx.Model.Obj().Audit = true
+	return nil
+}
+
 // TODO tag_extends 未完成
 func tag_table_extends(ctx *TTagContext) error {
 	fld_val := ctx.FieldTypeValue
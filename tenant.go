@@ -0,0 +1,146 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/orm/domain"
+	"github.com/volts-dev/utils"
+)
+
+type (
+	// TenancyMode 多租户模式，见 Config.Tenancy
+	TenancyMode uint8
+
+	tenantContextKey struct{}
+)
+
+const (
+	TenancyNone   TenancyMode = iota // 不区分租户
+	TenancySchema                    // 每个租户一个 schema(Postgres)
+	TenancyColumn                    // 各租户共用表，按租户字段区分记录
+)
+
+// WithTenant 在 ctx 上附加当前租户，session.WithContext(ctx) 后按 Config.Tenancy 隔离租户数据
+func WithTenant(ctx context.Context, tenant any) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext 返回 ctx 上的当前租户，未附加时返回 nil
+func TenantFromContext(ctx context.Context) any {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Value(tenantContextKey{})
+}
+
+// DefaultTenantSchema 租户的默认 schema 名：tenant_<租户>
+func DefaultTenantSchema(tenant any) string {
+	return "tenant_" + utils.ToString(tenant)
+}
+
+// Tenant 返回会话的当前租户，未附加时返回 nil
+func (self *TSession) Tenant() any {
+	return TenantFromContext(self.context)
+}
+
+// _tenantSchema schema 模式下租户对应的 schema 名，须为合法标识符
+func (self *TOrm) _tenantSchema(tenant any) (string, error) {
+	name := self.config.TenantSchema(tenant)
+	if _, err := self.dialect.Quoter().QuoteIdent(name); err != nil {
+		return "", fmt.Errorf("tenant %v: invalid schema %q: %w", tenant, name, err)
+	}
+	return name, nil
+}
+
+// _applyTenant schema 模式下把会话切换到 context 上租户的 schema
+func (self *TSession) _applyTenant() {
+	tenant := self.Tenant()
+	if tenant == nil || self.orm.config.Tenancy != TenancySchema {
+		return
+	}
+
+	schema, err := self.orm._tenantSchema(tenant)
+	if err != nil {
+		log.Errf("%v", err)
+		self.IsDeprecated = true
+		return
+	}
+	self.Schema = schema
+}
+
+// _tenantField 列模式下当前模型的租户字段与会话的租户；模型无租户字段或未附加租户时 field 为空
+func (self *TSession) _tenantField() (field string, tenant any) {
+	if self.orm.config.Tenancy != TenancyColumn || self.Statement.Model == nil {
+		return "", nil
+	}
+	if tenant = self.Tenant(); tenant == nil {
+		return "", nil
+	}
+	field = self.orm.config.TenantField
+	if self.Statement.Model.GetFieldByName(field) == nil {
+		return "", nil
+	}
+	return field, tenant
+}
+
+// _tenantDomain 列模式下限定当前租户记录的条件，无需限定时返回 nil
+func (self *TSession) _tenantDomain() *domain.TDomainNode {
+	field, tenant := self._tenantField()
+	if field == "" {
+		return nil
+	}
+	return domain.New(field, "=", tenant)
+}
+
+// _stampTenant 列模式下把记录的租户字段设为当前租户：新建时一律设置，
+// 更新时仅当提交了租户字段，使记录无法被移到其他租户
+func (self *TSession) _stampTenant(rec *dataset.TRecordSet, create bool) {
+	field, tenant := self._tenantField()
+	if field == "" || (!create && rec.GetFieldIndex(field) < 0) {
+		return
+	}
+	rec.SetByField(field, tenant)
+}
+
+// ProvisionTenant 为新租户建立表结构：schema 模式下创建该租户的 schema 并在其中同步 models，
+// 其余模式下各租户共用表，等同于 SyncModel。可重复调用
+func (self *TOrm) ProvisionTenant(tenant any, region string, models ...IModel) (modelNames []string, err error) {
+	if tenant == nil {
+		return nil, ErrTenantRequired
+	}
+	if self.config.Tenancy != TenancySchema {
+		return self.SyncModel(region, models...)
+	}
+	if self.dialect.DBType() != POSTGRES {
+		return nil, fmt.Errorf("schema tenancy is not supported by %s", self.dialect.DBType())
+	}
+
+	schema, err := self._tenantSchema(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	session := NewSession(self)
+	session.Schema = schema
+	if self.config.AllowDestructiveSync {
+		session.AllowUnsafe()
+	}
+	defer session.Close()
+
+	if _, err = session.Exec("CREATE SCHEMA IF NOT EXISTS " + self.dialect.Quoter().Quote(schema)); err != nil {
+		return nil, err
+	}
+
+	session.Begin()
+	defer func() {
+		session.Rollback(err)
+	}()
+
+	modelNames, err = session.SyncModel(region, models...)
+	if err != nil {
+		return nil, err
+	}
+	return modelNames, session.Commit()
+}
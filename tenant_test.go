@@ -0,0 +1,136 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	ormerr "github.com/volts-dev/orm/errors"
+)
+
+type (
+	TenantNote struct {
+		TModel   `table:"name('tenant_note')"`
+		Id       int64  `field:"pk autoincr"`
+		TenantId int64  `field:"int()"`
+		Name     string `field:"varchar() size(32)"`
+	}
+)
+
+func setupTenantOrm(t *testing.T, opts ...Option) *TOrm {
+	t.Helper()
+	ds := &TDataSource{DbType: "sqlite", DbName: filepath.Join(t.TempDir(), "tenant.db")}
+	o, err := New(append([]Option{WithDataSource(ds)}, opts...)...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err = o.SyncModel("", new(TenantNote), new(BulkItem)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	return o
+}
+
+func tenantNotes(t *testing.T, session *TSession) string {
+	t.Helper()
+	ds, err := session.Model("tenant_note").OrderBy("name").Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return fmt.Sprint(ds.Keys("name"))
+}
+
+func TestTenancy_Column(t *testing.T) {
+	o := setupTenantOrm(t, WithColumnTenancy(""))
+	defer o.Close()
+
+	ctxA := WithTenant(context.Background(), 1)
+	ctxB := WithTenant(context.Background(), 2)
+
+	// 新建记录一律归属当前租户，提交的租户值被覆盖
+	if _, err := o.NewSession().WithContext(ctxA).Model("tenant_note").Create(map[string]any{"name": "a1"}, map[string]any{"name": "a2", "tenant_id": 2}); err != nil {
+		t.Fatalf("Create A: %v", err)
+	}
+	ids, err := o.NewSession().WithContext(ctxB).Model("tenant_note").Create(map[string]any{"name": "b1"})
+	if err != nil {
+		t.Fatalf("Create B: %v", err)
+	}
+
+	if got := tenantNotes(t, o.NewSession().WithContext(ctxA)); got != "[a1 a2]" {
+		t.Fatalf("tenant A should only read its own records, got %s", got)
+	}
+	if n, _ := o.NewSession().WithContext(ctxB).Model("tenant_note").Count(); n != 1 {
+		t.Fatalf("tenant B count = %d, want 1", n)
+	}
+	// sudo 不跨越租户
+	if got := tenantNotes(t, o.NewSession().WithContext(WithSudo(ctxB))); got != "[b1]" {
+		t.Fatalf("sudo should stay within the tenant, got %s", got)
+	}
+	// 未附加租户时不限定
+	if got := tenantNotes(t, o.NewSession()); got != "[a1 a2 b1]" {
+		t.Fatalf("session without tenant should read every record, got %s", got)
+	}
+
+	// 按条件更新只命中本租户；按 id 更新其他租户的记录被拒绝
+	if n, err := o.NewSession().WithContext(ctxA).Model("tenant_note").In("name", "a1", "b1").Write(map[string]any{"name": "x1"}); err != nil || n != 1 {
+		t.Fatalf("Write should only touch tenant A, got %d %v", n, err)
+	}
+	_, err = o.NewSession().WithContext(ctxA).Model("tenant_note").Ids(ids...).Write(map[string]any{"name": "stolen"})
+	if !errors.Is(err, ormerr.ErrAccessDenied) {
+		t.Fatalf("Write on another tenant's record should be denied, got %v", err)
+	}
+	// 提交的租户值被改回当前租户，记录不会移到其他租户
+	if _, err = o.NewSession().WithContext(ctxB).Model("tenant_note").Ids(ids...).Write(map[string]any{"tenant_id": 1}); err != nil {
+		t.Fatalf("Write tenant field: %v", err)
+	}
+	if got := tenantNotes(t, o.NewSession().WithContext(ctxB)); got != "[b1]" {
+		t.Fatalf("record should stay with tenant B, got %s", got)
+	}
+
+	if _, err = o.NewSession().WithContext(ctxB).Model("tenant_note").Delete(); !errors.Is(err, ormerr.ErrUnsafe) {
+		t.Fatalf("Delete without condition should still be guarded, got %v", err)
+	}
+	if n, err := o.NewSession().WithContext(ctxA).Model("tenant_note").Where("name = ?", "b1").Delete(); err != nil || n != 0 {
+		t.Fatalf("Delete should not reach another tenant's records, got %d %v", n, err)
+	}
+	if n, err := o.NewSession().WithContext(ctxB).Model("tenant_note").Where("name = ?", "b1").Delete(); err != nil || n != 1 {
+		t.Fatalf("Delete should only remove tenant B's records, got %d %v", n, err)
+	}
+	if got := tenantNotes(t, o.NewSession()); got != "[a2 x1]" {
+		t.Fatalf("remaining records = %s", got)
+	}
+
+	// 无租户字段的模型不受影响
+	if _, err = o.NewSession().WithContext(ctxA).Model("bulk_item").Create(map[string]any{"code": "shared"}); err != nil {
+		t.Fatalf("Create unscoped: %v", err)
+	}
+	if n, _ := o.NewSession().WithContext(ctxB).Model("bulk_item").Count(); n != 1 {
+		t.Fatalf("model without tenant field should be shared, got %d", n)
+	}
+}
+
+func TestTenancy_Schema(t *testing.T) {
+	o := setupTenantOrm(t, WithSchemaTenancy(nil))
+	defer o.Close()
+
+	session := o.NewSession().WithContext(WithTenant(context.Background(), 7))
+	if session.Schema != "tenant_7" || session.Tenant() != 7 {
+		t.Fatalf("tenant should select its schema, got %q", session.Schema)
+	}
+	if session = o.NewSession().WithContext(context.Background()); session.Schema != o.Schema {
+		t.Fatalf("session without tenant should keep the default schema, got %q", session.Schema)
+	}
+
+	o.config.TenantSchema = func(tenant any) string { return fmt.Sprint("t;", tenant) }
+	if session = o.NewSession().WithContext(WithTenant(context.Background(), 7)); !session.IsDeprecated {
+		t.Fatal("invalid tenant schema should deprecate the session")
+	}
+
+	if _, err := o.ProvisionTenant(nil, ""); !errors.Is(err, ErrTenantRequired) {
+		t.Fatalf("ProvisionTenant without tenant should fail, got %v", err)
+	}
+	if _, err := o.ProvisionTenant(7, "", new(TenantNote)); err == nil {
+		t.Fatal("schema tenancy should be rejected on sqlite")
+	}
+}
@@ -0,0 +1,248 @@
+package orm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/volts-dev/orm/dialect"
+)
+
+const (
+	// TranslationTable 翻译字段非默认语言的取值。Postgres 上翻译字段本身为 JSONB 列(语言代码 -> 值)，
+	// 其余数据库沿用原列保存默认语言值，其他语言存入此附表，由 ORM 在同步含翻译字段的模型时建立
+	TranslationTable = "orm_translation"
+
+	// DefaultLang 未配置 Config.DefaultLang 时的默认语言
+	DefaultLang = "en_US"
+)
+
+type langContextKey struct{}
+
+// 语言代码如 en、en_US、zh-Hans-CN；只接受此格式，语言会内联进 SQL
+var langPattern = regexp.MustCompile(`^[A-Za-z]{2,3}([_-][A-Za-z0-9]{2,8}){0,3}$`)
+
+// WithLang 在 ctx 上附加当前语言，session.WithContext(ctx) 后翻译字段按该语言读写与搜索
+func WithLang(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, langContextKey{}, lang)
+}
+
+// LangFromContext 返回 ctx 上的当前语言，未附加时返回空字符串
+func LangFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	lang, _ := ctx.Value(langContextKey{}).(string)
+	return lang
+}
+
+// translationTableSql 翻译附表 DDL，以(模型,记录,字段,语言)为主键
+func translationTableSql(quoter dialect.Quoter, schema string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s VARCHAR(128) NOT NULL, %s VARCHAR(64) NOT NULL, %s VARCHAR(128) NOT NULL, %s VARCHAR(16) NOT NULL, %s TEXT, PRIMARY KEY (%s, %s, %s, %s))",
+		quoter.QuoteTable(schema, TranslationTable),
+		quoter.Quote("model"), quoter.Quote("res_id"), quoter.Quote("field"), quoter.Quote("lang"), quoter.Quote("value"),
+		quoter.Quote("model"), quoter.Quote("res_id"), quoter.Quote("field"), quoter.Quote("lang"))
+}
+
+// sqlString 把已校验的标识/语言写成 SQL 字符串字面量
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// hasTranslatable 模型是否有存储型翻译字段
+func hasTranslatable(model IModel) bool {
+	for _, field := range model.GetFields() {
+		if field.Translate() && field.Store() {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultLang 默认语言：翻译字段在其他语言缺值时回退到它
+func (self *TOrm) defaultLang() string {
+	if lang := self.config.DefaultLang; lang != "" {
+		return lang
+	}
+	return DefaultLang
+}
+
+// _translateJsonb 翻译字段是否以 JSONB 列存放全部语言
+func (self *TOrm) _translateJsonb() bool {
+	return self.dialect.DBType() == POSTGRES
+}
+
+// _translateColumnSql 把 Postgres 上的已有列改为 target 的类型：字符串列转为 JSONB 时原值作为默认语言的取值，
+// JSONB 转回字符串列(撤销)时只保留默认语言的取值
+func (self *TOrm) _translateColumnSql(schema, table string, target IField) string {
+	quoter := self.dialect.Quoter()
+	column := quoter.Quote(target.Name())
+	def := sqlString(self.defaultLang())
+	sqlType := self.dialect.GetSqlType(target)
+	if sqlTypeName(sqlType) == Jsonb {
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING CASE WHEN %s IS NULL THEN NULL ELSE jsonb_build_object(%s, %s) END",
+			quoter.QuoteTable(schema, table), column, Jsonb, column, def, column)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s->>%s",
+		quoter.QuoteTable(schema, table), column, sqlType, column, def)
+}
+
+// _translatedColumn 翻译字段在 lang 下的取值表达式，缺值时回退默认语言，供 SELECT 与 WHERE 使用
+func (self *TOrm) _translatedColumn(schema, lang string, model IModel, alias string, field IField) string {
+	quoter := self.dialect.Quoter()
+	column := quoter.Quote(alias) + "." + quoter.Quote(field.Name())
+	def := self.defaultLang()
+	if !langPattern.MatchString(lang) {
+		lang = def
+	}
+
+	if self._translateJsonb() {
+		if lang == def {
+			return fmt.Sprintf("(%s->>%s)", column, sqlString(def))
+		}
+		return fmt.Sprintf("COALESCE(%s->>%s, %s->>%s)", column, sqlString(lang), column, sqlString(def))
+	}
+
+	if lang == def {
+		return column
+	}
+	return fmt.Sprintf("COALESCE((SELECT %s FROM %s WHERE %s=%s AND %s=%s AND %s=%s AND %s=%s.%s), %s)",
+		quoter.Quote("value"), quoter.QuoteTable(schema, TranslationTable),
+		quoter.Quote("model"), sqlString(model.String()),
+		quoter.Quote("field"), sqlString(field.Name()),
+		quoter.Quote("lang"), sqlString(lang),
+		quoter.Quote("res_id"), quoter.Quote(alias), quoter.Quote(model.IdField()),
+		column)
+}
+
+// Lang 返回本会话翻译字段使用的语言：context 上经 WithLang 附加的语言，未附加或不合法时为默认语言
+func (self *TSession) Lang() string {
+	if lang := LangFromContext(self.context); langPattern.MatchString(lang) {
+		return lang
+	}
+	return self.orm.defaultLang()
+}
+
+// _translationSideTable 会话模型的翻译值是否写入附表，写入须与记录同进同退
+func (self *TSession) _translationSideTable() bool {
+	return self.Statement.Model != nil && !self.orm._translateJsonb() && hasTranslatable(self.Statement.Model)
+}
+
+// _splitTranslations 从待写入的值中取出需单独写入的翻译字段值，写入记录后交给 _writeTranslations：
+//   - JSONB：新建时直接写成 {默认语言: 值, 当前语言: 值}；更新时取出，按语言合并进原值
+//   - 附表：当前为默认语言时照常写列；其他语言写附表，新建时列上同时写入作为回退值
+func (self *TSession) _splitTranslations(values map[string]any, create bool) (map[string]any, error) {
+	model := self.Statement.Model
+	lang, def := self.Lang(), self.orm.defaultLang()
+	jsonb := self.orm._translateJsonb()
+
+	var trans map[string]any
+	for name, value := range values {
+		field := model.GetFieldByName(name)
+		if field == nil || !field.Translate() || !field.Store() {
+			continue
+		}
+
+		if jsonb && create {
+			if value != nil {
+				data, err := json.Marshal(map[string]any{def: value, lang: value})
+				if err != nil {
+					return nil, err
+				}
+				values[name] = string(data)
+			}
+			continue
+		}
+		if !jsonb && lang == def {
+			continue
+		}
+
+		if trans == nil {
+			trans = make(map[string]any)
+		}
+		trans[name] = value
+		if !create {
+			delete(values, name)
+		}
+	}
+
+	return trans, nil
+}
+
+// _writeTranslations 按会话语言写入 ids 记录的翻译值
+func (self *TSession) _writeTranslations(ids []any, trans map[string]any) error {
+	if len(ids) == 0 || len(trans) == 0 {
+		return nil
+	}
+
+	model := self.Statement.Model
+	quoter := self.orm.dialect.Quoter()
+	lang := self.Lang()
+	session := self._computeSession() // 沿用本会话事务，不重置本会话 Statement
+
+	if self.orm._translateJsonb() {
+		for name, value := range trans {
+			sql := fmt.Sprintf("UPDATE %s SET %s = COALESCE(%s, '{}'::jsonb) || jsonb_build_object(?::text, ?::text) WHERE %s IN (%s)",
+				quoter.QuoteTable(self.Schema, model.Table()), quoter.Quote(name), quoter.Quote(name),
+				quoter.Quote(model.IdField()), idsToSqlHolder(ids...))
+			if _, err := session._exec(sql, append([]any{lang, value}, ids...)...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// 附表：一次删除旧值，再以多行 INSERT 写入新值，均按方言的参数上限分批
+	table := quoter.QuoteTable(self.Schema, TranslationTable)
+	names := make([]any, 0, len(trans))
+	rows := make([][]any, 0, len(trans)*len(ids))
+	for name, value := range trans {
+		names = append(names, name)
+		if value == nil {
+			continue
+		}
+		for _, id := range ids {
+			rows = append(rows, []any{model.String(), id, name, lang, value})
+		}
+	}
+
+	size := min(DefaultBatchSize, max(self.orm.dialect.MaxParams()-len(names)-2, 1))
+	for start := 0; start < len(ids); start += size {
+		chunk := ids[start:min(start+size, len(ids))]
+		sql := fmt.Sprintf("DELETE FROM %s WHERE %s=? AND %s=? AND %s IN (%s) AND %s IN (%s)",
+			table, quoter.Quote("model"), quoter.Quote("lang"),
+			quoter.Quote("field"), idsToSqlHolder(names...), quoter.Quote("res_id"), idsToSqlHolder(chunk...))
+		args := append([]any{model.String(), lang}, names...)
+		if _, err := session._exec(sql, append(args, chunk...)...); err != nil {
+			return err
+		}
+	}
+
+	fields := []string{"model", "res_id", "field", "lang", "value"}
+	size = min(DefaultBatchSize, max(self.orm.dialect.MaxParams()/len(fields), 1))
+	for _, chunk := range bulkBatches(rows, size, nil) {
+		args := make([]any, 0, len(chunk)*len(fields))
+		for _, values := range chunk {
+			args = append(args, values...)
+		}
+		if _, err := session._exec(self.orm.dialect.GenBulkInsertSql(table, fields, len(chunk), nil, "", nil), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// _deleteTranslations 删除记录时一并清理附表中的翻译
+func (self *TSession) _deleteTranslations(ids []any) error {
+	if len(ids) == 0 || !self._translationSideTable() {
+		return nil
+	}
+
+	quoter := self.orm.dialect.Quoter()
+	sql := fmt.Sprintf("DELETE FROM %s WHERE %s=? AND %s IN (%s)",
+		quoter.QuoteTable(self.Schema, TranslationTable), quoter.Quote("model"), quoter.Quote("res_id"), idsToSqlHolder(ids...))
+	_, err := self._computeSession()._exec(sql, append([]any{self.Statement.Model.String()}, ids...)...)
+	return err
+}
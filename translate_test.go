@@ -0,0 +1,220 @@
+package orm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/volts-dev/dataset"
+	"github.com/volts-dev/orm/domain"
+)
+
+type TranslatedProduct struct {
+	TModel `table:"name('tr_product')"`
+	Id     int64  `field:"pk autoincr"`
+	Name   string `field:"varchar() size(64) translate"`
+	Code   string `field:"varchar() size(16)"`
+}
+
+type TranslatedNote struct {
+	TModel `table:"name('tr_note')"`
+	Id     int64  `field:"pk autoincr"`
+	Title  string `field:"varchar() size(64) translate"`
+	Body   string `field:"varchar() size(64) translate"`
+}
+
+func translatedName(t *testing.T, o *TOrm, ctx context.Context, id any) string {
+	t.Helper()
+	ds, err := o.NewSession().WithContext(ctx).Model("tr_product").Ids(id).Read()
+	if err != nil || ds.Count() != 1 {
+		t.Fatalf("Read tr_product(%v): %v", id, err)
+	}
+	return ds.FieldByName("name").AsString()
+}
+
+func TestTranslate_PerLanguageReadWriteAndSearch(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+
+	plan, err := o.PlanSyncModel("", new(TranslatedProduct))
+	if err != nil {
+		t.Fatalf("PlanSyncModel: %v", err)
+	}
+	if !strings.Contains(plan.String(), TranslationTable) {
+		t.Fatalf("plan should create the translation table:\n%s", plan)
+	}
+	if _, err = o.SyncModel("", new(TranslatedProduct)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+
+	en := context.Background()
+	fr := WithLang(en, "fr_FR")
+	de := WithLang(en, "de_DE")
+
+	ids, err := o.NewSession().Model("tr_product").Create(map[string]any{"name": "Chair", "code": "C1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err = o.NewSession().WithContext(fr).Model("tr_product").Ids(ids...).Write(map[string]any{"name": "Chaise", "code": "C2"}); err != nil {
+		t.Fatalf("Write fr: %v", err)
+	}
+
+	// 各语言独立取值，未翻译的语言回退默认语言；非翻译字段照常写列
+	if name := translatedName(t, o, en, ids[0]); name != "Chair" {
+		t.Fatalf("default language should keep its value, got %q", name)
+	}
+	if name := translatedName(t, o, fr, ids[0]); name != "Chaise" {
+		t.Fatalf("fr_FR should read its translation, got %q", name)
+	}
+	if name := translatedName(t, o, de, ids[0]); name != "Chair" {
+		t.Fatalf("missing translation should fall back to the default language, got %q", name)
+	}
+	if ds, _ := o.NewSession().Model("tr_product").Ids(ids...).Read(); ds.FieldByName("code").AsString() != "C2" {
+		t.Fatalf("plain fields must still be written along with translations")
+	}
+
+	// 非默认语言新建：列上保存回退值，同时记下该语言的翻译
+	more, err := o.NewSession().WithContext(fr).Model("tr_product").Create(map[string]any{"name": "Table"})
+	if err != nil {
+		t.Fatalf("Create fr: %v", err)
+	}
+	if name := translatedName(t, o, en, more[0]); name != "Table" {
+		t.Fatalf("record created in fr_FR should fall back to its value, got %q", name)
+	}
+
+	// 搜索按会话语言匹配
+	cnt, err := o.NewSession().WithContext(fr).Model("tr_product").Domain(`[('name', '=', 'Chaise')]`).Count()
+	if err != nil || cnt != 1 {
+		t.Fatalf("fr_FR search should match the translation, got %d: %v", cnt, err)
+	}
+	if cnt, _ = o.NewSession().Model("tr_product").Domain(`[('name', '=', 'Chaise')]`).Count(); cnt != 0 {
+		t.Fatalf("default language search must not match the fr_FR translation, got %d", cnt)
+	}
+
+	model, err := o.GetModel("tr_product", WithContext(fr))
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	ds, err := model.NameGet(ids)
+	if err != nil || ds.FieldByName("name").AsString() != "Chaise" {
+		t.Fatalf("NameGet should follow the context language: %v", err)
+	}
+	ds, err = model.NameSearch("Chaise", nil, "=", 0, "", nil)
+	if err != nil || ds.Count() != 1 {
+		t.Fatalf("NameSearch should match the translation: %v", err)
+	}
+
+	// ilike 在 sqlite 上跑不了，只校验生成的条件取翻译值
+	exp, err := NewExpression(o, model.GetBase(), domain.New("name", "ilike", "chai"), map[string]any{"lang": "fr_FR"})
+	if err != nil {
+		t.Fatalf("NewExpression: %v", err)
+	}
+	if where, _ := exp.toSql(); !strings.Contains(strings.Join(where, " "), TranslationTable) {
+		t.Fatalf("ilike on a translated field should resolve the translation, got %v", where)
+	}
+
+	// 删除记录一并清理翻译
+	if _, err = o.NewSession().Model("tr_product").Delete(ids...); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	left, err := o.NewSession().Query(`SELECT res_id FROM orm_translation WHERE model = ?`, "tr.product")
+	if err != nil {
+		t.Fatalf("query translations: %v", err)
+	}
+	if left.Count() != 1 || left.FieldByName("res_id").AsInteger() != more[0].(int64) {
+		t.Fatalf("only the remaining record's translation should be left, got %d rows", left.Count())
+	}
+}
+
+func TestTranslate_PostgresColumnAndSchema(t *testing.T) {
+	o := &TOrm{dialect: newPgDialectForTest(t), config: &Config{}}
+	varchar, err := NewField("name", WithSQLType(SQLType{Name: Varchar, DefaultLength: 64}))
+	if err != nil {
+		t.Fatalf("NewField: %v", err)
+	}
+	jsonb, err := NewField("name", WithSQLType(SQLType{Name: Varchar}))
+	if err != nil {
+		t.Fatalf("NewField: %v", err)
+	}
+	jsonb.Base().SqlType = SQLType{Name: Jsonb} // 同 tag_translate 在 Postgres 上的处理
+
+	// 已有字符串列改为翻译字段：原值成为默认语言的取值，撤销时取回默认语言
+	up := o._translateColumnSql("tenant1", "tr_product", jsonb)
+	for _, want := range []string{`"tenant1"."tr_product"`, "TYPE JSONB", `jsonb_build_object('en_US', "name")`} {
+		if !strings.Contains(up, want) {
+			t.Errorf("conversion to JSONB should contain %s, got %s", want, up)
+		}
+	}
+	down := o._translateColumnSql("tenant1", "tr_product", varchar)
+	for _, want := range []string{"TYPE VARCHAR", `"name"->>'en_US'`} {
+		if !strings.Contains(down, want) {
+			t.Errorf("conversion back should contain %s, got %s", want, down)
+		}
+	}
+
+	// 附表方言上按会话 schema 限定翻译附表
+	sqlite := setupMigrationOrm(t)
+	defer sqlite.Close()
+	if _, err = sqlite.SyncModel("", new(TranslatedProduct)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	model, err := sqlite.GetModel("tr_product")
+	if err != nil {
+		t.Fatalf("GetModel: %v", err)
+	}
+	exp, err := NewExpression(sqlite, model.GetBase(), domain.New("name", "=", "Chaise"), map[string]any{"lang": "fr_FR", "schema": "tenant1"})
+	if err != nil {
+		t.Fatalf("NewExpression: %v", err)
+	}
+	want := sqlite.dialect.Quoter().QuoteTable("tenant1", TranslationTable)
+	if where, _ := exp.toSql(); !strings.Contains(strings.Join(where, " "), want) {
+		t.Fatalf("translated search should use %s, got %v", want, where)
+	}
+}
+
+func TestTranslate_SideTableWritesInBulk(t *testing.T) {
+	o := setupMigrationOrm(t)
+	defer o.Close()
+	if _, err := o.SyncModel("", new(TranslatedNote)); err != nil {
+		t.Fatalf("SyncModel: %v", err)
+	}
+	values := make([]any, 0, 5)
+	for i := 0; i < 5; i++ {
+		values = append(values, map[string]any{"title": "Title", "body": "Body"})
+	}
+	ids, err := o.NewSession().Model("tr_note").Create(values...)
+	if err != nil || len(ids) != 5 {
+		t.Fatalf("Create: %v %v", ids, err)
+	}
+
+	// 多条记录、多个翻译字段各只用一条 DELETE 与一条 INSERT；重复写入覆盖旧值
+	fr := WithLang(context.Background(), "fr_FR")
+	recorder := &sqlRecorder{}
+	o.db.AddHook(recorder)
+	for _, title := range []string{"Titre", "Titre 2"} {
+		recorder.sqls = nil
+		if _, err = o.NewSession().WithContext(fr).Model("tr_note").Ids(ids...).Write(map[string]any{"title": title, "body": "Corps"}); err != nil {
+			t.Fatalf("Write fr: %v", err)
+		}
+		table := o.dialect.Quoter().Quote(TranslationTable)
+		del, ins := recorder.count("DELETE FROM "+table), recorder.count("INSERT INTO "+table)
+		if del != 1 || ins != 1 {
+			t.Fatalf("translations should be written in bulk, got %d DELETE and %d INSERT", del, ins)
+		}
+	}
+
+	ds, err := o.NewSession().Query(`SELECT count(1) AS cnt FROM orm_translation WHERE model = ? AND lang = ?`, "tr.note", "fr_FR")
+	if err != nil || ds.FieldByName("cnt").AsInteger() != 10 {
+		t.Fatalf("each record should keep one translation per field: %v", err)
+	}
+	ds, err = o.NewSession().WithContext(fr).Model("tr_note").Ids(ids...).Read()
+	if err != nil || ds.Count() != 5 {
+		t.Fatalf("Read fr: %v", err)
+	}
+	ds.Range(func(pos int, record *dataset.TRecordSet) error {
+		if record.FieldByName("title").AsString() != "Titre 2" || record.FieldByName("body").AsString() != "Corps" {
+			t.Fatalf("record %d should read its latest translation", pos)
+		}
+		return nil
+	})
+}
@@ -77,6 +77,61 @@ type structCacheKey struct {
 // Populated lazily; struct field shapes never change, so entries are never invalidated.
 var structInfoCache sync.Map
 
+// structFieldInfos 返回结构体类型各字段的反射元数据(按 FieldIdentifier 标签解析)，结果缓存复用
+func structFieldInfos(vType reflect.Type, fieldIdentifier string) []structFieldInfo {
+	cacheKey := structCacheKey{t: vType, fieldIdentifier: fieldIdentifier}
+	if cached, ok := structInfoCache.Load(cacheKey); ok {
+		return cached.([]structFieldInfo)
+	}
+
+	infos := make([]structFieldInfo, 0, vType.NumField())
+	for i := 0; i < vType.NumField(); i++ {
+		sf := vType.Field(i)
+		info := structFieldInfo{reflectIdx: i}
+
+		// unexported
+		if sf.PkgPath != "" {
+			info.skip = true
+			infos = append(infos, info)
+			continue
+		}
+
+		tag := sf.Tag.Get(fieldIdentifier)
+		if tag == "-" {
+			info.skip = true
+			infos = append(infos, info)
+			continue
+		}
+
+		// default ORM name
+		info.ormName = fmtFieldName(sf.Name)
+
+		// parse tag for name override and extends/relate
+		for _, part := range splitTag(tag) {
+			parsed := parseTag(part)
+			switch strings.ToLower(parsed[0]) {
+			case "name":
+				if len(parsed) > 1 {
+					info.ormName = fmtFieldName(parsed[1])
+				}
+			case "extends", "relate":
+				info.isExtends = true
+			}
+		}
+
+		// time detection (type-level, not value-level)
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		info.isTime = ft.ConvertibleTo(TimeType)
+
+		infos = append(infos, info)
+	}
+	structInfoCache.Store(cacheKey, infos)
+	return infos
+}
+
 // StructToMap converts src (struct or *struct) to map[string]any using model
 // metadata. omitFields, if non-empty, excludes those orm-named fields from the
 // result (mirrors the Statement.OmitFields semantics).
@@ -103,58 +158,7 @@ func StructToMap(src any, model IModel, omitFields []string) (res_map map[string
 	fieldIdentifier := orm.Config().FieldIdentifier
 	vType := v.Type()
 
-	// load or build cache entry
-	var infos []structFieldInfo
-	cacheKey := structCacheKey{t: vType, fieldIdentifier: fieldIdentifier}
-	if cached, ok := structInfoCache.Load(cacheKey); ok {
-		infos = cached.([]structFieldInfo)
-	} else {
-		infos = make([]structFieldInfo, 0, vType.NumField())
-		for i := 0; i < vType.NumField(); i++ {
-			sf := vType.Field(i)
-			info := structFieldInfo{reflectIdx: i}
-
-			// unexported
-			if sf.PkgPath != "" {
-				info.skip = true
-				infos = append(infos, info)
-				continue
-			}
-
-			tag := sf.Tag.Get(fieldIdentifier)
-			if tag == "-" {
-				info.skip = true
-				infos = append(infos, info)
-				continue
-			}
-
-			// default ORM name
-			info.ormName = fmtFieldName(sf.Name)
-
-			// parse tag for name override and extends/relate
-			for _, part := range splitTag(tag) {
-				parsed := parseTag(part)
-				switch strings.ToLower(parsed[0]) {
-				case "name":
-					if len(parsed) > 1 {
-						info.ormName = fmtFieldName(parsed[1])
-					}
-				case "extends", "relate":
-					info.isExtends = true
-				}
-			}
-
-			// time detection (type-level, not value-level)
-			ft := sf.Type
-			if ft.Kind() == reflect.Ptr {
-				ft = ft.Elem()
-			}
-			info.isTime = ft.ConvertibleTo(TimeType)
-
-			infos = append(infos, info)
-		}
-		structInfoCache.Store(cacheKey, infos)
-	}
+	infos := structFieldInfos(vType, fieldIdentifier)
 
 	res_map = make(map[string]any)
 	lToOmitFields := len(omitFields) > 0
@@ -225,6 +229,11 @@ func StructToMap(src any, model IModel, omitFields []string) (res_map map[string
 						log.Err("unhandled struct field type", info.ormName)
 					}
 				}
+			case reflect.Ptr:
+				if fv.IsNil() {
+					continue // nil 指针即未提供
+				}
+				lValue = fv.Elem().Interface()
 			default:
 				lValue = fv.Interface()
 			}